@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// BatchEntry is an item to be stored by SetItems. Non-positive TTL means the default TTL of the server
+type BatchEntry struct {
+	Key  string
+	Item storage.Storable
+	TTL  time.Duration
+}
+
+// BatchSetResult is a result of storing a single item. Stored is false if the key exists in only-if-absent mode
+// or if the item has not been stored because of Err (e.g. storage.ErrOutOfMemory)
+type BatchSetResult struct {
+	Key     string
+	Stored  bool
+	Version uint64
+	Err     error
+}
+
+type batchEntry struct {
+	Key   string           `json:"key"`
+	Value storage.Storable `json:"value"`
+	Type  string           `json:"type,omitempty"`
+	TTL   string           `json:"ttl,omitempty"`
+}
+
+type batchGetResult struct {
+	Key     string          `json:"key"`
+	Found   bool            `json:"found"`
+	Value   json.RawMessage `json:"value"`
+	Type    string          `json:"type"`
+	Version uint64          `json:"version"`
+}
+
+type batchSetResult struct {
+	Key     string `json:"key"`
+	Stored  bool   `json:"stored"`
+	Version uint64 `json:"version"`
+	Error   string `json:"error"`
+}
+
+type batchDeleteResult struct {
+	Key     string `json:"key"`
+	Deleted bool   `json:"deleted"`
+}
+
+// GetItems call reads many items in a single request. Absent keys are missing in the result
+func (client *GedisClient) GetItems(keys ...string) (map[string]storage.Storable, error) {
+	return client.GetItemsContext(context.Background(), keys...)
+}
+
+// GetItemsContext ...
+func (client *GedisClient) GetItemsContext(ctx context.Context, keys ...string) (map[string]storage.Storable, error) {
+	var results []batchGetResult
+	if _, err := client.call(ctx, http.MethodPost, "batch/get", map[string][]string{"keys": keys}, &results); err != nil {
+		return nil, err
+	}
+	items := make(map[string]storage.Storable, len(results))
+	for _, result := range results {
+		if !result.Found {
+			continue
+		}
+		item, err := parseStorable(result.Value, result.Type)
+		if err != nil {
+			return nil, err
+		}
+		items[result.Key] = item
+	}
+	return items, nil
+}
+
+// SetItems call stores many items in a single request, creating or replacing them. With onlyIfAbsent
+// the items are stored only if their keys do not exist. Items are stored independently, so results
+// are returned per item in the same order
+func (client *GedisClient) SetItems(entries []BatchEntry, onlyIfAbsent bool) ([]BatchSetResult, error) {
+	return client.SetItemsContext(context.Background(), entries, onlyIfAbsent)
+}
+
+// SetItemsContext ...
+func (client *GedisClient) SetItemsContext(ctx context.Context, entries []BatchEntry, onlyIfAbsent bool) ([]BatchSetResult, error) {
+	request := struct {
+		Entries      []batchEntry `json:"entries"`
+		OnlyIfAbsent bool         `json:"nx,omitempty"`
+	}{make([]batchEntry, len(entries)), onlyIfAbsent}
+	for i, entry := range entries {
+		request.Entries[i] = batchEntry{Key: entry.Key, Value: entry.Item}
+		if entry.TTL > 0 {
+			request.Entries[i].TTL = entry.TTL.String()
+		}
+		switch entry.Item.(type) {
+		case storage.Set:
+			request.Entries[i].Type = "set"
+		case *storage.SortedSet:
+			request.Entries[i].Type = "zset"
+		}
+	}
+
+	var results []batchSetResult
+	if _, err := client.call(ctx, http.MethodPost, "batch/set", request, &results); err != nil {
+		return nil, err
+	}
+	setResults := make([]BatchSetResult, len(results))
+	for i, result := range results {
+		setResults[i] = BatchSetResult{Key: result.Key, Stored: result.Stored, Version: result.Version}
+		if result.Error == storage.ErrOutOfMemory.Error() {
+			setResults[i].Err = storage.ErrOutOfMemory
+		} else if result.Error != "" {
+			setResults[i].Err = errors.New(result.Error)
+		}
+	}
+	return setResults, nil
+}
+
+// DeleteItems call removes many items in a single request and returns the number of existed ones
+func (client *GedisClient) DeleteItems(keys ...string) (int, error) {
+	return client.DeleteItemsContext(context.Background(), keys...)
+}
+
+// DeleteItemsContext ...
+func (client *GedisClient) DeleteItemsContext(ctx context.Context, keys ...string) (int, error) {
+	var results []batchDeleteResult
+	if _, err := client.call(ctx, http.MethodPost, "batch/delete", map[string][]string{"keys": keys}, &results); err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, result := range results {
+		if result.Deleted {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/izhamoidsin/gedis/pubsub"
+)
+
+// maxReconnectDelay limits the backoff between attempts to restore a broken subscription
+const maxReconnectDelay = 5 * time.Second
+
+// Publish call sends the message to the channel and returns the number of subscribers received it
+func (client *GedisClient) Publish(channel string, message string) (int, error) {
+	return client.PublishContext(context.Background(), channel, message)
+}
+
+// PublishContext ...
+func (client *GedisClient) PublishContext(ctx context.Context, channel string, message string) (int, error) {
+	var received int
+	_, err := client.call(ctx, http.MethodPost, "channels/"+url.PathEscape(channel), message, &received)
+	return received, err
+}
+
+// Subscribe call streams messages of the channel until ctx is done, then the returned channel is closed.
+// Broken connections are restored automatically, messages published meanwhile are lost
+func (client *GedisClient) Subscribe(ctx context.Context, channel string) (<-chan pubsub.Message, error) {
+	return client.subscribe(ctx, "channels/"+url.PathEscape(channel))
+}
+
+// PSubscribe works as Subscribe but receives messages of all the channels matching the glob pattern
+func (client *GedisClient) PSubscribe(ctx context.Context, pattern string) (<-chan pubsub.Message, error) {
+	return client.subscribe(ctx, "channels/"+url.PathEscape(pattern)+"?pattern=true")
+}
+
+// subscribe call decodes events of the stream as messages
+func (client *GedisClient) subscribe(ctx context.Context, path string) (<-chan pubsub.Message, error) {
+	messages := make(chan pubsub.Message)
+	err := client.stream(ctx, path, func(data []byte) bool {
+		var message pubsub.Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			return true
+		}
+		select {
+		case messages <- message:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() { close(messages) })
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// stream call passes data of every event to handle until ctx is done, then calls closed.
+// The failure of the first connection is returned, later ones are retried with a backoff
+func (client *GedisClient) stream(ctx context.Context, path string, handle func(data []byte) bool, closed func()) error {
+	response, err := client.openStream(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer closed()
+		delay := 100 * time.Millisecond
+		for {
+			if readEvents(response, handle) {
+				delay = 100 * time.Millisecond
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+				if delay *= 2; delay > maxReconnectDelay {
+					delay = maxReconnectDelay
+				}
+				if response, err = client.openStream(ctx, path); err == nil {
+					break
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (client *GedisClient) openStream(ctx context.Context, path string) (*http.Response, error) {
+	request, err := client.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "text/event-stream")
+	response, err := client.longLiving().Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		body, _ := readBody(response)
+		return nil, statusError(response.StatusCode, body)
+	}
+	return response, nil
+}
+
+// readEvents call passes `data` of the events to handle until the stream is broken or handle returns false.
+// Comment lines (heartbeats) and other fields are skipped. True is returned if any event was received.
+// Lines are not limited in length, as replication records carry whole entries of any size
+func readEvents(response *http.Response, handle func(data []byte) bool) bool {
+	defer response.Body.Close()
+	received := false
+	var data []string
+	reader := bufio.NewReader(response.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// incomplete last line of the broken stream is dropped
+			return received
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		switch {
+		case line == "":
+			if len(data) == 0 {
+				continue
+			}
+			if !handle([]byte(strings.Join(data, "\n"))) {
+				return received
+			}
+			received = true
+			data = data[:0]
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
@@ -1,129 +1,181 @@
 package client
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
-	"io"
-	"io/ioutil"
+	"context"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/izhamoidsin/gedis/storage"
 )
 
-// TODO verify all errors supressing with _
-// TODO refactor error handling
-
 // GedisClient is go lang client to Gedis Server. Wraps HTTP calls and provide
-// a native API
+// a native API. Every method has a variant taking context.Context, e.g. GetItemContext for GetItem
 type GedisClient struct {
-	host    string
-	port    int
-	strPort string
+	scheme     string
+	host       string // host:port of the server
+	basePath   string
+	httpClient *http.Client
+	timeout    time.Duration
+	userAgent  string
+	header     http.Header
+	slots      atomic.Value // *slots.Map once LoadSlots is called
 }
 
-// CreateClient call creates a new instance of client and initializes it
+// CreateClient call creates a client of the server at http://host:port with the default options
 func CreateClient(host string, port int) *GedisClient {
-	client := new(GedisClient)
-	client.host = host
-	client.port = port
-	client.strPort = strconv.Itoa(port)
+	client := &GedisClient{scheme: "http", host: net.JoinHostPort(host, strconv.Itoa(port)), header: http.Header{}}
+	client.httpClient = client.followingSlots(nil)
 	return client
 }
 
+// fullURL call makes the URL of the path at the server the client is created for,
+// or at the owner of the entry if slot routing is enabled by LoadSlots
 func (client *GedisClient) fullURL(path string) string {
-	return "http://" + client.host + ":" + client.strPort + "/" + path
+	host := client.host
+	if node := client.slotOwner(path); node != "" {
+		host = node
+	}
+	return client.scheme + "://" + host + client.basePath + "/" + path
+}
+
+// entryQuery call adds `type` param for sets and sorted sets, as they are sent as JSON arrays
+func entryQuery(item storage.Storable, ttl time.Duration) string {
+	query := url.Values{}
+	if ttl > 0 {
+		query.Set("ttl", ttl.String())
+	}
+	switch item.(type) {
+	case storage.Set:
+		query.Set("type", "set")
+	case *storage.SortedSet:
+		query.Set("type", "zset")
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
 }
 
 // GetKeys call retruns slice of all the keys stored in Gedis at the moment
 // or an error if appeared
 func (client *GedisClient) GetKeys() ([]string, error) {
-	respose, err := http.Get(client.fullURL("keys"))
-	if err != nil {
-		return nil, err
-	}
-
-	body, err := ioutil.ReadAll(io.LimitReader(respose.Body, 1048576))
-	if err != nil {
-		return nil, err
-	}
+	return client.GetKeysContext(context.Background())
+}
 
-	if err = respose.Body.Close(); err != nil {
+// GetKeysContext ...
+func (client *GedisClient) GetKeysContext(ctx context.Context) ([]string, error) {
+	keys := []string{}
+	if _, err := client.call(ctx, http.MethodGet, "keys", nil, &keys); err != nil {
 		return nil, err
 	}
-
-	var keys []string
-	if err = json.Unmarshal(body, &keys); err == nil {
-		return keys, nil
-	}
-	return nil, err
+	return keys, nil
 }
 
 // GetItem ...
 func (client *GedisClient) GetItem(key string) (storage.Storable, bool, error) {
-	response, err := http.Get(client.fullURL("entries/" + key))
-	return handleGetResult(response, err)
+	return client.GetItemContext(context.Background(), key)
+}
+
+// GetItemContext ...
+func (client *GedisClient) GetItemContext(ctx context.Context, key string) (storage.Storable, bool, error) {
+	item, _, _, exists, err := client.getEntry(ctx, "entries/"+key)
+	return item, exists, err
+}
+
+// GetItemWithExpiry works as GetItem but also returns the moment of the item expiration
+func (client *GedisClient) GetItemWithExpiry(key string) (storage.Storable, time.Time, bool, error) {
+	return client.GetItemWithExpiryContext(context.Background(), key)
+}
+
+// GetItemWithExpiryContext ...
+func (client *GedisClient) GetItemWithExpiryContext(ctx context.Context, key string) (storage.Storable, time.Time, bool, error) {
+	item, expireAt, _, exists, err := client.getEntry(ctx, "entries/"+key)
+	return item, expireAt, exists, err
 }
 
 // UpdateItem ...
 func (client *GedisClient) UpdateItem(key string, item storage.Storable) error {
-	bts, err := json.Marshal(item)
-	if err != nil {
-		return err
-	}
+	return client.UpdateItemWithTTLContext(context.Background(), key, item, 0)
+}
 
-	request, err := http.NewRequest(
-		http.MethodPut,
-		client.fullURL("entries/"+key),
-		bytes.NewBuffer(bts),
-	)
-	if err != nil {
-		return err
-	}
-	response, err := http.DefaultClient.Do(request)
-	if response.StatusCode != http.StatusNoContent {
-		err = errors.New("Unexpected response status code")
-	}
-	return err
+// UpdateItemContext ...
+func (client *GedisClient) UpdateItemContext(ctx context.Context, key string, item storage.Storable) error {
+	return client.UpdateItemWithTTLContext(ctx, key, item, 0)
+}
+
+// UpdateItemWithTTL works as UpdateItem but sets the item specific TTL.
+// Non-positive ttl means the default TTL of the server
+func (client *GedisClient) UpdateItemWithTTL(key string, item storage.Storable, ttl time.Duration) error {
+	return client.UpdateItemWithTTLContext(context.Background(), key, item, ttl)
+}
+
+// UpdateItemWithTTLContext ...
+func (client *GedisClient) UpdateItemWithTTLContext(ctx context.Context, key string, item storage.Storable, ttl time.Duration) error {
+	return client.expectStatus(ctx, http.MethodPut, "entries/"+key+entryQuery(item, ttl), item, http.StatusNoContent)
 }
 
 // AppendItem ...
 func (client *GedisClient) AppendItem(key string, item storage.Storable) error {
-	bts, _ := json.Marshal(item)
-	response, err := http.Post(client.fullURL("entries/"+key), "application/json; charset=UTF-8", bytes.NewBuffer(bts))
+	return client.AppendItemWithTTLContext(context.Background(), key, item, 0)
+}
 
-	if response.StatusCode != http.StatusCreated {
-		err = errors.New("Unexpected response status code")
-	}
+// AppendItemContext ...
+func (client *GedisClient) AppendItemContext(ctx context.Context, key string, item storage.Storable) error {
+	return client.AppendItemWithTTLContext(ctx, key, item, 0)
+}
 
-	return err
+// AppendItemWithTTL works as AppendItem but sets the item specific TTL.
+// Non-positive ttl means the default TTL of the server
+func (client *GedisClient) AppendItemWithTTL(key string, item storage.Storable, ttl time.Duration) error {
+	return client.AppendItemWithTTLContext(context.Background(), key, item, ttl)
+}
+
+// AppendItemWithTTLContext ...
+func (client *GedisClient) AppendItemWithTTLContext(ctx context.Context, key string, item storage.Storable, ttl time.Duration) error {
+	return client.expectStatus(ctx, http.MethodPost, "entries/"+key+entryQuery(item, ttl), item, http.StatusCreated)
 }
 
 // DeleteItem ...
 func (client *GedisClient) DeleteItem(key string) error {
-	request, _ := http.NewRequest(
-		http.MethodDelete,
-		client.fullURL("entries/"+key),
-		bytes.NewBuffer([]byte{}),
-	)
-	response, error := http.DefaultClient.Do(request)
-
-	if response.StatusCode != http.StatusNoContent {
-		error = errors.New("Unexpected response status code")
-	}
+	return client.DeleteItemContext(context.Background(), key)
+}
 
-	return error
+// DeleteItemContext ...
+func (client *GedisClient) DeleteItemContext(ctx context.Context, key string) error {
+	return client.expectStatus(ctx, http.MethodDelete, "entries/"+key, nil, http.StatusNoContent)
+}
+
+// expectStatus call performs the request and turns any status but the expected one into an error
+func (client *GedisClient) expectStatus(ctx context.Context, method string, path string, body interface{}, expected int) error {
+	statusCode, responseBody, err := client.do(ctx, method, path, body)
+	if err == nil && statusCode != expected {
+		err = statusError(statusCode, responseBody)
+	}
+	return err
 }
 
 // GetItemByNestedIndex ...
 func (client *GedisClient) GetItemByNestedIndex(key string, index string) (storage.Storable, bool, error) {
-	response, err := http.Get(client.fullURL("entries/" + key + "/elements/" + index)) // TODO make index numeric
-	return handleGetResult(response, err)
+	return client.GetItemByNestedIndexContext(context.Background(), key, index)
+}
+
+// GetItemByNestedIndexContext ...
+func (client *GedisClient) GetItemByNestedIndexContext(ctx context.Context, key string, index string) (storage.Storable, bool, error) {
+	item, _, _, exists, err := client.getEntry(ctx, "entries/"+key+"/elements/"+index) // TODO make index numeric
+	return item, exists, err
 }
 
 // GetItemByNestedKey ...
 func (client *GedisClient) GetItemByNestedKey(key string, subKey string) (storage.Storable, bool, error) {
-	response, err := http.Get(client.fullURL("entries/" + key + "/entries/" + subKey))
-	return handleGetResult(response, err)
+	return client.GetItemByNestedKeyContext(context.Background(), key, subKey)
+}
+
+// GetItemByNestedKeyContext ...
+func (client *GedisClient) GetItemByNestedKeyContext(ctx context.Context, key string, subKey string) (storage.Storable, bool, error) {
+	item, _, _, exists, err := client.getEntry(ctx, "entries/"+key+"/entries/"+subKey)
+	return item, exists, err
 }
@@ -1,21 +1,48 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/izhamoidsin/gedis/raft"
 	"github.com/izhamoidsin/gedis/server"
+	"github.com/izhamoidsin/gedis/slots"
 	"github.com/izhamoidsin/gedis/storage"
 )
 
-var storageRegistry storage.Storage = storage.InitSyncStorage(time.Minute)
+var storageRegistry storage.Storage = storage.InitSyncMapStorage(time.Minute)
 var srv = server.CreateServer(storageRegistry)
 
 var client = CreateClient("localhost", 8088)
 
 func TestRunTestServer(t *testing.T) {
 	go func() { log.Fatal(srv.StartSerever(8088)) }()
+	if !waitForServer(client) {
+		t.Fatal("Test server has not been started")
+	}
+}
+
+// waitForServer call waits until the server is ready to accept connections
+func waitForServer(client *GedisClient) bool {
+	for i := 0; i < 50; i++ {
+		if response, err := http.Get(client.fullURL("heartbeat")); err == nil {
+			response.Body.Close()
+			return true
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	return false
 }
 
 func TestSaveGetUpdateAndDelete(t *testing.T) {
@@ -64,3 +91,974 @@ func TestNestedOps(t *testing.T) {
 		t.Error("Can not get entry by key")
 	}
 }
+
+func TestTTL(t *testing.T) {
+	key, value := "ttl", "short living value"
+	ttl := time.Second * 30
+
+	if error := client.AppendItemWithTTL(key, value, ttl); error != nil {
+		t.Error("Can not save item with TTL. " + error.Error())
+	}
+	storedVal, expireAt, exists, err := client.GetItemWithExpiry(key)
+	if err != nil || !exists || storedVal != value {
+		t.Error("Can not get test value with TTL back")
+	}
+	if left := time.Until(expireAt); left > ttl || left < ttl-time.Second*5 {
+		t.Error("Expiration time does not match the TTL of the item")
+	}
+	if error := client.UpdateItemWithTTL(key, value, time.Hour); error != nil {
+		t.Error("Can not update item with TTL. " + error.Error())
+	}
+	if _, expireAt, _, _ := client.GetItemWithExpiry(key); time.Until(expireAt) < ttl {
+		t.Error("Expiration time is not prolonged by update")
+	}
+}
+
+func TestListOps(t *testing.T) {
+	key := "list"
+
+	if length, error := client.PushToList(key, false, "Bravo", "Charlie"); error != nil || length != 2 {
+		t.Error("Can not push to the tail of the list")
+	}
+	if length, error := client.PushToList(key, true, "Alpha"); error != nil || length != 3 {
+		t.Error("Can not push to the head of the list")
+	}
+	if elements, error := client.GetListRange(key, 0, -1); error != nil || !reflect.DeepEqual(elements, []string{"Alpha", "Bravo", "Charlie"}) {
+		t.Error("Can not get the range of the list")
+	}
+	if error := client.SetListElement(key, -1, "Delta"); error != nil {
+		t.Error("Can not set element of the list")
+	}
+	if element, exists, error := client.GetItemByNestedIndex(key, "2"); error != nil || !exists || element != "Delta" {
+		t.Error("Can not get element of the list just set")
+	}
+	if error := client.SetListElement(key, 10, "Echo"); error == nil {
+		t.Error("Outbounding index does not lead to error")
+	}
+	if element, exists, error := client.PopFromList(key, true); error != nil || !exists || element != "Alpha" {
+		t.Error("Can not pop from the head of the list")
+	}
+	if error := client.TrimList(key, 0, 0); error != nil {
+		t.Error("Can not trim the list")
+	}
+	if length, error := client.GetListLength(key); error != nil || length != 1 {
+		t.Error("Length of the trimmed list does not match")
+	}
+	if element, exists, error := client.PopFromList(key, false); error != nil || !exists || element != "Bravo" {
+		t.Error("Can not pop from the tail of the list")
+	}
+	if _, exists, error := client.PopFromList(key, false); error != nil || exists {
+		t.Error("Pop from the empty list does not report absence of the element")
+	}
+	for method, path := range map[string]string{
+		http.MethodPost: "entries/" + key + "/elements",
+		http.MethodPut:  "entries/" + key + "/elements/0",
+	} {
+		request, _ := http.NewRequest(method, client.fullURL(path), strings.NewReader(`["unterminated`))
+		response, error := http.DefaultClient.Do(request)
+		if error != nil {
+			t.Fatal("Can not send malformed list element. " + error.Error())
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Malformed list element sent with %s is responded with %d", method, response.StatusCode)
+		}
+	}
+}
+
+func TestDictOps(t *testing.T) {
+	key := "dict"
+
+	if created, error := client.SetDictEntry(key, "1", "One"); error != nil || !created {
+		t.Error("Can not set entry of a new dictionary")
+	}
+	if created, error := client.SetDictEntry(key, "1", "Uno"); error != nil || created {
+		t.Error("Update of the dictionary entry is reported as creation")
+	}
+	client.SetDictEntry(key, "2", "Two")
+	if entries, error := client.GetDictEntries(key); error != nil || !reflect.DeepEqual(entries, map[string]string{"1": "Uno", "2": "Two"}) {
+		t.Error("Can not get entries of the dictionary")
+	}
+	if keys, error := client.GetDictKeys(key); error != nil || !reflect.DeepEqual(keys, []string{"1", "2"}) {
+		t.Error("Can not get keys of the dictionary")
+	}
+	if length, error := client.GetDictLength(key); error != nil || length != 2 {
+		t.Error("Can not get length of the dictionary")
+	}
+	if exists, error := client.DictEntryExists(key, "2"); error != nil || !exists {
+		t.Error("Existing dictionary entry is not found")
+	}
+	if deleted, error := client.DeleteDictEntry(key, "2"); error != nil || !deleted {
+		t.Error("Can not delete entry of the dictionary")
+	}
+	if exists, error := client.DictEntryExists(key, "2"); error != nil || exists {
+		t.Error("Deleted dictionary entry is still found")
+	}
+	request, _ := http.NewRequest(http.MethodPut, client.fullURL("entries/"+key+"/entries/3"), strings.NewReader(`{"unterminated`))
+	response, error := http.DefaultClient.Do(request)
+	if error != nil {
+		t.Fatal("Can not send malformed dictionary value. " + error.Error())
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Malformed dictionary value is responded with %d", response.StatusCode)
+	}
+	client.AppendItem("not_dict", "string value")
+	if _, error := client.SetDictEntry("not_dict", "1", "One"); error == nil {
+		t.Error("Setting entry of non-dictionary value does not lead to error")
+	}
+}
+
+func TestSetOps(t *testing.T) {
+	key := "set"
+
+	if added, error := client.AddToSet(key, "Alpha", "Bravo", "Alpha"); error != nil || added != 2 {
+		t.Error("Can not add members to a new set")
+	}
+	if isMember, error := client.IsSetMember(key, "Bravo"); error != nil || !isMember {
+		t.Error("Member of the set is not found")
+	}
+	if cardinality, error := client.GetSetCardinality(key); error != nil || cardinality != 2 {
+		t.Error("Can not get cardinality of the set")
+	}
+	if item, exists, error := client.GetItem(key); error != nil || !exists || !reflect.DeepEqual(item, storage.NewSet("Alpha", "Bravo")) {
+		t.Error("Set is not returned as a whole entry")
+	}
+	if removed, error := client.RemoveFromSet(key, "Bravo"); error != nil || removed != 1 {
+		t.Error("Can not remove member of the set")
+	}
+	if popped, error := client.PopRandomSetMembers(key, 5); error != nil || !reflect.DeepEqual(popped, []string{"Alpha"}) {
+		t.Error("Can not pop members of the set")
+	}
+
+	client.AppendItem("set_a", storage.NewSet("1", "2", "3"))
+	client.AppendItem("set_b", storage.NewSet("2", "3", "4"))
+	if members, error := client.CombineSets(storage.SetIntersection, "set_a", "set_b"); error != nil || !reflect.DeepEqual(members, []string{"2", "3"}) {
+		t.Error("Can not get intersection of the sets")
+	}
+	if cardinality, error := client.StoreCombinedSets(storage.SetUnion, "set_c", "set_a", "set_b"); error != nil || cardinality != 4 {
+		t.Error("Can not store union of the sets")
+	}
+	if members, error := client.GetRandomSetMembers("set_c", 2); error != nil || len(members) != 2 {
+		t.Error("Can not get random members of the set")
+	}
+	if _, error := client.CombineSets("symmetric", "set_a"); error == nil {
+		t.Error("Unknown set operation does not lead to error")
+	}
+}
+
+func TestSortedSetOps(t *testing.T) {
+	key := "board"
+
+	if added, error := client.AddToSortedSet(key, storage.ScoredMember{Member: "alice", Score: 10}, storage.ScoredMember{Member: "bob", Score: 20}); error != nil || added != 2 {
+		t.Error("Can not add members to a new sorted set")
+	}
+	if score, error := client.IncrementSortedSetScore(key, "carol", 15.5); error != nil || score != 15.5 {
+		t.Error("Can not increment score of a new member")
+	}
+	if score, exists, error := client.GetSortedSetScore(key, "bob"); error != nil || !exists || score != 20 {
+		t.Error("Can not get score of the member")
+	}
+	if rank, exists, error := client.GetSortedSetRank(key, "bob", true); error != nil || !exists || rank != 0 {
+		t.Error("Can not get reverse rank of the member")
+	}
+	if _, exists, error := client.GetSortedSetRank(key, "dave", false); error != nil || exists {
+		t.Error("Rank of missing member is found")
+	}
+	if cardinality, error := client.GetSortedSetCardinality(key); error != nil || cardinality != 3 {
+		t.Error("Can not get cardinality of the sorted set")
+	}
+	expected := []storage.ScoredMember{{Member: "carol", Score: 15.5}, {Member: "alice", Score: 10}}
+	if members, error := client.GetSortedSetRangeByRank(key, 1, 2, true); error != nil || !reflect.DeepEqual(members, expected) {
+		t.Error("Can not get range of the sorted set by rank")
+	}
+	scoreRange := storage.ScoreRange{Min: 10, Max: math.Inf(1), MinExclusive: true}
+	if members, error := client.GetSortedSetRangeByScore(key, scoreRange, false, 0, 1); error != nil || !reflect.DeepEqual(members, expected[:1]) {
+		t.Error("Can not get range of the sorted set by score")
+	}
+	if item, exists, error := client.GetItem(key); error != nil || !exists || reflect.TypeOf(item) != reflect.TypeOf(&storage.SortedSet{}) {
+		t.Error("Sorted set is not returned as a whole entry")
+	}
+	if popped, error := client.PopFromSortedSet(key, false, 1); error != nil || !reflect.DeepEqual(popped, expected[1:]) {
+		t.Error("Can not pop the member with the lowest score")
+	}
+	if removed, error := client.RemoveFromSortedSet(key, "bob", "carol"); error != nil || removed != 2 {
+		t.Error("Can not remove members of the sorted set")
+	}
+}
+
+func TestCounters(t *testing.T) {
+	key := "counter"
+
+	if value, error := client.Increment(key); error != nil || value != 1 {
+		t.Error("Can not create a counter")
+	}
+	if value, error := client.IncrementBy(key, 10); error != nil || value != 11 {
+		t.Error("Can not increment the counter")
+	}
+	if value, error := client.DecrementBy(key, 2); error != nil || value != 9 {
+		t.Error("Can not decrement the counter")
+	}
+	if value, error := client.IncrementByFloat(key, 1); error != nil || value != 10 {
+		t.Error("Can not increment the counter by float")
+	}
+	if value, error := client.IncrementByFloat(key, -0.25); error != nil || value != 9.75 {
+		t.Error("Can not decrement the counter by float")
+	}
+	if _, error := client.Increment(key); error == nil {
+		t.Error("Incrementing float counter by integer does not lead to error")
+	}
+	if item, _, _ := client.GetItem(key); item != "9.75" {
+		t.Error("Counter is not stored as a string")
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	key := "cas"
+	client.AppendItem(key, "1")
+
+	_, version, exists, error := client.GetItemWithVersion(key)
+	if error != nil || !exists || version == 0 {
+		t.Fatal("Can not get version of the item")
+	}
+	newVersion, error := client.UpdateItemIfVersion(key, "2", version)
+	if error != nil || newVersion <= version {
+		t.Error("Can not update the item of the expected version")
+	}
+	_, error = client.UpdateItemIfVersion(key, "3", version)
+	if conflict, isConflict := error.(*VersionConflictError); !isConflict || conflict.ActualVersion != newVersion {
+		t.Error("Stale version does not lead to conflict error")
+	}
+	if item, _, _ := client.GetItem(key); item != "2" {
+		t.Error("Item is overridden in spite of conflict")
+	}
+	if error := client.DeleteItemIfVersion(key, version); error == nil {
+		t.Error("Item is deleted in spite of stale version")
+	}
+	if error := client.DeleteItemIfVersion(key, newVersion); error != nil {
+		t.Error("Can not delete the item of the expected version")
+	}
+	_, error = client.UpdateItemIfVersion(key, "4", newVersion)
+	if conflict, isConflict := error.(*VersionConflictError); !isConflict || conflict.ActualVersion != 0 {
+		t.Error("Update of deleted item does not lead to conflict error")
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	client.AppendItem("tx_stock", "10")
+	_, version, _, _ := client.GetItemWithVersion("tx_stock")
+
+	results, error := client.Transaction().
+		Watch("tx_stock", version).
+		Watch("tx_order", 0).
+		IncrementBy("tx_stock", -3).
+		Set("tx_order", map[string]string{"item": "apple", "count": "3"}).
+		PushToList("tx_log", false, "ordered 3 apples").
+		Get("tx_stock").
+		Exec()
+	if error != nil || len(results) != 4 {
+		t.Fatal("Can not execute the transaction")
+	}
+	var stock int64
+	var stored string
+	if results.Decode(0, &stock); stock != 7 {
+		t.Error("Result of the increment does not match")
+	}
+	if results.Decode(3, &stored); stored != "7" {
+		t.Error("Transaction does not see its own changes")
+	}
+
+	if _, error := client.Transaction().Watch("tx_stock", version).IncrementBy("tx_stock", -3).Exec(); error != storage.ErrTransactionAborted {
+		t.Error("Change of the watched item does not abort the transaction")
+	}
+	if _, error := client.Transaction().IncrementBy("tx_stock", -3).IncrementBy("tx_order", 1).Exec(); error == nil {
+		t.Error("Failed command does not fail the transaction")
+	}
+	if item, _, _ := client.GetItem("tx_stock"); item != "7" {
+		t.Error("Changes of failed transactions are applied")
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	news, error := client.Subscribe(ctx, "news.sport")
+	if error != nil {
+		t.Fatal("Can not subscribe to the channel. " + error.Error())
+	}
+	all, error := client.PSubscribe(ctx, "news.*")
+	if error != nil {
+		t.Fatal("Can not subscribe to the pattern. " + error.Error())
+	}
+	if received, error := client.Publish("news.sport", "goal"); error != nil || received != 2 {
+		t.Error("Message is not received by both subscribers")
+	}
+	if message := <-news; message.Channel != "news.sport" || message.Payload != "goal" {
+		t.Error("Channel subscriber receives wrong message")
+	}
+	if message := <-all; message.Pattern != "news.*" || message.Payload != "goal" {
+		t.Error("Pattern subscriber receives wrong message")
+	}
+	if received, _ := client.Publish("weather", "rain"); received != 0 {
+		t.Error("Message is received by subscribers of other channels")
+	}
+	for _, channel := range []string{"__keyspace__:news.sport", "__keyevent__:del"} {
+		if _, error := client.Publish(channel, "fake"); error == nil {
+			t.Error("Message is published to reserved channel " + channel)
+		}
+	}
+
+	cancel()
+	for range news {
+	}
+	if _, error := client.PSubscribe(context.Background(), "news.[a"); error == nil {
+		t.Error("Malformed pattern is accepted")
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, error := client.Notifications(ctx, "ntf_*", storage.MutationAppend, storage.MutationDelete, storage.MutationExpire)
+	if error != nil {
+		t.Fatal("Can not subscribe to notifications. " + error.Error())
+	}
+	client.AppendItem("ntf_key", "1")
+	client.UpdateItem("ntf_key", "2")
+	client.AppendItem("other_key", "1")
+	client.DeleteItem("ntf_key")
+	client.AppendItemWithTTL("ntf_short", "1", 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	client.GetItem("ntf_short")
+
+	expected := []KeyspaceEvent{
+		{"ntf_key", storage.MutationAppend},
+		{"ntf_key", storage.MutationDelete},
+		{"ntf_short", storage.MutationAppend},
+		{"ntf_short", storage.MutationExpire},
+	}
+	for _, expectedEvent := range expected {
+		if event := <-events; event != expectedEvent {
+			t.Error("Unexpected notification " + string(event.Event) + " of " + event.Key)
+		}
+	}
+
+	if _, error := client.Notifications(ctx, "", "unknown"); error == nil {
+		t.Error("Unknown event type is accepted")
+	}
+}
+
+func TestWatchItem(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	states, error := client.WatchItem(ctx, "watched")
+	if error != nil {
+		t.Fatal("Can not watch the item. " + error.Error())
+	}
+	if state := <-states; state.Exists || state.Version != 0 {
+		t.Error("Absent item is not reported as absent")
+	}
+	client.AppendItem("watched", "1")
+	if state := <-states; !state.Exists || state.Item != "1" || state.Version == 0 {
+		t.Error("Creation of the item is not reported")
+	}
+	client.UpdateItem("watched", "2")
+	if state := <-states; state.Item != "2" {
+		t.Error("Update of the item is not reported")
+	}
+	client.DeleteItem("watched")
+	if state := <-states; state.Exists {
+		t.Error("Removal of the item is not reported")
+	}
+
+	start := time.Now()
+	response, error := http.Get(client.fullURL("entries/watched?watch=true&version=0&timeout=100ms"))
+	if error != nil || response.StatusCode != http.StatusNotModified || time.Since(start) < 100*time.Millisecond {
+		t.Error("Long poll does not wait for the timeout")
+	}
+	response, error = http.Get(client.fullURL("entries/watched?watch=true&version=42"))
+	if error != nil || response.StatusCode != http.StatusNotFound {
+		t.Error("Long poll does not return immediately if the version differs")
+	}
+}
+
+func TestScanKeys(t *testing.T) {
+	for i := 0; i < 25; i++ {
+		client.AppendItem("scan:"+strconv.Itoa(i), "value")
+	}
+	client.AppendItem("scan:list", []string{"value"})
+
+	seen := map[string]bool{}
+	it := client.IterateKeys("scan:*", "string", 4)
+	for it.Next() {
+		seen[it.Key()] = true
+	}
+	if it.Err() != nil || len(seen) != 25 || seen["scan:list"] {
+		t.Error("Iterator does not walk all the matching keys")
+	}
+
+	if keys, next, error := client.ScanKeys(0, 1000, "scan:list", ""); error != nil || len(keys) != 1 || next != 0 {
+		t.Error("Single page scan does not return the key")
+	}
+	if _, _, error := client.ScanKeys(0, 10, "", "unknown"); error == nil {
+		t.Error("Unknown type is accepted")
+	}
+	response, error := http.Get(client.fullURL("keys?match=" + url.QueryEscape("scan[a-")))
+	if error != nil {
+		t.Fatal("Can not scan with malformed pattern. " + error.Error())
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Scan with malformed pattern is responded with %d", response.StatusCode)
+	}
+}
+
+func TestBatchOps(t *testing.T) {
+	results, error := client.SetItems([]BatchEntry{
+		{Key: "batch_str", Item: "value"},
+		{Key: "batch_list", Item: []string{"a", "b"}, TTL: time.Hour},
+		{Key: "batch_set", Item: storage.NewSet("x", "y")},
+	}, false)
+	if error != nil || len(results) != 3 || !results[0].Stored || !results[2].Stored || results[1].Version == 0 {
+		t.Fatal("Can not store the batch")
+	}
+	if _, expireAt, _, _ := client.GetItemWithExpiry("batch_list"); time.Until(expireAt) < 59*time.Minute {
+		t.Error("Per key TTL of the batch is not applied")
+	}
+
+	results, _ = client.SetItems([]BatchEntry{{Key: "batch_str", Item: "other"}, {Key: "batch_new", Item: "new"}}, true)
+	if results[0].Stored || !results[1].Stored {
+		t.Error("Only if absent mode is not respected")
+	}
+
+	items, error := client.GetItems("batch_str", "batch_list", "batch_set", "batch_missing")
+	if error != nil || len(items) != 3 || items["batch_str"] != "value" ||
+		!reflect.DeepEqual(items["batch_list"], []string{"a", "b"}) || !reflect.DeepEqual(items["batch_set"], storage.NewSet("x", "y")) {
+		t.Error("Can not get the batch back")
+	}
+
+	if deleted, error := client.DeleteItems("batch_str", "batch_new", "batch_missing"); error != nil || deleted != 2 {
+		t.Error("Batch delete does not report existed keys")
+	}
+	if items, _ := client.GetItems("batch_str", "batch_new"); len(items) != 0 {
+		t.Error("Keys deleted in the batch still exist")
+	}
+}
+
+func TestReplication(t *testing.T) {
+	leaderStorage := storage.InitSyncMapStorage(time.Minute)
+	followerStorage := storage.InitShardedStorage(time.Minute, 4)
+	replica := storage.NewReplica(followerStorage)
+	followerServer := server.CreateServer(followerStorage)
+	followerServer.SetReplica(replica, "http://localhost:8089")
+	go func() { log.Fatal(server.CreateServer(leaderStorage).StartSerever(8089)) }()
+	go func() { log.Fatal(followerServer.StartSerever(8090)) }()
+	leader, follower := CreateClient("localhost", 8089), CreateClient("localhost", 8090)
+	if !waitForServer(leader) || !waitForServer(follower) {
+		t.Fatal("Test servers have not been started")
+	}
+
+	leader.AppendItem("replicated", "before sync")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if error := leader.Replicate(ctx, replica); error != nil {
+		t.Fatal("Can not replicate. " + error.Error())
+	}
+	// the write is redirected to the leader and comes back with the mutation stream
+	if error := follower.UpdateItem("replicated", "after sync"); error != nil {
+		t.Fatal("Write to the follower is not redirected. " + error.Error())
+	}
+	if item, _, _ := leader.GetItem("replicated"); item != "after sync" {
+		t.Error("Write to the follower does not reach the leader")
+	}
+	replicated := false
+	for i := 0; i < 50 && !replicated; i++ {
+		item, _, _ := follower.GetItem("replicated")
+		if replicated = item == "after sync"; !replicated {
+			time.Sleep(time.Millisecond * 100)
+		}
+	}
+	if !replicated {
+		t.Fatal("Write is not replicated to the follower")
+	}
+	// records are longer than the default limit of bufio.Scanner
+	big := strings.Repeat("x", 100*1024)
+	leader.AppendItem("big", big)
+	replicated = false
+	for i := 0; i < 50 && !replicated; i++ {
+		item, _, _ := follower.GetItem("big")
+		if replicated = item == big; !replicated {
+			time.Sleep(time.Millisecond * 100)
+		}
+	}
+	if !replicated {
+		t.Fatal("Big entry is not replicated to the follower")
+	}
+
+	response, error := http.Get(follower.fullURL("admin/replication"))
+	if error != nil {
+		t.Fatal("Can not get replication status. " + error.Error())
+	}
+	defer response.Body.Close()
+	var status struct {
+		Role   string `json:"role"`
+		Synced bool   `json:"synced"`
+		Offset uint64 `json:"offset"`
+		Lag    uint64 `json:"lag"`
+	}
+	if json.NewDecoder(response.Body).Decode(&status); status.Role != "follower" || !status.Synced || status.Offset != 2 || status.Lag != 0 {
+		t.Errorf("Unexpected replication status: %+v", status)
+	}
+}
+
+func TestClusterMode(t *testing.T) {
+	members := []string{"localhost:8091", "localhost:8092", "localhost:8093"}
+	for _, id := range members {
+		registry := storage.InitSyncMapStorage(time.Minute)
+		node := raft.NewNode(raft.Config{ID: id, Members: members, ElectionTimeout: 100 * time.Millisecond}, registry, raft.NewHTTPTransport(nil))
+		nodeServer := server.CreateServer(registry)
+		nodeServer.SetRaftNode(node)
+		port, _ := strconv.Atoi(id[len("localhost:"):])
+		go func() { log.Fatal(nodeServer.StartSerever(port)) }()
+		node.Start()
+		defer node.Stop()
+	}
+	nodeClient := CreateClient("localhost", 8093)
+	if !waitForServer(nodeClient) {
+		t.Fatal("Test servers have not been started")
+	}
+
+	put := func(query string, value string) int {
+		request, _ := http.NewRequest(http.MethodPut, nodeClient.fullURL("cluster/entries/lock"+query), strings.NewReader(value))
+		response, error := http.DefaultClient.Do(request)
+		if error != nil {
+			t.Fatal("Can not store through the cluster. " + error.Error())
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	// requests are redirected to the leader once it is elected
+	elected := false
+	for i := 0; i < 50 && !elected; i++ {
+		if elected = put("?nx=true", `"owner1"`) == http.StatusNoContent; !elected {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	if !elected {
+		t.Fatal("Cluster does not accept writes")
+	}
+	if status := put("?nx=true", `"owner2"`); status != http.StatusPreconditionFailed {
+		t.Errorf("Lock is acquired twice: %d", status)
+	}
+	if error := nodeClient.UpdateItem("lock", "owner2"); error == nil {
+		t.Error("Write bypassing the cluster is accepted")
+	}
+	// writes which are not replicated through the log are not served at all
+	for path, body := range map[string]string{
+		"entries/list/elements": `"element"`,
+		"batch/set":             `{"entries": [{"key": "batched", "value": "value"}]}`,
+		"tx":                    `{"commands": [{"command": "set", "key": "tx", "value": "value"}]}`,
+	} {
+		response, error := http.Post(nodeClient.fullURL(path), "application/json", strings.NewReader(body))
+		if error != nil {
+			t.Fatal("Can not write bypassing the cluster. " + error.Error())
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusNotImplemented {
+			t.Errorf("Write to %s bypassing the cluster is responded with %d", path, response.StatusCode)
+		}
+	}
+
+	response, error := http.Get(nodeClient.fullURL("cluster/entries/lock"))
+	if error != nil {
+		t.Fatal("Can not read through the cluster. " + error.Error())
+	}
+	defer response.Body.Close()
+	var value string
+	if json.NewDecoder(response.Body).Decode(&value); value != "owner1" {
+		t.Error("Linearizable read returns " + value)
+	}
+}
+
+func TestSlotRouting(t *testing.T) {
+	nodes := []string{"localhost:8094", "localhost:8095"}
+	storages := make(map[string]*storage.SyncMapStorage)
+	for _, node := range nodes {
+		registry := storage.InitSyncMapStorage(time.Minute)
+		nodeServer := server.CreateServer(registry)
+		nodeServer.SetSlots(node, slots.SplitEvenly(nodes...))
+		storages[node] = registry
+		port, _ := strconv.Atoi(node[len("localhost:"):])
+		go func() { log.Fatal(nodeServer.StartSerever(port)) }()
+	}
+	nodeClient := CreateClient("localhost", 8094)
+	if !waitForServer(nodeClient) || !waitForServer(CreateClient("localhost", 8095)) {
+		t.Fatal("Test servers have not been started")
+	}
+	key := "slotted"
+	for i := 0; slots.KeySlot(key) < slots.Count/2; i++ {
+		key = "slotted" + strconv.Itoa(i)
+	}
+	slot := slots.KeySlot(key)
+
+	// the request to the first node is redirected to the owner of the slot
+	if error := nodeClient.AppendItem(key, "value"); error != nil {
+		t.Fatal("Write is not redirected to the owner. " + error.Error())
+	}
+	if _, exists := storages["localhost:8095"].GetValueByKey(key); !exists {
+		t.Fatal("Entry is not stored by the owner of the slot")
+	}
+	if _, error := nodeClient.GetItems(key); error == nil {
+		t.Error("Multi-key request is accepted in slot mode")
+	}
+
+	if error := nodeClient.LoadSlots(); error != nil {
+		t.Fatal("Can not load slots. " + error.Error())
+	}
+	if ranges := nodeClient.Slots(); len(ranges) != 2 || ranges[1].Node != "localhost:8095" {
+		t.Errorf("Unexpected slot map: %v", ranges)
+	}
+	if owner := nodeClient.slotOwner("entries/" + key); owner != "localhost:8095" {
+		t.Error("Entry is routed to " + owner)
+	}
+
+	response, error := http.Post("http://localhost:8095/admin/slots/"+strconv.Itoa(slot)+"/migrate?to=localhost:8094", "", nil)
+	if error != nil {
+		t.Fatal("Can not migrate the slot. " + error.Error())
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Slot migration failed with %d", response.StatusCode)
+	}
+	if _, exists := storages["localhost:8095"].GetValueByKey(key); exists {
+		t.Error("Migrated entry is kept by the old owner")
+	}
+	// the client still routes to the old owner which redirects it to the new one
+	if item, _, error := nodeClient.GetItem(key); error != nil || item != "value" {
+		t.Fatal("Migrated entry is not found")
+	}
+	if owner := nodeClient.slotOwner("entries/" + key); owner != "localhost:8094" {
+		t.Error("Client does not learn the new owner of the slot, it routes to " + owner)
+	}
+
+	// a migration to a slow node does not lock other slots, the migrating one rejects writes only
+	importing, release := make(chan struct{}), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(importing)
+		<-release
+		http.Error(w, "Import failed", http.StatusInternalServerError)
+	}))
+	defer slow.Close()
+	migrating, neighbour := "", ""
+	for i := 0; neighbour == ""; i++ {
+		candidate := "migrating" + strconv.Itoa(i)
+		switch candidateSlot := slots.KeySlot(candidate); {
+		case candidateSlot < slots.Count/2 || candidateSlot == slot:
+		case migrating == "":
+			migrating = candidate
+		case candidateSlot != slots.KeySlot(migrating) && candidateSlot%256 == slots.KeySlot(migrating)%256:
+			// the slots share the lock stripe
+			neighbour = candidate
+		}
+	}
+	storages["localhost:8095"].AppendNewValue(migrating, "value")
+	storages["localhost:8095"].AppendNewValue(neighbour, "value")
+	put := func(key string) int {
+		request, _ := http.NewRequest(http.MethodPut, "http://localhost:8095/entries/"+key, strings.NewReader(`"updated"`))
+		response, error := http.DefaultClient.Do(request)
+		if error != nil {
+			t.Fatal("Can not update the entry. " + error.Error())
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	migrated := make(chan int)
+	go func() {
+		migrateURL := "http://localhost:8095/admin/slots/" + strconv.Itoa(slots.KeySlot(migrating)) + "/migrate?to=" + strings.TrimPrefix(slow.URL, "http://")
+		response, error := http.Post(migrateURL, "", nil)
+		if error != nil {
+			migrated <- 0
+			return
+		}
+		response.Body.Close()
+		migrated <- response.StatusCode
+	}()
+	<-importing
+	if status := put(migrating); status != http.StatusServiceUnavailable {
+		t.Errorf("Write to the migrating slot is responded with %d", status)
+	}
+	if status := put(neighbour); status != http.StatusNoContent {
+		t.Errorf("Write to the slot sharing the lock with the migrating one is responded with %d", status)
+	}
+	if response, error := http.Get("http://localhost:8095/entries/" + migrating); error != nil || response.StatusCode != http.StatusOK {
+		t.Error("Entry of the migrating slot is not read")
+	} else {
+		response.Body.Close()
+	}
+	close(release)
+	if status := <-migrated; status != http.StatusBadGateway {
+		t.Errorf("Failed migration is responded with %d", status)
+	}
+	if status := put(migrating); status != http.StatusNoContent {
+		t.Errorf("Write to the slot kept after the failed migration is responded with %d", status)
+	}
+
+	// nodes serving TLS redirect with the same scheme
+	secureServer := server.CreateServer(storage.InitSyncMapStorage(time.Minute))
+	secureServer.SetSlots("localhost:8443", slots.SplitEvenly("localhost:8443", "localhost:8444"))
+	secure := httptest.NewTLSServer(secureServer.Handler())
+	defer secure.Close()
+	// the transport does not follow redirects
+	request, _ := http.NewRequest(http.MethodGet, secure.URL+"/entries/"+key, nil)
+	if response, error := secure.Client().Transport.RoundTrip(request); error != nil {
+		t.Error("Can not read through TLS. " + error.Error())
+	} else {
+		response.Body.Close()
+		if location := response.Header.Get("Location"); location != "https://localhost:8444/entries/"+key {
+			t.Error("Request served with TLS is redirected to " + location)
+		}
+	}
+}
+
+func TestShardedRing(t *testing.T) {
+	sharded := CreateShardedClient(0,
+		ShardNode{Client: CreateClient("host1", 8081)},
+		ShardNode{Client: CreateClient("host2", 8081)},
+		ShardNode{Client: CreateClient("host3", 8081), Weight: 2})
+	owners := make(map[string]string)
+	shares := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		key := "key" + strconv.Itoa(i)
+		client, _ := sharded.ClientFor(key)
+		owners[key] = client.host
+		shares[client.host]++
+	}
+	if shares["host3:8081"] < 4000 || shares["host3:8081"] > 6000 || shares["host1:8081"] < 1500 || shares["host2:8081"] < 1500 {
+		t.Errorf("Keys are not spread according to the weights: %v", shares)
+	}
+	if first, _ := sharded.ClientFor("{user1}:name"); first != nil {
+		if second, _ := sharded.ClientFor("{user1}:email"); second != first {
+			t.Error("Keys sharing the hash tag belong to different nodes")
+		}
+	}
+
+	sharded.nodes[0].healthy = false
+	sharded.buildRing()
+	for key, owner := range owners {
+		client, _ := sharded.ClientFor(key)
+		if client.host == "host1:8081" || (owner != "host1:8081" && client.host != owner) {
+			t.Fatal("Key " + key + " of a healthy node is moved on ejection")
+		}
+	}
+	for _, node := range sharded.nodes {
+		node.healthy = false
+	}
+	sharded.buildRing()
+	if _, _, error := sharded.GetItem("key"); error != ErrNoNodes {
+		t.Error("Request is sent while all the nodes are ejected")
+	}
+}
+
+func TestShardedClient(t *testing.T) {
+	ports := []int{8096, 8097, 8098}
+	var nodes []ShardNode
+	for _, port := range ports {
+		nodes = append(nodes, ShardNode{Client: CreateClient("localhost", port)})
+	}
+	for i, node := range nodes[:2] {
+		nodeServer := server.CreateServer(storage.InitSyncMapStorage(time.Minute))
+		port := ports[i]
+		go func() { log.Fatal(nodeServer.StartSerever(port)) }()
+		if !waitForServer(node.Client) {
+			t.Fatal("Test servers have not been started")
+		}
+	}
+	sharded := CreateShardedClient(0, nodes...)
+	defer sharded.Close()
+	if error := sharded.StartHealthChecks(50*time.Millisecond, 2); error != nil {
+		t.Fatal("Can not start health checks. " + error.Error())
+	}
+	waitForNodes := func(count int) bool {
+		for i := 0; i < 50; i++ {
+			if len(sharded.HealthyNodes()) == count {
+				return true
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false
+	}
+	if !waitForNodes(2) {
+		t.Fatalf("Node which is down is not ejected: %v", sharded.HealthyNodes())
+	}
+
+	var keys []string
+	for i := 0; i < 50; i++ {
+		key := "sharded" + strconv.Itoa(i)
+		if error := sharded.AppendItem(key, strconv.Itoa(i)); error != nil {
+			t.Fatal("Can not store the item. " + error.Error())
+		}
+		keys = append(keys, key)
+	}
+	for _, node := range nodes[:2] {
+		if nodeKeys, _ := node.Client.GetKeys(); len(nodeKeys) == 0 || len(nodeKeys) == len(keys) {
+			t.Errorf("Keys are not spread over the nodes: %d on %s", len(nodeKeys), node.Client.host)
+		}
+	}
+	if allKeys, error := sharded.GetKeys(); error != nil || len(allKeys) != len(keys) {
+		t.Errorf("Keys of all the nodes are not gathered: %d (%v)", len(allKeys), error)
+	}
+	scanned := make(map[string]bool)
+	for it := sharded.IterateKeys("sharded*", "", 7); it.Next(); {
+		if scanned[it.Key()] {
+			t.Error("Key is scanned twice: " + it.Key())
+		}
+		scanned[it.Key()] = true
+	}
+	if len(scanned) != len(keys) {
+		t.Errorf("Not all the keys are scanned: %d", len(scanned))
+	}
+
+	if items, error := sharded.GetItems(keys...); error != nil || len(items) != len(keys) || items["sharded7"] != "7" {
+		t.Errorf("Items are not gathered from the nodes: %d (%v)", len(items), error)
+	}
+	results, error := sharded.SetItems([]BatchEntry{{Key: "sharded0", Item: "new"}, {Key: "sharded_new", Item: "new"}}, true)
+	if error != nil || len(results) != 2 || results[0].Key != "sharded0" || results[0].Stored || !results[1].Stored {
+		t.Errorf("Unexpected batch results: %+v (%v)", results, error)
+	}
+	if deleted, error := sharded.DeleteItems(keys[:10]...); error != nil || deleted != 10 {
+		t.Errorf("Items are not deleted from the nodes: %d (%v)", deleted, error)
+	}
+
+	tx := sharded.Transaction().Set("{sharded}:a", "1").IncrementBy("{sharded}:b", 2)
+	if _, error := tx.Exec(); error != nil {
+		t.Error("Transaction of keys sharing the hash tag fails. " + error.Error())
+	}
+	other := keys[0]
+	for _, key := range keys {
+		first, _ := sharded.ClientFor(keys[0])
+		if client, _ := sharded.ClientFor(key); client != first {
+			other = key
+		}
+	}
+	if _, error := sharded.Transaction().Delete(keys[0]).Delete(other).Exec(); error != ErrCrossNode {
+		t.Error("Transaction of keys on different nodes is sent")
+	}
+
+	nodeServer := server.CreateServer(storage.InitSyncMapStorage(time.Minute))
+	go func() { log.Fatal(nodeServer.StartSerever(8098)) }()
+	if !waitForNodes(3) {
+		t.Fatalf("Node which is up again is not added back: %v", sharded.HealthyNodes())
+	}
+	moved := false
+	for _, key := range keys {
+		client, _ := sharded.ClientFor(key)
+		moved = moved || client == nodes[2].Client
+	}
+	if !moved {
+		t.Error("No key is moved to the node added back")
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	for _, baseURL := range []string{"localhost:8088", "ftp://localhost", "http://", "http://localhost?db=1"} {
+		if _, error := NewClient(baseURL); error == nil {
+			t.Error("Invalid base URL is accepted: " + baseURL)
+		}
+	}
+
+	var received *http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gedis/entries/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		received = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`"value"`))
+	}))
+	defer proxy.Close()
+	optioned, error := NewClient(proxy.URL+"/gedis/", WithHTTPClient(proxy.Client()), WithTimeout(100*time.Millisecond),
+		WithUserAgent("gedis-test"), WithHeader("Authorization", "Bearer token"))
+	if error != nil {
+		t.Fatal("Can not create the client. " + error.Error())
+	}
+	if value, exists, error := optioned.GetItem("key"); error != nil || !exists || value != "value" {
+		t.Fatal("Can not get the item through the proxy")
+	}
+	if received.URL.Path != "/gedis/entries/key" || received.UserAgent() != "gedis-test" || received.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Request is not configured by the options: %v %v", received.URL, received.Header)
+	}
+	if _, _, error := optioned.GetItem("slow"); error == nil {
+		t.Error("Request is not limited by the timeout")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, error := optioned.GetItemContext(ctx, "key"); error == nil {
+		t.Error("Request is sent with a cancelled context")
+	}
+}
+
+func TestHTTPClientTimeoutOfLongLivingRequests(t *testing.T) {
+	streams := int32(0)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/channels/timed":
+			atomic.AddInt32(&streams, 1)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case r.URL.Query().Get("watch") == "true":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("ETag", `"2"`)
+			w.Write([]byte(`"changed"`))
+		default:
+			w.Header().Set("ETag", `"1"`)
+			w.Write([]byte(`"value"`))
+		}
+	}))
+	defer proxy.Close()
+	timed, error := NewClient(proxy.URL, WithHTTPClient(&http.Client{Timeout: 100 * time.Millisecond}))
+	if error != nil {
+		t.Fatal("Can not create the client. " + error.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, error := timed.Subscribe(ctx, "timed"); error != nil {
+		t.Fatal("Can not subscribe. " + error.Error())
+	}
+	states, error := timed.WatchItem(ctx, "key")
+	if error != nil {
+		t.Fatal("Can not watch the item. " + error.Error())
+	}
+	<-states
+	select {
+	case state := <-states:
+		if state.Item != "changed" {
+			t.Errorf("Unexpected state of the item: %v", state.Item)
+		}
+	case <-time.After(time.Second):
+		t.Error("Long poll is limited by the timeout of the HTTP client")
+	}
+	if atomic.LoadInt32(&streams) != 1 {
+		t.Error("Stream is limited by the timeout of the HTTP client")
+	}
+
+	// nothing listens there, transport errors should be returned instead of panics
+	down := CreateClient("localhost", 8099)
+	if error := down.UpdateItem("key", "value"); error == nil {
+		t.Error("Update of an unreachable server succeeded")
+	}
+	if _, _, error := down.GetItem("key"); error == nil {
+		t.Error("Get of an unreachable server succeeded")
+	}
+	if _, _, _, error := down.GetItemWithExpiry("key"); error == nil {
+		t.Error("Expiry of an unreachable server succeeded")
+	}
+	if error := down.DeleteItem("key"); error == nil {
+		t.Error("Delete of an unreachable server succeeded")
+	}
+}
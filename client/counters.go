@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Increment call atomically increments the integer counter by one, the counter is created at zero
+// if there is no such key. Returns the new value
+func (client *GedisClient) Increment(key string) (int64, error) {
+	return client.IncrementContext(context.Background(), key)
+}
+
+// IncrementContext ...
+func (client *GedisClient) IncrementContext(ctx context.Context, key string) (int64, error) {
+	return client.IncrementByContext(ctx, key, 1)
+}
+
+// Decrement works as Increment but decrements the counter by one
+func (client *GedisClient) Decrement(key string) (int64, error) {
+	return client.DecrementContext(context.Background(), key)
+}
+
+// DecrementContext ...
+func (client *GedisClient) DecrementContext(ctx context.Context, key string) (int64, error) {
+	return client.IncrementByContext(ctx, key, -1)
+}
+
+// IncrementBy call atomically adds delta to the integer counter
+func (client *GedisClient) IncrementBy(key string, delta int64) (int64, error) {
+	return client.IncrementByContext(context.Background(), key, delta)
+}
+
+// IncrementByContext ...
+func (client *GedisClient) IncrementByContext(ctx context.Context, key string, delta int64) (int64, error) {
+	var value int64
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/incr", delta, &value)
+	return value, err
+}
+
+// DecrementBy call atomically subtracts delta from the integer counter
+func (client *GedisClient) DecrementBy(key string, delta int64) (int64, error) {
+	return client.DecrementByContext(context.Background(), key, delta)
+}
+
+// DecrementByContext ...
+func (client *GedisClient) DecrementByContext(ctx context.Context, key string, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, errors.New("Decrement would overflow")
+	}
+	return client.IncrementByContext(ctx, key, -delta)
+}
+
+// IncrementByFloat call atomically adds delta to the numeric counter
+func (client *GedisClient) IncrementByFloat(key string, delta float64) (float64, error) {
+	return client.IncrementByFloatContext(context.Background(), key, delta)
+}
+
+// IncrementByFloatContext ...
+func (client *GedisClient) IncrementByFloatContext(ctx context.Context, key string, delta float64) (float64, error) {
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return 0, errors.New("Increment should be a finite number")
+	}
+	var value float64
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/incr", floatNumber(delta), &value)
+	return value, err
+}
+
+// floatNumber call keeps the fraction of the number, as the server treats numbers without it as integers
+func floatNumber(value float64) json.Number {
+	number := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(number, ".e") {
+		number += ".0"
+	}
+	return json.Number(number)
+}
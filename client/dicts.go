@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// SetDictEntry call atomically sets a single entry of the dictionary, the dictionary is created
+// if there is no such key. Returns true if the sub-key is a new one
+func (client *GedisClient) SetDictEntry(key string, subKey string, value string) (bool, error) {
+	return client.SetDictEntryContext(context.Background(), key, subKey, value)
+}
+
+// SetDictEntryContext ...
+func (client *GedisClient) SetDictEntryContext(ctx context.Context, key string, subKey string, value string) (bool, error) {
+	statusCode, responseBody, err := client.do(ctx, http.MethodPut, "entries/"+key+"/entries/"+subKey, value)
+	if err != nil {
+		return false, err
+	}
+	switch statusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusNoContent:
+		return false, nil
+	}
+	return false, statusError(statusCode, responseBody)
+}
+
+// DeleteDictEntry call atomically deletes a single entry of the dictionary.
+// Returns true if the sub-key existed
+func (client *GedisClient) DeleteDictEntry(key string, subKey string) (bool, error) {
+	return client.DeleteDictEntryContext(context.Background(), key, subKey)
+}
+
+// DeleteDictEntryContext ...
+func (client *GedisClient) DeleteDictEntryContext(ctx context.Context, key string, subKey string) (bool, error) {
+	return client.call(ctx, http.MethodDelete, "entries/"+key+"/entries/"+subKey, nil, nil)
+}
+
+// GetDictEntries ...
+func (client *GedisClient) GetDictEntries(key string) (map[string]string, error) {
+	return client.GetDictEntriesContext(context.Background(), key)
+}
+
+// GetDictEntriesContext ...
+func (client *GedisClient) GetDictEntriesContext(ctx context.Context, key string) (map[string]string, error) {
+	entries := map[string]string{}
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/entries", nil, &entries)
+	return entries, err
+}
+
+// DictEntryExists ...
+func (client *GedisClient) DictEntryExists(key string, subKey string) (bool, error) {
+	return client.DictEntryExistsContext(context.Background(), key, subKey)
+}
+
+// DictEntryExistsContext ...
+func (client *GedisClient) DictEntryExistsContext(ctx context.Context, key string, subKey string) (bool, error) {
+	return client.call(ctx, http.MethodHead, "entries/"+key+"/entries/"+subKey, nil, nil)
+}
+
+// GetDictLength ...
+func (client *GedisClient) GetDictLength(key string) (int, error) {
+	return client.GetDictLengthContext(context.Background(), key)
+}
+
+// GetDictLengthContext ...
+func (client *GedisClient) GetDictLengthContext(ctx context.Context, key string) (int, error) {
+	var length int
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/entries?view=length", nil, &length)
+	return length, err
+}
+
+// GetDictKeys call returns sorted sub-keys of the dictionary
+func (client *GedisClient) GetDictKeys(key string) ([]string, error) {
+	return client.GetDictKeysContext(context.Background(), key)
+}
+
+// GetDictKeysContext ...
+func (client *GedisClient) GetDictKeysContext(ctx context.Context, key string) ([]string, error) {
+	keys := []string{}
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/entries?view=keys", nil, &keys)
+	return keys, err
+}
@@ -1,44 +1,173 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/izhamoidsin/gedis/storage"
 )
 
-func handleGetResult(response *http.Response, err error) (storage.Storable, bool, error) {
+// parseEntry call turns the response to an entry request into the item along with its expiration and version.
+// Absence of the entry (404) is reported with false instead of error
+func parseEntry(statusCode int, header http.Header, body []byte) (storage.Storable, time.Time, uint64, bool, error) {
+	switch statusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, time.Time{}, 0, false, nil
+	default:
+		return nil, time.Time{}, 0, false, statusError(statusCode, body)
+	}
+	item, err := parseStorable(body, header.Get("Entry-Type"))
 	if err != nil {
-		return nil, false, err
+		return nil, time.Time{}, 0, false, err
 	}
-	if response.StatusCode == http.StatusOK {
-		val, err := parseStorableFormResponseBody(response)
-		return val, true, err
+	version, err := parseETag(header)
+	return item, parseExpireAt(header), version, true, err
+}
+
+// getEntry call reads the entry at the path, see parseEntry
+func (client *GedisClient) getEntry(ctx context.Context, path string) (storage.Storable, time.Time, uint64, bool, error) {
+	statusCode, header, body, err := client.doWithHeaders(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, time.Time{}, 0, false, err
 	}
-	if response.StatusCode == http.StatusNotFound {
-		return nil, false, nil
+	return parseEntry(statusCode, header, body)
+}
+
+// newRequest call creates a request to the path bound to ctx with the headers and the user agent of the client
+func (client *GedisClient) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequest(method, client.fullURL(path), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range client.header {
+		request.Header[name] = append([]string(nil), values...)
+	}
+	if client.userAgent != "" {
+		request.Header.Set("User-Agent", client.userAgent)
 	}
+	return request.WithContext(ctx), nil
+}
 
-	return nil, false, errors.New("Unexpected response status code" + strconv.Itoa(response.StatusCode))
+// readBody call reads up to 1MB of the response body and closes it
+func readBody(response *http.Response) ([]byte, error) {
+	defer response.Body.Close()
+	return ioutil.ReadAll(io.LimitReader(response.Body, 1048576))
 }
 
-func parseStorableFormResponseBody(r *http.Response) (resp storage.Storable, err error) {
-	var luckyString string
-	var luckyArray []string
-	var luckyDict map[string]string
+// do performs the request sending body (if not nil) as JSON, returns status code and body of the response
+func (client *GedisClient) do(ctx context.Context, method string, path string, body interface{}) (int, []byte, error) {
+	statusCode, _, responseBody, err := client.doWithHeaders(ctx, method, path, body, nil)
+	return statusCode, responseBody, err
+}
 
-	// TODO add parsing of TTL based header, e.g. Expire-In or Expire-At
+// doWithHeaders works as do but also sends headers of the request and returns headers of the response.
+// The request is limited by the timeout of the client if there is one
+func (client *GedisClient) doWithHeaders(ctx context.Context, method string, path string, body interface{}, header http.Header) (int, http.Header, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		bts, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		reader = bytes.NewReader(bts)
+	}
+	if client.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
 
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	request, err := client.newRequest(ctx, method, path, reader)
 	if err != nil {
-		panic(err)
+		return 0, nil, nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json; charset=UTF-8")
 	}
-	if err = r.Body.Close(); err != nil {
-		panic(err)
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	responseBody, err := readBody(response)
+	return response.StatusCode, response.Header, responseBody, err
+}
+
+// call performs the request decoding JSON response body into result (if not nil).
+// Absence of the resource (404) is reported with false instead of error
+func (client *GedisClient) call(ctx context.Context, method string, path string, body interface{}, result interface{}) (bool, error) {
+	statusCode, responseBody, err := client.do(ctx, method, path, body)
+	if err != nil {
+		return false, err
+	}
+	if statusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if statusCode >= http.StatusMultipleChoices {
+		return false, statusError(statusCode, responseBody)
+	}
+	if result != nil {
+		if err := json.Unmarshal(responseBody, result); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// statusError call turns bad request responses into errors with the message sent by the server
+func statusError(statusCode int, body []byte) error {
+	if statusCode == http.StatusBadRequest {
+		return errors.New(strings.TrimSpace(string(body)))
+	}
+	return unexpectedStatusError(statusCode)
+}
+
+// unexpectedStatusError call converts status codes with a special meaning to storage errors
+func unexpectedStatusError(statusCode int) error {
+	switch statusCode {
+	case http.StatusInsufficientStorage:
+		return storage.ErrOutOfMemory
+	case http.StatusConflict:
+		return storage.ErrTransactionAborted
+	}
+	return errors.New("Unexpected response status code " + strconv.Itoa(statusCode))
+}
+
+// parseExpireAt call relies on Expire-At header and falls back to Expire-In one.
+// Zero time is returned if there are no such headers
+func parseExpireAt(header http.Header) time.Time {
+	if expireAt, err := http.ParseTime(header.Get("Expire-At")); err == nil {
+		return expireAt
+	}
+	if seconds, err := strconv.Atoi(header.Get("Expire-In")); err == nil {
+		return time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return time.Time{}
+}
+
+// parseStorable call relies on the entry type to distinguish sets and sorted sets from lists
+func parseStorable(body []byte, entryType string) (resp storage.Storable, err error) {
+	var luckyString string
+	var luckyArray []string
+	var luckyDict map[string]string
+
+	if entryType == "zset" {
+		sortedSet := new(storage.SortedSet)
+		if err = json.Unmarshal(body, sortedSet); err == nil {
+			return sortedSet, nil
+		}
+		return nil, err
 	}
 
 	if err = json.Unmarshal(body, &luckyString); err == nil {
@@ -46,6 +175,9 @@ func parseStorableFormResponseBody(r *http.Response) (resp storage.Storable, err
 	}
 
 	if err = json.Unmarshal(body, &luckyArray); err == nil {
+		if entryType == "set" {
+			return storage.NewSet(luckyArray...), nil
+		}
 		return luckyArray, nil
 	}
 
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+func side(toHead bool) string {
+	if toHead {
+		return "left"
+	}
+	return "right"
+}
+
+func sideQuery(toHead bool) string {
+	return "?side=" + side(toHead)
+}
+
+func rangeQuery(start int, stop int) string {
+	return "?start=" + strconv.Itoa(start) + "&stop=" + strconv.Itoa(stop)
+}
+
+// PushToList call atomically pushes values to the head or the tail of the list,
+// the list is created if there is no such key. The length of the list is returned
+func (client *GedisClient) PushToList(key string, toHead bool, values ...string) (int, error) {
+	return client.PushToListContext(context.Background(), key, toHead, values...)
+}
+
+// PushToListContext ...
+func (client *GedisClient) PushToListContext(ctx context.Context, key string, toHead bool, values ...string) (int, error) {
+	var length int
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/elements"+sideQuery(toHead), values, &length)
+	return length, err
+}
+
+// PopFromList call atomically removes and returns the first or the last element of the list
+func (client *GedisClient) PopFromList(key string, fromHead bool) (string, bool, error) {
+	return client.PopFromListContext(context.Background(), key, fromHead)
+}
+
+// PopFromListContext ...
+func (client *GedisClient) PopFromListContext(ctx context.Context, key string, fromHead bool) (string, bool, error) {
+	var element string
+	exists, err := client.call(ctx, http.MethodDelete, "entries/"+key+"/elements"+sideQuery(fromHead), nil, &element)
+	return element, exists, err
+}
+
+// GetListRange call returns elements of the list from start to stop inclusive.
+// Negative indexes are counted from the end of the list
+func (client *GedisClient) GetListRange(key string, start int, stop int) ([]string, error) {
+	return client.GetListRangeContext(context.Background(), key, start, stop)
+}
+
+// GetListRangeContext ...
+func (client *GedisClient) GetListRangeContext(ctx context.Context, key string, start int, stop int) ([]string, error) {
+	elements := []string{}
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/elements"+rangeQuery(start, stop), nil, &elements)
+	return elements, err
+}
+
+// SetListElement ...
+func (client *GedisClient) SetListElement(key string, index int, value string) error {
+	return client.SetListElementContext(context.Background(), key, index, value)
+}
+
+// SetListElementContext ...
+func (client *GedisClient) SetListElementContext(ctx context.Context, key string, index int, value string) error {
+	_, err := client.call(ctx, http.MethodPut, "entries/"+key+"/elements/"+strconv.Itoa(index), value, nil)
+	return err
+}
+
+// TrimList call keeps only elements of the list from start to stop inclusive
+func (client *GedisClient) TrimList(key string, start int, stop int) error {
+	return client.TrimListContext(context.Background(), key, start, stop)
+}
+
+// TrimListContext ...
+func (client *GedisClient) TrimListContext(ctx context.Context, key string, start int, stop int) error {
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/elements/trim"+rangeQuery(start, stop), nil, nil)
+	return err
+}
+
+// GetListLength ...
+func (client *GedisClient) GetListLength(key string) (int, error) {
+	return client.GetListLengthContext(context.Background(), key)
+}
+
+// GetListLengthContext ...
+func (client *GedisClient) GetListLengthContext(ctx context.Context, key string) (int, error) {
+	var length int
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/elements/length", nil, &length)
+	return length, err
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// KeyspaceEvent is a notification about a change of the key
+type KeyspaceEvent struct {
+	Key   string               `json:"key"`
+	Event storage.MutationType `json:"event"`
+}
+
+// Notifications call streams changes of the keys matching the glob pattern (all keys if empty) until ctx is done,
+// then the returned channel is closed. Only the passed types of changes are streamed, all of them if none is passed
+func (client *GedisClient) Notifications(ctx context.Context, match string, events ...storage.MutationType) (<-chan KeyspaceEvent, error) {
+	query := url.Values{}
+	if match != "" {
+		query.Set("match", match)
+	}
+	if len(events) > 0 {
+		names := make([]string, len(events))
+		for i, event := range events {
+			names[i] = string(event)
+		}
+		query.Set("events", strings.Join(names, ","))
+	}
+
+	notifications := make(chan KeyspaceEvent)
+	err := client.stream(ctx, "notifications?"+query.Encode(), func(data []byte) bool {
+		var event KeyspaceEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return true
+		}
+		select {
+		case notifications <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() { close(notifications) })
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
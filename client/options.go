@@ -0,0 +1,100 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option configures the client created by NewClient
+type Option func(client *GedisClient)
+
+// WithHTTPClient option makes the client send requests with the HTTP client, e.g. to configure TLS,
+// proxies or connection pooling. The HTTP client is copied, so its redirect policy could be extended
+// to learn moved slots, see LoadSlots. Like WithTimeout its Timeout does not limit subscriptions,
+// notifications, replication and watching
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *GedisClient) {
+		client.httpClient = httpClient
+	}
+}
+
+// WithTimeout option limits every request of the client. Subscriptions, notifications, replication
+// and watching are long-living, so they are limited by their contexts only
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *GedisClient) {
+		client.timeout = timeout
+	}
+}
+
+// WithUserAgent option sets User-Agent header of the requests
+func WithUserAgent(userAgent string) Option {
+	return func(client *GedisClient) {
+		client.userAgent = userAgent
+	}
+}
+
+// WithHeader option adds the header to every request, e.g. for authentication by a proxy in front of the server
+func WithHeader(name string, value string) Option {
+	return func(client *GedisClient) {
+		client.header.Add(name, value)
+	}
+}
+
+// NewClient call creates a client of the server at the base URL, e.g. `https://gedis.example.com`
+// or `http://localhost:8081/gedis` if the server is behind a proxy with a path prefix
+func NewClient(baseURL string, options ...Option) (*GedisClient, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, errors.New("Base URL should be an absolute http or https URL: " + baseURL)
+	}
+	if base.RawQuery != "" || base.Fragment != "" {
+		return nil, errors.New("Base URL should not have a query or a fragment: " + baseURL)
+	}
+
+	client := &GedisClient{scheme: base.Scheme, host: base.Host, basePath: strings.TrimSuffix(base.EscapedPath(), "/"), header: http.Header{}}
+	for _, option := range options {
+		option(client)
+	}
+	client.httpClient = client.followingSlots(client.httpClient)
+	return client, nil
+}
+
+// followingSlots call copies the HTTP client (nil means the default one) extending its redirect policy by learnSlot
+func (client *GedisClient) followingSlots(httpClient *http.Client) *http.Client {
+	copied := new(http.Client)
+	if httpClient != nil {
+		*copied = *httpClient
+	}
+	checkRedirect := copied.CheckRedirect
+	copied.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		var err error
+		if checkRedirect != nil {
+			err = checkRedirect(request, via)
+		} else if len(via) >= maxRedirects {
+			err = errors.New("Stopped after " + strconv.Itoa(maxRedirects) + " redirects")
+		}
+		if err == nil {
+			client.learnSlot(request)
+		}
+		return err
+	}
+	return copied
+}
+
+// longLiving call returns the HTTP client of the client without its timeout for long polls and streams,
+// they are limited by their contexts only
+func (client *GedisClient) longLiving() *http.Client {
+	if client.httpClient.Timeout == 0 {
+		return client.httpClient
+	}
+	copied := *client.httpClient
+	copied.Timeout = 0
+	return &copied
+}
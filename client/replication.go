@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// Replicate call makes the replica follow the server until ctx is done: a full sync of the server
+// storage is applied first, then every its mutation. Once the stream is broken or a record
+// could not be applied the call reconnects and starts over with a full sync.
+// An error is returned if the first connection fails
+func (client *GedisClient) Replicate(ctx context.Context, replica *storage.Replica) error {
+	return client.stream(ctx, "replication", func(data []byte) bool {
+		var record storage.ReplicationRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Println("Malformed replication record: " + err.Error())
+			return false
+		}
+		if err := replica.Apply(record); err != nil {
+			log.Println("Can not apply replication record: " + err.Error())
+			return false
+		}
+		return true
+	}, func() {})
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type scanPage struct {
+	Cursor string   `json:"cursor"`
+	Keys   []string `json:"keys"`
+}
+
+// ScanKeys call returns a page of up to count keys matching the glob pattern and of the type
+// (empty ones mean any) starting at the cursor, which is zero for the first page.
+// The cursor of the next page is returned as well, it is zero if there are no more keys
+func (client *GedisClient) ScanKeys(cursor uint64, count int, match string, entryType string) ([]string, uint64, error) {
+	return client.ScanKeysContext(context.Background(), cursor, count, match, entryType)
+}
+
+// ScanKeysContext ...
+func (client *GedisClient) ScanKeysContext(ctx context.Context, cursor uint64, count int, match string, entryType string) ([]string, uint64, error) {
+	query := url.Values{}
+	query.Set("cursor", strconv.FormatUint(cursor, 10))
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+	if match != "" {
+		query.Set("match", match)
+	}
+	if entryType != "" {
+		query.Set("type", entryType)
+	}
+
+	var page scanPage
+	if _, err := client.call(ctx, http.MethodGet, "keys?"+query.Encode(), nil, &page); err != nil {
+		return nil, 0, err
+	}
+	next, err := strconv.ParseUint(page.Cursor, 10, 64)
+	if err != nil {
+		return nil, 0, errors.New("Unexpected cursor " + page.Cursor)
+	}
+	return page.Keys, next, nil
+}
+
+// keyScanner is either GedisClient or ShardedClient
+type keyScanner interface {
+	ScanKeysContext(ctx context.Context, cursor uint64, count int, match string, entryType string) ([]string, uint64, error)
+}
+
+// KeyIterator walks all the pages of the scan:
+//
+//	for it := client.IterateKeys("user:*", "", 100); it.Next(); {
+//		key := it.Key()
+//	}
+//	err := it.Err()
+type KeyIterator struct {
+	ctx       context.Context
+	client    keyScanner
+	count     int
+	match     string
+	entryType string
+
+	cursor   uint64
+	started  bool
+	keys     []string
+	position int
+	err      error
+}
+
+// IterateKeys call creates an iterator over the keys matching the glob pattern and of the type
+// (empty ones mean any) fetching count keys per request (the server default if non-positive)
+func (client *GedisClient) IterateKeys(match string, entryType string, count int) *KeyIterator {
+	return client.IterateKeysContext(context.Background(), match, entryType, count)
+}
+
+// IterateKeysContext works as IterateKeys, requests of the iterator are bound to ctx
+func (client *GedisClient) IterateKeysContext(ctx context.Context, match string, entryType string, count int) *KeyIterator {
+	return &KeyIterator{ctx: ctx, client: client, count: count, match: match, entryType: entryType, position: -1}
+}
+
+// Next call advances the iterator, false is returned once all the keys are walked or an error appeared
+func (it *KeyIterator) Next() bool {
+	it.position++
+	for it.position >= len(it.keys) {
+		if it.err != nil || (it.started && it.cursor == 0) {
+			return false
+		}
+		it.keys, it.cursor, it.err = it.client.ScanKeysContext(it.ctx, it.cursor, it.count, it.match, it.entryType)
+		it.started = true
+		it.position = 0
+	}
+	return true
+}
+
+// Key call returns the current key
+func (it *KeyIterator) Key() string {
+	return it.keys[it.position]
+}
+
+// Err call returns the error stopped the iteration if any
+func (it *KeyIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+func setsQuery(keys []string) string {
+	query := url.Values{}
+	for _, key := range keys {
+		query.Add("key", key)
+	}
+	return query.Encode()
+}
+
+// AddToSet call atomically adds members to the set, the set is created if there is no such key.
+// Returns the number of members which were not in the set before
+func (client *GedisClient) AddToSet(key string, members ...string) (int, error) {
+	return client.AddToSetContext(context.Background(), key, members...)
+}
+
+// AddToSetContext ...
+func (client *GedisClient) AddToSetContext(ctx context.Context, key string, members ...string) (int, error) {
+	var added int
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/members", members, &added)
+	return added, err
+}
+
+// RemoveFromSet call atomically removes members of the set, the set is removed once it gets empty.
+// Returns the number of members which were in the set
+func (client *GedisClient) RemoveFromSet(key string, members ...string) (int, error) {
+	return client.RemoveFromSetContext(context.Background(), key, members...)
+}
+
+// RemoveFromSetContext ...
+func (client *GedisClient) RemoveFromSetContext(ctx context.Context, key string, members ...string) (int, error) {
+	var removed int
+	_, err := client.call(ctx, http.MethodDelete, "entries/"+key+"/members", members, &removed)
+	return removed, err
+}
+
+// IsSetMember ...
+func (client *GedisClient) IsSetMember(key string, member string) (bool, error) {
+	return client.IsSetMemberContext(context.Background(), key, member)
+}
+
+// IsSetMemberContext ...
+func (client *GedisClient) IsSetMemberContext(ctx context.Context, key string, member string) (bool, error) {
+	return client.call(ctx, http.MethodHead, "entries/"+key+"/members/"+member, nil, nil)
+}
+
+// GetSetCardinality ...
+func (client *GedisClient) GetSetCardinality(key string) (int, error) {
+	return client.GetSetCardinalityContext(context.Background(), key)
+}
+
+// GetSetCardinalityContext ...
+func (client *GedisClient) GetSetCardinalityContext(ctx context.Context, key string) (int, error) {
+	var cardinality int
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/members?view=length", nil, &cardinality)
+	return cardinality, err
+}
+
+// GetSetMembers call returns sorted members of the set
+func (client *GedisClient) GetSetMembers(key string) ([]string, error) {
+	return client.GetSetMembersContext(context.Background(), key)
+}
+
+// GetSetMembersContext ...
+func (client *GedisClient) GetSetMembersContext(ctx context.Context, key string) ([]string, error) {
+	members := []string{}
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/members", nil, &members)
+	return members, err
+}
+
+// GetRandomSetMembers call returns up to count distinct random members of the set
+func (client *GedisClient) GetRandomSetMembers(key string, count int) ([]string, error) {
+	return client.GetRandomSetMembersContext(context.Background(), key, count)
+}
+
+// GetRandomSetMembersContext ...
+func (client *GedisClient) GetRandomSetMembersContext(ctx context.Context, key string, count int) ([]string, error) {
+	members := []string{}
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/members?random="+strconv.Itoa(count), nil, &members)
+	return members, err
+}
+
+// PopRandomSetMembers call atomically removes and returns up to count distinct random members of the set
+func (client *GedisClient) PopRandomSetMembers(key string, count int) ([]string, error) {
+	return client.PopRandomSetMembersContext(context.Background(), key, count)
+}
+
+// PopRandomSetMembersContext ...
+func (client *GedisClient) PopRandomSetMembersContext(ctx context.Context, key string, count int) ([]string, error) {
+	members := []string{}
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/members/pop?count="+strconv.Itoa(count), nil, &members)
+	return members, err
+}
+
+// CombineSets call returns sorted members of the union, intersection or difference of the sets.
+// Missing keys are considered as empty sets
+func (client *GedisClient) CombineSets(operation storage.SetOperation, keys ...string) ([]string, error) {
+	return client.CombineSetsContext(context.Background(), operation, keys...)
+}
+
+// CombineSetsContext ...
+func (client *GedisClient) CombineSetsContext(ctx context.Context, operation storage.SetOperation, keys ...string) ([]string, error) {
+	members := []string{}
+	_, err := client.call(ctx, http.MethodGet, "sets/"+string(operation)+"?"+setsQuery(keys), nil, &members)
+	return members, err
+}
+
+// StoreCombinedSets call works as CombineSets but stores the result into destination key.
+// Returns cardinality of the result
+func (client *GedisClient) StoreCombinedSets(operation storage.SetOperation, destination string, keys ...string) (int, error) {
+	return client.StoreCombinedSetsContext(context.Background(), operation, destination, keys...)
+}
+
+// StoreCombinedSetsContext ...
+func (client *GedisClient) StoreCombinedSetsContext(ctx context.Context, operation storage.SetOperation, destination string, keys ...string) (int, error) {
+	var cardinality int
+	path := "sets/" + string(operation) + "?destination=" + url.QueryEscape(destination) + "&" + setsQuery(keys)
+	_, err := client.call(ctx, http.MethodPost, path, nil, &cardinality)
+	return cardinality, err
+}
@@ -0,0 +1,295 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/izhamoidsin/gedis/slots"
+)
+
+// defaultVirtualNodes is the number of points a node of weight 1 takes on the ring
+const defaultVirtualNodes = 160
+
+// ErrNoNodes is returned by ShardedClient when all of its nodes are ejected
+var ErrNoNodes = errors.New("There are no healthy nodes")
+
+// ErrCrossNode is returned by ShardedClient when keys of a multi-key operation belong to different nodes
+var ErrCrossNode = errors.New("Keys belong to different nodes")
+
+// ShardNode is a node of ShardedClient. The share of keys the node gets is proportional to its weight,
+// non-positive weight means 1
+type ShardNode struct {
+	Client *GedisClient
+	Weight int
+}
+
+type shardNode struct {
+	name     string
+	client   *GedisClient
+	weight   int
+	healthy  bool
+	failures int
+}
+
+type ringPoint struct {
+	hash uint64
+	node *shardNode
+}
+
+// ShardedClient spreads keys over a few servers with consistent hashing: every node takes virtual nodes
+// (points) on a hash ring in proportion to its weight, and a key belongs to the first point following its hash.
+// So once a node is ejected or added back only its keys move. Like in slot mode, only the hash tag of the key
+// is hashed (see slots.HashTag), so multi-key operations work for keys sharing the tag.
+// It has the same methods as GedisClient: requests to a key go to its node, GetKeys, ScanKeys and batches
+// are scattered over the nodes, pub/sub channels are spread over the nodes by their names
+type ShardedClient struct {
+	nodes        []*shardNode
+	virtualNodes int
+
+	lock sync.RWMutex
+	ring []ringPoint
+	stop chan struct{}
+	done chan struct{}
+}
+
+// CreateShardedClient call creates a client of the nodes, all of them are considered healthy until
+// health checks are started. Non-positive virtualNodes means 160 points per unit of weight
+func CreateShardedClient(virtualNodes int, nodes ...ShardNode) *ShardedClient {
+	sharded := new(ShardedClient)
+	sharded.virtualNodes = virtualNodes
+	if virtualNodes <= 0 {
+		sharded.virtualNodes = defaultVirtualNodes
+	}
+	for _, node := range nodes {
+		weight := node.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		sharded.nodes = append(sharded.nodes, &shardNode{name: node.Client.host, client: node.Client, weight: weight, healthy: true})
+	}
+	sharded.buildRing()
+	return sharded
+}
+
+// ringHash call is FNV-1a mixed by the finalizer of MurmurHash3, as plain FNV spreads similar strings
+// (like names of virtual nodes) poorly
+func ringHash(s string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(s))
+	x := hash.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// buildRing call places the points of the healthy nodes on the ring, the lock should be held by the caller
+func (sharded *ShardedClient) buildRing() {
+	var ring []ringPoint
+	for _, node := range sharded.nodes {
+		if !node.healthy {
+			continue
+		}
+		for i := 0; i < node.weight*sharded.virtualNodes; i++ {
+			ring = append(ring, ringPoint{ringHash(node.name + "#" + strconv.Itoa(i)), node})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	sharded.ring = ring
+}
+
+// nodeFor call returns the healthy node owning the key, nil if there is none
+func (sharded *ShardedClient) nodeFor(key string) *shardNode {
+	hash := ringHash(slots.HashTag(key))
+	sharded.lock.RLock()
+	defer sharded.lock.RUnlock()
+	if len(sharded.ring) == 0 {
+		return nil
+	}
+	i := sort.Search(len(sharded.ring), func(i int) bool { return sharded.ring[i].hash >= hash })
+	if i == len(sharded.ring) {
+		i = 0
+	}
+	return sharded.ring[i].node
+}
+
+// ClientFor call returns the client of the node owning the key
+func (sharded *ShardedClient) ClientFor(key string) (*GedisClient, error) {
+	if node := sharded.nodeFor(key); node != nil {
+		return node.client, nil
+	}
+	return nil, ErrNoNodes
+}
+
+// clientForKeys call returns the client of the node owning all the keys
+func (sharded *ShardedClient) clientForKeys(keys ...string) (*GedisClient, error) {
+	if len(keys) == 0 {
+		return sharded.ClientFor("")
+	}
+	node := sharded.nodeFor(keys[0])
+	if node == nil {
+		return nil, ErrNoNodes
+	}
+	for _, key := range keys[1:] {
+		if sharded.nodeFor(key) != node {
+			return nil, ErrCrossNode
+		}
+	}
+	return node.client, nil
+}
+
+// groupKeys call groups the keys by their nodes keeping indexes of the keys
+func (sharded *ShardedClient) groupKeys(keys []string) (map[*shardNode][]int, error) {
+	groups := make(map[*shardNode][]int)
+	for i, key := range keys {
+		node := sharded.nodeFor(key)
+		if node == nil {
+			return nil, ErrNoNodes
+		}
+		groups[node] = append(groups[node], i)
+	}
+	return groups, nil
+}
+
+// HealthyNodes call returns addresses of the nodes keys are currently spread over
+func (sharded *ShardedClient) HealthyNodes() []string {
+	sharded.lock.RLock()
+	defer sharded.lock.RUnlock()
+	var names []string
+	for _, node := range sharded.nodes {
+		if node.healthy {
+			names = append(names, node.name)
+		}
+	}
+	return names
+}
+
+func (sharded *ShardedClient) healthyNodes() []*shardNode {
+	sharded.lock.RLock()
+	defer sharded.lock.RUnlock()
+	var nodes []*shardNode
+	for _, node := range sharded.nodes {
+		if node.healthy {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// scatter call runs the call for every node in parallel and returns the first error
+func scatter(nodes []*shardNode, call func(i int, node *shardNode) error) error {
+	errs := make([]error, len(nodes))
+	var wait sync.WaitGroup
+	for i, node := range nodes {
+		wait.Add(1)
+		go func(i int, node *shardNode) {
+			defer wait.Done()
+			errs[i] = call(i, node)
+		}(i, node)
+	}
+	wait.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartHealthChecks call makes the client check `/heartbeat` of every node each interval. A node is ejected
+// from the ring after the number of consecutive failed checks and is added back once a check succeeds
+func (sharded *ShardedClient) StartHealthChecks(interval time.Duration, failures int) error {
+	if interval <= 0 || failures <= 0 {
+		return errors.New("Health check interval and failures should be positive")
+	}
+
+	sharded.lock.Lock()
+	defer sharded.lock.Unlock()
+	if sharded.stop != nil {
+		return errors.New("Health checks are already started")
+	}
+	sharded.stop = make(chan struct{})
+	sharded.done = make(chan struct{})
+
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				sharded.checkHealth(interval, failures)
+			}
+		}
+	}(sharded.stop, sharded.done)
+	return nil
+}
+
+// checkHealth call checks all the nodes at once and rebuilds the ring if any of them is ejected or added back
+func (sharded *ShardedClient) checkHealth(timeout time.Duration, failures int) {
+	alive := make([]bool, len(sharded.nodes))
+	scatter(sharded.nodes, func(i int, node *shardNode) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		alive[i] = node.client.ping(ctx) == nil
+		return nil
+	})
+
+	sharded.lock.Lock()
+	defer sharded.lock.Unlock()
+	changed := false
+	for i, node := range sharded.nodes {
+		if alive[i] {
+			node.failures = 0
+		} else {
+			node.failures++
+		}
+		if healthy := node.failures < failures; healthy != node.healthy {
+			node.healthy = healthy
+			changed = true
+		}
+	}
+	if changed {
+		sharded.buildRing()
+	}
+}
+
+// ping call checks the server is up
+func (client *GedisClient) ping(ctx context.Context) error {
+	request, err := client.newRequest(ctx, http.MethodHead, "heartbeat", nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New("Heartbeat status " + strconv.Itoa(response.StatusCode))
+	}
+	return nil
+}
+
+// Close call stops health checks
+func (sharded *ShardedClient) Close() {
+	sharded.lock.Lock()
+	stop, done := sharded.stop, sharded.done
+	sharded.stop, sharded.done = nil, nil
+	sharded.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
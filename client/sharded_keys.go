@@ -0,0 +1,664 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// Methods of ShardedClient working with a single key are sent to the node owning the key.
+// ErrNoNodes is returned if all the nodes are ejected
+
+// GetItem ...
+func (sharded *ShardedClient) GetItem(key string) (storage.Storable, bool, error) {
+	return sharded.GetItemContext(context.Background(), key)
+}
+
+// GetItemContext ...
+func (sharded *ShardedClient) GetItemContext(ctx context.Context, key string) (storage.Storable, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return client.GetItemContext(ctx, key)
+}
+
+// GetItemWithExpiry ...
+func (sharded *ShardedClient) GetItemWithExpiry(key string) (storage.Storable, time.Time, bool, error) {
+	return sharded.GetItemWithExpiryContext(context.Background(), key)
+}
+
+// GetItemWithExpiryContext ...
+func (sharded *ShardedClient) GetItemWithExpiryContext(ctx context.Context, key string) (storage.Storable, time.Time, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return client.GetItemWithExpiryContext(ctx, key)
+}
+
+// GetItemWithVersion ...
+func (sharded *ShardedClient) GetItemWithVersion(key string) (storage.Storable, uint64, bool, error) {
+	return sharded.GetItemWithVersionContext(context.Background(), key)
+}
+
+// GetItemWithVersionContext ...
+func (sharded *ShardedClient) GetItemWithVersionContext(ctx context.Context, key string) (storage.Storable, uint64, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return client.GetItemWithVersionContext(ctx, key)
+}
+
+// UpdateItem ...
+func (sharded *ShardedClient) UpdateItem(key string, item storage.Storable) error {
+	return sharded.UpdateItemContext(context.Background(), key, item)
+}
+
+// UpdateItemContext ...
+func (sharded *ShardedClient) UpdateItemContext(ctx context.Context, key string, item storage.Storable) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.UpdateItemContext(ctx, key, item)
+}
+
+// UpdateItemWithTTL ...
+func (sharded *ShardedClient) UpdateItemWithTTL(key string, item storage.Storable, ttl time.Duration) error {
+	return sharded.UpdateItemWithTTLContext(context.Background(), key, item, ttl)
+}
+
+// UpdateItemWithTTLContext ...
+func (sharded *ShardedClient) UpdateItemWithTTLContext(ctx context.Context, key string, item storage.Storable, ttl time.Duration) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.UpdateItemWithTTLContext(ctx, key, item, ttl)
+}
+
+// UpdateItemIfVersion ...
+func (sharded *ShardedClient) UpdateItemIfVersion(key string, item storage.Storable, version uint64) (uint64, error) {
+	return sharded.UpdateItemIfVersionContext(context.Background(), key, item, version)
+}
+
+// UpdateItemIfVersionContext ...
+func (sharded *ShardedClient) UpdateItemIfVersionContext(ctx context.Context, key string, item storage.Storable, version uint64) (uint64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.UpdateItemIfVersionContext(ctx, key, item, version)
+}
+
+// UpdateItemIfVersionWithTTL ...
+func (sharded *ShardedClient) UpdateItemIfVersionWithTTL(key string, item storage.Storable, version uint64, ttl time.Duration) (uint64, error) {
+	return sharded.UpdateItemIfVersionWithTTLContext(context.Background(), key, item, version, ttl)
+}
+
+// UpdateItemIfVersionWithTTLContext ...
+func (sharded *ShardedClient) UpdateItemIfVersionWithTTLContext(ctx context.Context, key string, item storage.Storable, version uint64, ttl time.Duration) (uint64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.UpdateItemIfVersionWithTTLContext(ctx, key, item, version, ttl)
+}
+
+// AppendItem ...
+func (sharded *ShardedClient) AppendItem(key string, item storage.Storable) error {
+	return sharded.AppendItemContext(context.Background(), key, item)
+}
+
+// AppendItemContext ...
+func (sharded *ShardedClient) AppendItemContext(ctx context.Context, key string, item storage.Storable) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.AppendItemContext(ctx, key, item)
+}
+
+// AppendItemWithTTL ...
+func (sharded *ShardedClient) AppendItemWithTTL(key string, item storage.Storable, ttl time.Duration) error {
+	return sharded.AppendItemWithTTLContext(context.Background(), key, item, ttl)
+}
+
+// AppendItemWithTTLContext ...
+func (sharded *ShardedClient) AppendItemWithTTLContext(ctx context.Context, key string, item storage.Storable, ttl time.Duration) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.AppendItemWithTTLContext(ctx, key, item, ttl)
+}
+
+// DeleteItem ...
+func (sharded *ShardedClient) DeleteItem(key string) error {
+	return sharded.DeleteItemContext(context.Background(), key)
+}
+
+// DeleteItemContext ...
+func (sharded *ShardedClient) DeleteItemContext(ctx context.Context, key string) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.DeleteItemContext(ctx, key)
+}
+
+// DeleteItemIfVersion ...
+func (sharded *ShardedClient) DeleteItemIfVersion(key string, version uint64) error {
+	return sharded.DeleteItemIfVersionContext(context.Background(), key, version)
+}
+
+// DeleteItemIfVersionContext ...
+func (sharded *ShardedClient) DeleteItemIfVersionContext(ctx context.Context, key string, version uint64) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.DeleteItemIfVersionContext(ctx, key, version)
+}
+
+// GetItemByNestedIndex ...
+func (sharded *ShardedClient) GetItemByNestedIndex(key string, index string) (storage.Storable, bool, error) {
+	return sharded.GetItemByNestedIndexContext(context.Background(), key, index)
+}
+
+// GetItemByNestedIndexContext ...
+func (sharded *ShardedClient) GetItemByNestedIndexContext(ctx context.Context, key string, index string) (storage.Storable, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return client.GetItemByNestedIndexContext(ctx, key, index)
+}
+
+// GetItemByNestedKey ...
+func (sharded *ShardedClient) GetItemByNestedKey(key string, subKey string) (storage.Storable, bool, error) {
+	return sharded.GetItemByNestedKeyContext(context.Background(), key, subKey)
+}
+
+// GetItemByNestedKeyContext ...
+func (sharded *ShardedClient) GetItemByNestedKeyContext(ctx context.Context, key string, subKey string) (storage.Storable, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return client.GetItemByNestedKeyContext(ctx, key, subKey)
+}
+
+// WatchItem ...
+func (sharded *ShardedClient) WatchItem(ctx context.Context, key string) (<-chan WatchedItem, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.WatchItem(ctx, key)
+}
+
+// Increment ...
+func (sharded *ShardedClient) Increment(key string) (int64, error) {
+	return sharded.IncrementContext(context.Background(), key)
+}
+
+// IncrementContext ...
+func (sharded *ShardedClient) IncrementContext(ctx context.Context, key string) (int64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.IncrementContext(ctx, key)
+}
+
+// Decrement ...
+func (sharded *ShardedClient) Decrement(key string) (int64, error) {
+	return sharded.DecrementContext(context.Background(), key)
+}
+
+// DecrementContext ...
+func (sharded *ShardedClient) DecrementContext(ctx context.Context, key string) (int64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.DecrementContext(ctx, key)
+}
+
+// IncrementBy ...
+func (sharded *ShardedClient) IncrementBy(key string, delta int64) (int64, error) {
+	return sharded.IncrementByContext(context.Background(), key, delta)
+}
+
+// IncrementByContext ...
+func (sharded *ShardedClient) IncrementByContext(ctx context.Context, key string, delta int64) (int64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.IncrementByContext(ctx, key, delta)
+}
+
+// DecrementBy ...
+func (sharded *ShardedClient) DecrementBy(key string, delta int64) (int64, error) {
+	return sharded.DecrementByContext(context.Background(), key, delta)
+}
+
+// DecrementByContext ...
+func (sharded *ShardedClient) DecrementByContext(ctx context.Context, key string, delta int64) (int64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.DecrementByContext(ctx, key, delta)
+}
+
+// IncrementByFloat ...
+func (sharded *ShardedClient) IncrementByFloat(key string, delta float64) (float64, error) {
+	return sharded.IncrementByFloatContext(context.Background(), key, delta)
+}
+
+// IncrementByFloatContext ...
+func (sharded *ShardedClient) IncrementByFloatContext(ctx context.Context, key string, delta float64) (float64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.IncrementByFloatContext(ctx, key, delta)
+}
+
+// SetDictEntry ...
+func (sharded *ShardedClient) SetDictEntry(key string, subKey string, value string) (bool, error) {
+	return sharded.SetDictEntryContext(context.Background(), key, subKey, value)
+}
+
+// SetDictEntryContext ...
+func (sharded *ShardedClient) SetDictEntryContext(ctx context.Context, key string, subKey string, value string) (bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return false, err
+	}
+	return client.SetDictEntryContext(ctx, key, subKey, value)
+}
+
+// DeleteDictEntry ...
+func (sharded *ShardedClient) DeleteDictEntry(key string, subKey string) (bool, error) {
+	return sharded.DeleteDictEntryContext(context.Background(), key, subKey)
+}
+
+// DeleteDictEntryContext ...
+func (sharded *ShardedClient) DeleteDictEntryContext(ctx context.Context, key string, subKey string) (bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return false, err
+	}
+	return client.DeleteDictEntryContext(ctx, key, subKey)
+}
+
+// GetDictEntries ...
+func (sharded *ShardedClient) GetDictEntries(key string) (map[string]string, error) {
+	return sharded.GetDictEntriesContext(context.Background(), key)
+}
+
+// GetDictEntriesContext ...
+func (sharded *ShardedClient) GetDictEntriesContext(ctx context.Context, key string) (map[string]string, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetDictEntriesContext(ctx, key)
+}
+
+// DictEntryExists ...
+func (sharded *ShardedClient) DictEntryExists(key string, subKey string) (bool, error) {
+	return sharded.DictEntryExistsContext(context.Background(), key, subKey)
+}
+
+// DictEntryExistsContext ...
+func (sharded *ShardedClient) DictEntryExistsContext(ctx context.Context, key string, subKey string) (bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return false, err
+	}
+	return client.DictEntryExistsContext(ctx, key, subKey)
+}
+
+// GetDictLength ...
+func (sharded *ShardedClient) GetDictLength(key string) (int, error) {
+	return sharded.GetDictLengthContext(context.Background(), key)
+}
+
+// GetDictLengthContext ...
+func (sharded *ShardedClient) GetDictLengthContext(ctx context.Context, key string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.GetDictLengthContext(ctx, key)
+}
+
+// GetDictKeys ...
+func (sharded *ShardedClient) GetDictKeys(key string) ([]string, error) {
+	return sharded.GetDictKeysContext(context.Background(), key)
+}
+
+// GetDictKeysContext ...
+func (sharded *ShardedClient) GetDictKeysContext(ctx context.Context, key string) ([]string, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetDictKeysContext(ctx, key)
+}
+
+// PushToList ...
+func (sharded *ShardedClient) PushToList(key string, toHead bool, values ...string) (int, error) {
+	return sharded.PushToListContext(context.Background(), key, toHead, values...)
+}
+
+// PushToListContext ...
+func (sharded *ShardedClient) PushToListContext(ctx context.Context, key string, toHead bool, values ...string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.PushToListContext(ctx, key, toHead, values...)
+}
+
+// PopFromList ...
+func (sharded *ShardedClient) PopFromList(key string, fromHead bool) (string, bool, error) {
+	return sharded.PopFromListContext(context.Background(), key, fromHead)
+}
+
+// PopFromListContext ...
+func (sharded *ShardedClient) PopFromListContext(ctx context.Context, key string, fromHead bool) (string, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return "", false, err
+	}
+	return client.PopFromListContext(ctx, key, fromHead)
+}
+
+// GetListRange ...
+func (sharded *ShardedClient) GetListRange(key string, start int, stop int) ([]string, error) {
+	return sharded.GetListRangeContext(context.Background(), key, start, stop)
+}
+
+// GetListRangeContext ...
+func (sharded *ShardedClient) GetListRangeContext(ctx context.Context, key string, start int, stop int) ([]string, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetListRangeContext(ctx, key, start, stop)
+}
+
+// SetListElement ...
+func (sharded *ShardedClient) SetListElement(key string, index int, value string) error {
+	return sharded.SetListElementContext(context.Background(), key, index, value)
+}
+
+// SetListElementContext ...
+func (sharded *ShardedClient) SetListElementContext(ctx context.Context, key string, index int, value string) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.SetListElementContext(ctx, key, index, value)
+}
+
+// TrimList ...
+func (sharded *ShardedClient) TrimList(key string, start int, stop int) error {
+	return sharded.TrimListContext(context.Background(), key, start, stop)
+}
+
+// TrimListContext ...
+func (sharded *ShardedClient) TrimListContext(ctx context.Context, key string, start int, stop int) error {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return err
+	}
+	return client.TrimListContext(ctx, key, start, stop)
+}
+
+// GetListLength ...
+func (sharded *ShardedClient) GetListLength(key string) (int, error) {
+	return sharded.GetListLengthContext(context.Background(), key)
+}
+
+// GetListLengthContext ...
+func (sharded *ShardedClient) GetListLengthContext(ctx context.Context, key string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.GetListLengthContext(ctx, key)
+}
+
+// AddToSet ...
+func (sharded *ShardedClient) AddToSet(key string, members ...string) (int, error) {
+	return sharded.AddToSetContext(context.Background(), key, members...)
+}
+
+// AddToSetContext ...
+func (sharded *ShardedClient) AddToSetContext(ctx context.Context, key string, members ...string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.AddToSetContext(ctx, key, members...)
+}
+
+// RemoveFromSet ...
+func (sharded *ShardedClient) RemoveFromSet(key string, members ...string) (int, error) {
+	return sharded.RemoveFromSetContext(context.Background(), key, members...)
+}
+
+// RemoveFromSetContext ...
+func (sharded *ShardedClient) RemoveFromSetContext(ctx context.Context, key string, members ...string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.RemoveFromSetContext(ctx, key, members...)
+}
+
+// IsSetMember ...
+func (sharded *ShardedClient) IsSetMember(key string, member string) (bool, error) {
+	return sharded.IsSetMemberContext(context.Background(), key, member)
+}
+
+// IsSetMemberContext ...
+func (sharded *ShardedClient) IsSetMemberContext(ctx context.Context, key string, member string) (bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return false, err
+	}
+	return client.IsSetMemberContext(ctx, key, member)
+}
+
+// GetSetCardinality ...
+func (sharded *ShardedClient) GetSetCardinality(key string) (int, error) {
+	return sharded.GetSetCardinalityContext(context.Background(), key)
+}
+
+// GetSetCardinalityContext ...
+func (sharded *ShardedClient) GetSetCardinalityContext(ctx context.Context, key string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.GetSetCardinalityContext(ctx, key)
+}
+
+// GetSetMembers ...
+func (sharded *ShardedClient) GetSetMembers(key string) ([]string, error) {
+	return sharded.GetSetMembersContext(context.Background(), key)
+}
+
+// GetSetMembersContext ...
+func (sharded *ShardedClient) GetSetMembersContext(ctx context.Context, key string) ([]string, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSetMembersContext(ctx, key)
+}
+
+// GetRandomSetMembers ...
+func (sharded *ShardedClient) GetRandomSetMembers(key string, count int) ([]string, error) {
+	return sharded.GetRandomSetMembersContext(context.Background(), key, count)
+}
+
+// GetRandomSetMembersContext ...
+func (sharded *ShardedClient) GetRandomSetMembersContext(ctx context.Context, key string, count int) ([]string, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetRandomSetMembersContext(ctx, key, count)
+}
+
+// PopRandomSetMembers ...
+func (sharded *ShardedClient) PopRandomSetMembers(key string, count int) ([]string, error) {
+	return sharded.PopRandomSetMembersContext(context.Background(), key, count)
+}
+
+// PopRandomSetMembersContext ...
+func (sharded *ShardedClient) PopRandomSetMembersContext(ctx context.Context, key string, count int) ([]string, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.PopRandomSetMembersContext(ctx, key, count)
+}
+
+// AddToSortedSet ...
+func (sharded *ShardedClient) AddToSortedSet(key string, members ...storage.ScoredMember) (int, error) {
+	return sharded.AddToSortedSetContext(context.Background(), key, members...)
+}
+
+// AddToSortedSetContext ...
+func (sharded *ShardedClient) AddToSortedSetContext(ctx context.Context, key string, members ...storage.ScoredMember) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.AddToSortedSetContext(ctx, key, members...)
+}
+
+// IncrementSortedSetScore ...
+func (sharded *ShardedClient) IncrementSortedSetScore(key string, member string, delta float64) (float64, error) {
+	return sharded.IncrementSortedSetScoreContext(context.Background(), key, member, delta)
+}
+
+// IncrementSortedSetScoreContext ...
+func (sharded *ShardedClient) IncrementSortedSetScoreContext(ctx context.Context, key string, member string, delta float64) (float64, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.IncrementSortedSetScoreContext(ctx, key, member, delta)
+}
+
+// RemoveFromSortedSet ...
+func (sharded *ShardedClient) RemoveFromSortedSet(key string, members ...string) (int, error) {
+	return sharded.RemoveFromSortedSetContext(context.Background(), key, members...)
+}
+
+// RemoveFromSortedSetContext ...
+func (sharded *ShardedClient) RemoveFromSortedSetContext(ctx context.Context, key string, members ...string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.RemoveFromSortedSetContext(ctx, key, members...)
+}
+
+// GetSortedSetScore ...
+func (sharded *ShardedClient) GetSortedSetScore(key string, member string) (float64, bool, error) {
+	return sharded.GetSortedSetScoreContext(context.Background(), key, member)
+}
+
+// GetSortedSetScoreContext ...
+func (sharded *ShardedClient) GetSortedSetScoreContext(ctx context.Context, key string, member string) (float64, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, false, err
+	}
+	return client.GetSortedSetScoreContext(ctx, key, member)
+}
+
+// GetSortedSetRank ...
+func (sharded *ShardedClient) GetSortedSetRank(key string, member string, reverse bool) (int, bool, error) {
+	return sharded.GetSortedSetRankContext(context.Background(), key, member, reverse)
+}
+
+// GetSortedSetRankContext ...
+func (sharded *ShardedClient) GetSortedSetRankContext(ctx context.Context, key string, member string, reverse bool) (int, bool, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, false, err
+	}
+	return client.GetSortedSetRankContext(ctx, key, member, reverse)
+}
+
+// GetSortedSetCardinality ...
+func (sharded *ShardedClient) GetSortedSetCardinality(key string) (int, error) {
+	return sharded.GetSortedSetCardinalityContext(context.Background(), key)
+}
+
+// GetSortedSetCardinalityContext ...
+func (sharded *ShardedClient) GetSortedSetCardinalityContext(ctx context.Context, key string) (int, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return client.GetSortedSetCardinalityContext(ctx, key)
+}
+
+// GetSortedSetRangeByRank ...
+func (sharded *ShardedClient) GetSortedSetRangeByRank(key string, start int, stop int, reverse bool) ([]storage.ScoredMember, error) {
+	return sharded.GetSortedSetRangeByRankContext(context.Background(), key, start, stop, reverse)
+}
+
+// GetSortedSetRangeByRankContext ...
+func (sharded *ShardedClient) GetSortedSetRangeByRankContext(ctx context.Context, key string, start int, stop int, reverse bool) ([]storage.ScoredMember, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSortedSetRangeByRankContext(ctx, key, start, stop, reverse)
+}
+
+// GetSortedSetRangeByScore ...
+func (sharded *ShardedClient) GetSortedSetRangeByScore(key string, scoreRange storage.ScoreRange, reverse bool, offset int, count int) ([]storage.ScoredMember, error) {
+	return sharded.GetSortedSetRangeByScoreContext(context.Background(), key, scoreRange, reverse, offset, count)
+}
+
+// GetSortedSetRangeByScoreContext ...
+func (sharded *ShardedClient) GetSortedSetRangeByScoreContext(ctx context.Context, key string, scoreRange storage.ScoreRange, reverse bool, offset int, count int) ([]storage.ScoredMember, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSortedSetRangeByScoreContext(ctx, key, scoreRange, reverse, offset, count)
+}
+
+// PopFromSortedSet ...
+func (sharded *ShardedClient) PopFromSortedSet(key string, max bool, count int) ([]storage.ScoredMember, error) {
+	return sharded.PopFromSortedSetContext(context.Background(), key, max, count)
+}
+
+// PopFromSortedSetContext ...
+func (sharded *ShardedClient) PopFromSortedSetContext(ctx context.Context, key string, max bool, count int) ([]storage.ScoredMember, error) {
+	client, err := sharded.ClientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return client.PopFromSortedSetContext(ctx, key, max, count)
+}
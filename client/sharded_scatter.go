@@ -0,0 +1,360 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/izhamoidsin/gedis/pubsub"
+	"github.com/izhamoidsin/gedis/slots"
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// GetKeys call gathers the keys of all the healthy nodes
+func (sharded *ShardedClient) GetKeys() ([]string, error) {
+	return sharded.GetKeysContext(context.Background())
+}
+
+// GetKeysContext ...
+func (sharded *ShardedClient) GetKeysContext(ctx context.Context) ([]string, error) {
+	var lock sync.Mutex
+	seen := make(map[string]bool)
+	keys := []string{}
+	err := scatter(sharded.healthyNodes(), func(i int, node *shardNode) error {
+		nodeKeys, err := node.client.GetKeysContext(ctx)
+		lock.Lock()
+		defer lock.Unlock()
+		for _, key := range nodeKeys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ScanKeys call works as GedisClient.ScanKeys scanning all the healthy nodes with the same cursor.
+// Servers order keys by storage.ScanHash, so the page holds keys of all the nodes with hashes less than
+// the least cursor the nodes return, which is the cursor of the next page. The page could have up to
+// count keys of every node
+func (sharded *ShardedClient) ScanKeys(cursor uint64, count int, match string, entryType string) ([]string, uint64, error) {
+	return sharded.ScanKeysContext(context.Background(), cursor, count, match, entryType)
+}
+
+// ScanKeysContext ...
+func (sharded *ShardedClient) ScanKeysContext(ctx context.Context, cursor uint64, count int, match string, entryType string) ([]string, uint64, error) {
+	nodes := sharded.healthyNodes()
+	if len(nodes) == 0 {
+		return nil, 0, ErrNoNodes
+	}
+	pages := make([][]string, len(nodes))
+	cursors := make([]uint64, len(nodes))
+	err := scatter(nodes, func(i int, node *shardNode) error {
+		var err error
+		pages[i], cursors[i], err = node.client.ScanKeysContext(ctx, cursor, count, match, entryType)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	next := uint64(0)
+	for _, nodeCursor := range cursors {
+		if nodeCursor != 0 && (next == 0 || nodeCursor < next) {
+			next = nodeCursor
+		}
+	}
+	seen := make(map[string]bool)
+	keys := []string{}
+	for _, page := range pages {
+		for _, key := range page {
+			// keys past the next cursor are returned by the next page
+			if !seen[key] && (next == 0 || storage.ScanHash(key) < next) {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return storage.ScanHash(keys[i]) < storage.ScanHash(keys[j]) })
+	return keys, next, nil
+}
+
+// IterateKeys call creates an iterator over the keys of all the healthy nodes, see GedisClient.IterateKeys
+func (sharded *ShardedClient) IterateKeys(match string, entryType string, count int) *KeyIterator {
+	return sharded.IterateKeysContext(context.Background(), match, entryType, count)
+}
+
+// IterateKeysContext ...
+func (sharded *ShardedClient) IterateKeysContext(ctx context.Context, match string, entryType string, count int) *KeyIterator {
+	return &KeyIterator{ctx: ctx, client: sharded, count: count, match: match, entryType: entryType, position: -1}
+}
+
+// GetItems call reads the items from their nodes in parallel
+func (sharded *ShardedClient) GetItems(keys ...string) (map[string]storage.Storable, error) {
+	return sharded.GetItemsContext(context.Background(), keys...)
+}
+
+// GetItemsContext ...
+func (sharded *ShardedClient) GetItemsContext(ctx context.Context, keys ...string) (map[string]storage.Storable, error) {
+	groups, err := sharded.groupKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	var lock sync.Mutex
+	items := make(map[string]storage.Storable, len(keys))
+	err = scatter(groupNodes(groups), func(i int, node *shardNode) error {
+		nodeKeys := make([]string, len(groups[node]))
+		for j, index := range groups[node] {
+			nodeKeys[j] = keys[index]
+		}
+		nodeItems, err := node.client.GetItemsContext(ctx, nodeKeys...)
+		lock.Lock()
+		defer lock.Unlock()
+		for key, item := range nodeItems {
+			items[key] = item
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// SetItems call stores the items on their nodes in parallel, results are returned in the order of the entries
+func (sharded *ShardedClient) SetItems(entries []BatchEntry, onlyIfAbsent bool) ([]BatchSetResult, error) {
+	return sharded.SetItemsContext(context.Background(), entries, onlyIfAbsent)
+}
+
+// SetItemsContext ...
+func (sharded *ShardedClient) SetItemsContext(ctx context.Context, entries []BatchEntry, onlyIfAbsent bool) ([]BatchSetResult, error) {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	groups, err := sharded.groupKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BatchSetResult, len(entries))
+	err = scatter(groupNodes(groups), func(i int, node *shardNode) error {
+		nodeEntries := make([]BatchEntry, len(groups[node]))
+		for j, index := range groups[node] {
+			nodeEntries[j] = entries[index]
+		}
+		nodeResults, err := node.client.SetItemsContext(ctx, nodeEntries, onlyIfAbsent)
+		if err == nil && len(nodeResults) != len(nodeEntries) {
+			err = errors.New("Unexpected number of results from " + node.name)
+		}
+		if err != nil {
+			return err
+		}
+		for j, index := range groups[node] {
+			results[index] = nodeResults[j]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteItems call removes the items from their nodes in parallel and returns the number of existed ones
+func (sharded *ShardedClient) DeleteItems(keys ...string) (int, error) {
+	return sharded.DeleteItemsContext(context.Background(), keys...)
+}
+
+// DeleteItemsContext ...
+func (sharded *ShardedClient) DeleteItemsContext(ctx context.Context, keys ...string) (int, error) {
+	groups, err := sharded.groupKeys(keys)
+	if err != nil {
+		return 0, err
+	}
+	var lock sync.Mutex
+	deleted := 0
+	err = scatter(groupNodes(groups), func(i int, node *shardNode) error {
+		nodeKeys := make([]string, len(groups[node]))
+		for j, index := range groups[node] {
+			nodeKeys[j] = keys[index]
+		}
+		nodeDeleted, err := node.client.DeleteItemsContext(ctx, nodeKeys...)
+		lock.Lock()
+		deleted += nodeDeleted
+		lock.Unlock()
+		return err
+	})
+	return deleted, err
+}
+
+func groupNodes(groups map[*shardNode][]int) []*shardNode {
+	nodes := make([]*shardNode, 0, len(groups))
+	for node := range groups {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// CombineSets call combines the sets on their node, ErrCrossNode is returned if they belong to different nodes
+func (sharded *ShardedClient) CombineSets(operation storage.SetOperation, keys ...string) ([]string, error) {
+	return sharded.CombineSetsContext(context.Background(), operation, keys...)
+}
+
+// CombineSetsContext ...
+func (sharded *ShardedClient) CombineSetsContext(ctx context.Context, operation storage.SetOperation, keys ...string) ([]string, error) {
+	client, err := sharded.clientForKeys(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return client.CombineSetsContext(ctx, operation, keys...)
+}
+
+// StoreCombinedSets works as CombineSets, the destination should belong to the same node as well
+func (sharded *ShardedClient) StoreCombinedSets(operation storage.SetOperation, destination string, keys ...string) (int, error) {
+	return sharded.StoreCombinedSetsContext(context.Background(), operation, destination, keys...)
+}
+
+// StoreCombinedSetsContext ...
+func (sharded *ShardedClient) StoreCombinedSetsContext(ctx context.Context, operation storage.SetOperation, destination string, keys ...string) (int, error) {
+	client, err := sharded.clientForKeys(append([]string{destination}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+	return client.StoreCombinedSetsContext(ctx, operation, destination, keys...)
+}
+
+// Transaction call starts building a new transaction, on Exec it is sent to the node of its keys
+// or ErrCrossNode is returned if they belong to different nodes
+func (sharded *ShardedClient) Transaction() *Transaction {
+	return &Transaction{sharded: sharded, request: txRequest{Watch: map[string]uint64{}}}
+}
+
+// Publish call sends the message to the node of the channel, channels are spread over the nodes as keys are
+func (sharded *ShardedClient) Publish(channel string, message string) (int, error) {
+	return sharded.PublishContext(context.Background(), channel, message)
+}
+
+// PublishContext ...
+func (sharded *ShardedClient) PublishContext(ctx context.Context, channel string, message string) (int, error) {
+	client, err := sharded.ClientFor(channel)
+	if err != nil {
+		return 0, err
+	}
+	return client.PublishContext(ctx, channel, message)
+}
+
+// Subscribe call streams messages of the channel from its node
+func (sharded *ShardedClient) Subscribe(ctx context.Context, channel string) (<-chan pubsub.Message, error) {
+	client, err := sharded.ClientFor(channel)
+	if err != nil {
+		return nil, err
+	}
+	return client.Subscribe(ctx, channel)
+}
+
+// PSubscribe call merges messages of the channels matching the pattern from all the healthy nodes
+func (sharded *ShardedClient) PSubscribe(ctx context.Context, pattern string) (<-chan pubsub.Message, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	nodes := sharded.healthyNodes()
+	inputs := make([]<-chan pubsub.Message, len(nodes))
+	err := scatter(nodes, func(i int, node *shardNode) error {
+		var err error
+		inputs[i], err = node.client.PSubscribe(ctx, pattern)
+		return err
+	})
+	if err == nil && len(nodes) == 0 {
+		err = ErrNoNodes
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	messages := make(chan pubsub.Message)
+	var wait sync.WaitGroup
+	for _, input := range inputs {
+		wait.Add(1)
+		go func(input <-chan pubsub.Message) {
+			defer wait.Done()
+			for message := range input {
+				select {
+				case messages <- message:
+				case <-ctx.Done():
+				}
+			}
+		}(input)
+	}
+	go func() {
+		wait.Wait()
+		cancel()
+		close(messages)
+	}()
+	return messages, nil
+}
+
+// Notifications call merges keyspace notifications of all the healthy nodes
+func (sharded *ShardedClient) Notifications(ctx context.Context, match string, events ...storage.MutationType) (<-chan KeyspaceEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	nodes := sharded.healthyNodes()
+	inputs := make([]<-chan KeyspaceEvent, len(nodes))
+	err := scatter(nodes, func(i int, node *shardNode) error {
+		var err error
+		inputs[i], err = node.client.Notifications(ctx, match, events...)
+		return err
+	})
+	if err == nil && len(nodes) == 0 {
+		err = ErrNoNodes
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	notifications := make(chan KeyspaceEvent)
+	var wait sync.WaitGroup
+	for _, input := range inputs {
+		wait.Add(1)
+		go func(input <-chan KeyspaceEvent) {
+			defer wait.Done()
+			for event := range input {
+				select {
+				case notifications <- event:
+				case <-ctx.Done():
+				}
+			}
+		}(input)
+	}
+	go func() {
+		wait.Wait()
+		cancel()
+		close(notifications)
+	}()
+	return notifications, nil
+}
+
+// Replicate call is not supported: a full sync of a node would remove the keys of the others from the replica.
+// Every node should be replicated to its own replica instead
+func (sharded *ShardedClient) Replicate(ctx context.Context, replica *storage.Replica) error {
+	return errors.New("Replication of sharded nodes is not supported, replicate every node separately")
+}
+
+// LoadSlots call is not supported as keys are spread by the client itself
+func (sharded *ShardedClient) LoadSlots() error {
+	return sharded.LoadSlotsContext(context.Background())
+}
+
+// LoadSlotsContext ...
+func (sharded *ShardedClient) LoadSlotsContext(ctx context.Context) error {
+	return errors.New("Slot routing is not supported by sharded client")
+}
+
+// Slots call always returns nil, see LoadSlots
+func (sharded *ShardedClient) Slots() []slots.Range {
+	return nil
+}
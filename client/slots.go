@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/slots"
+)
+
+// maxRedirects is the number of redirects the client follows, the same as http.DefaultClient does
+const maxRedirects = 10
+
+// LoadSlots call fetches the slot map from the server the client is created for and enables slot routing:
+// requests to entries are sent directly to the nodes owning them. Slots moved afterwards are learnt
+// from redirects of the nodes, the call could be repeated to refresh the whole map.
+// Requests to other keys than a single one (e.g. GetKeys) are still sent to the server the client is created for
+func (client *GedisClient) LoadSlots() error {
+	return client.LoadSlotsContext(context.Background())
+}
+
+// LoadSlotsContext ...
+func (client *GedisClient) LoadSlotsContext(ctx context.Context) error {
+	var ranges []slots.Range
+	if found, err := client.call(ctx, http.MethodGet, "slots", nil, &ranges); err != nil || !found {
+		if err == nil {
+			err = errors.New("Slot map is not found")
+		}
+		return err
+	}
+
+	slotMap := client.slotMap()
+	if slotMap == nil {
+		slotMap = slots.NewMap()
+	}
+	if err := slotMap.SetRanges(ranges); err != nil {
+		return err
+	}
+	client.slots.Store(slotMap)
+	return nil
+}
+
+// Slots call returns the slot map known to the client, nil if slot routing is disabled
+func (client *GedisClient) Slots() []slots.Range {
+	if slotMap := client.slotMap(); slotMap != nil {
+		return slotMap.Ranges()
+	}
+	return nil
+}
+
+func (client *GedisClient) slotMap() *slots.Map {
+	slotMap, _ := client.slots.Load().(*slots.Map)
+	return slotMap
+}
+
+// slotOwner call returns the node owning the entry of the path, empty if it is unknown or the path is not of an entry
+func (client *GedisClient) slotOwner(path string) string {
+	slotMap := client.slotMap()
+	if slotMap == nil || !strings.HasPrefix(path, "entries/") {
+		return ""
+	}
+	key := strings.TrimPrefix(path, "entries/")
+	if end := strings.IndexAny(key, "/?"); end >= 0 {
+		key = key[:end]
+	}
+	return slotMap.Owner(slots.KeySlot(key))
+}
+
+// learnSlot call is a part of the redirect policy of the client: once a node redirects a request to the owner
+// of the slot, the owner is remembered, so next requests to the slot go there directly
+func (client *GedisClient) learnSlot(request *http.Request) {
+	slotMap := client.slotMap()
+	if slotMap == nil || request.Response == nil {
+		return
+	}
+	if slot, err := strconv.Atoi(request.Response.Header.Get(slots.Header)); err == nil {
+		slotMap.Assign(slot, slot, request.URL.Host)
+	}
+}
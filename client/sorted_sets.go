@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// memberInfo is a response of the sorted set member lookup
+type memberInfo struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+	Rank   int     `json:"rank"`
+}
+
+func orderQuery(reverse bool) string {
+	if reverse {
+		return "order=desc"
+	}
+	return "order=asc"
+}
+
+func scoreBound(score float64, exclusive bool) string {
+	bound := strconv.FormatFloat(score, 'g', -1, 64)
+	if exclusive {
+		return "(" + bound
+	}
+	return bound
+}
+
+// AddToSortedSet call atomically sets scores of the members, the sorted set is created if there is no such key.
+// Returns the number of new members
+func (client *GedisClient) AddToSortedSet(key string, members ...storage.ScoredMember) (int, error) {
+	return client.AddToSortedSetContext(context.Background(), key, members...)
+}
+
+// AddToSortedSetContext ...
+func (client *GedisClient) AddToSortedSetContext(ctx context.Context, key string, members ...storage.ScoredMember) (int, error) {
+	scores := make(map[string]float64, len(members))
+	for _, member := range members {
+		scores[member.Member] = member.Score
+	}
+	var added int
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/scores", scores, &added)
+	return added, err
+}
+
+// IncrementSortedSetScore call atomically adds delta to the score of the member and returns the new score.
+// The member is added with delta score if it is not in the sorted set
+func (client *GedisClient) IncrementSortedSetScore(key string, member string, delta float64) (float64, error) {
+	return client.IncrementSortedSetScoreContext(context.Background(), key, member, delta)
+}
+
+// IncrementSortedSetScoreContext ...
+func (client *GedisClient) IncrementSortedSetScoreContext(ctx context.Context, key string, member string, delta float64) (float64, error) {
+	var score float64
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/scores/"+member+"/increment", delta, &score)
+	return score, err
+}
+
+// RemoveFromSortedSet call atomically removes members of the sorted set.
+// Returns the number of members which were in the sorted set
+func (client *GedisClient) RemoveFromSortedSet(key string, members ...string) (int, error) {
+	return client.RemoveFromSortedSetContext(context.Background(), key, members...)
+}
+
+// RemoveFromSortedSetContext ...
+func (client *GedisClient) RemoveFromSortedSetContext(ctx context.Context, key string, members ...string) (int, error) {
+	var removed int
+	_, err := client.call(ctx, http.MethodDelete, "entries/"+key+"/scores", members, &removed)
+	return removed, err
+}
+
+// GetSortedSetScore ...
+func (client *GedisClient) GetSortedSetScore(key string, member string) (float64, bool, error) {
+	return client.GetSortedSetScoreContext(context.Background(), key, member)
+}
+
+// GetSortedSetScoreContext ...
+func (client *GedisClient) GetSortedSetScoreContext(ctx context.Context, key string, member string) (float64, bool, error) {
+	var info memberInfo
+	exists, err := client.call(ctx, http.MethodGet, "entries/"+key+"/scores/"+member, nil, &info)
+	return info.Score, exists, err
+}
+
+// GetSortedSetRank call returns 0-based rank of the member in ascending or descending (reverse) order
+func (client *GedisClient) GetSortedSetRank(key string, member string, reverse bool) (int, bool, error) {
+	return client.GetSortedSetRankContext(context.Background(), key, member, reverse)
+}
+
+// GetSortedSetRankContext ...
+func (client *GedisClient) GetSortedSetRankContext(ctx context.Context, key string, member string, reverse bool) (int, bool, error) {
+	var info memberInfo
+	exists, err := client.call(ctx, http.MethodGet, "entries/"+key+"/scores/"+member+"?"+orderQuery(reverse), nil, &info)
+	return info.Rank, exists, err
+}
+
+// GetSortedSetCardinality ...
+func (client *GedisClient) GetSortedSetCardinality(key string) (int, error) {
+	return client.GetSortedSetCardinalityContext(context.Background(), key)
+}
+
+// GetSortedSetCardinalityContext ...
+func (client *GedisClient) GetSortedSetCardinalityContext(ctx context.Context, key string) (int, error) {
+	var cardinality int
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/scores?view=length", nil, &cardinality)
+	return cardinality, err
+}
+
+// GetSortedSetRangeByRank call returns members from start to stop inclusive in ascending or descending (reverse) order.
+// Negative indexes are counted from the end
+func (client *GedisClient) GetSortedSetRangeByRank(key string, start int, stop int, reverse bool) ([]storage.ScoredMember, error) {
+	return client.GetSortedSetRangeByRankContext(context.Background(), key, start, stop, reverse)
+}
+
+// GetSortedSetRangeByRankContext ...
+func (client *GedisClient) GetSortedSetRangeByRankContext(ctx context.Context, key string, start int, stop int, reverse bool) ([]storage.ScoredMember, error) {
+	members := []storage.ScoredMember{}
+	path := "entries/" + key + "/scores" + rangeQuery(start, stop) + "&" + orderQuery(reverse)
+	_, err := client.call(ctx, http.MethodGet, path, nil, &members)
+	return members, err
+}
+
+// GetSortedSetRangeByScore call returns up to count members within the range in ascending or descending (reverse)
+// order skipping offset of them. Negative count means no limit
+func (client *GedisClient) GetSortedSetRangeByScore(key string, scoreRange storage.ScoreRange, reverse bool, offset int, count int) ([]storage.ScoredMember, error) {
+	return client.GetSortedSetRangeByScoreContext(context.Background(), key, scoreRange, reverse, offset, count)
+}
+
+// GetSortedSetRangeByScoreContext ...
+func (client *GedisClient) GetSortedSetRangeByScoreContext(ctx context.Context, key string, scoreRange storage.ScoreRange, reverse bool, offset int, count int) ([]storage.ScoredMember, error) {
+	query := url.Values{}
+	query.Set("min", scoreBound(scoreRange.Min, scoreRange.MinExclusive))
+	query.Set("max", scoreBound(scoreRange.Max, scoreRange.MaxExclusive))
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("count", strconv.Itoa(count))
+	members := []storage.ScoredMember{}
+	_, err := client.call(ctx, http.MethodGet, "entries/"+key+"/scores?"+query.Encode()+"&"+orderQuery(reverse), nil, &members)
+	return members, err
+}
+
+// PopFromSortedSet call atomically removes and returns up to count members with the lowest or the highest (max) scores
+func (client *GedisClient) PopFromSortedSet(key string, max bool, count int) ([]storage.ScoredMember, error) {
+	return client.PopFromSortedSetContext(context.Background(), key, max, count)
+}
+
+// PopFromSortedSetContext ...
+func (client *GedisClient) PopFromSortedSetContext(ctx context.Context, key string, max bool, count int) ([]storage.ScoredMember, error) {
+	side := "min"
+	if max {
+		side = "max"
+	}
+	members := []storage.ScoredMember{}
+	_, err := client.call(ctx, http.MethodPost, "entries/"+key+"/scores/pop?side="+side+"&count="+strconv.Itoa(count), nil, &members)
+	return members, err
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+type txCommand struct {
+	Command string      `json:"command"`
+	Key     string      `json:"key"`
+	Field   string      `json:"field,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+	Type    string      `json:"type,omitempty"`
+	Side    string      `json:"side,omitempty"`
+	TTL     string      `json:"ttl,omitempty"`
+}
+
+type txRequest struct {
+	Watch    map[string]uint64 `json:"watch,omitempty"`
+	Commands []txCommand       `json:"commands"`
+}
+
+// TxResults are results of the transaction commands in the order they were added
+type TxResults []json.RawMessage
+
+// Decode call decodes the result of i-th command into v
+func (results TxResults) Decode(i int, v interface{}) error {
+	return json.Unmarshal(results[i], v)
+}
+
+// Transaction is a builder of a multi-key transaction. Commands are sent to the server on Exec
+// and are applied all or nothing. Transactions of ShardedClient are sent to the node of their keys
+type Transaction struct {
+	client  *GedisClient
+	sharded *ShardedClient
+	request txRequest
+}
+
+// Transaction call starts building a new transaction
+func (client *GedisClient) Transaction() *Transaction {
+	return &Transaction{client: client, request: txRequest{Watch: map[string]uint64{}}}
+}
+
+func (tx *Transaction) add(command txCommand) *Transaction {
+	tx.request.Commands = append(tx.request.Commands, command)
+	return tx
+}
+
+// Watch call aborts the transaction if the item does not have the version at the moment of Exec.
+// Zero version means the item should be absent
+func (tx *Transaction) Watch(key string, version uint64) *Transaction {
+	tx.request.Watch[key] = version
+	return tx
+}
+
+// Get call adds reading of the item as it is seen by the transaction
+func (tx *Transaction) Get(key string) *Transaction {
+	return tx.add(txCommand{Command: "get", Key: key})
+}
+
+// Set call adds storing of the item regardless of the current one
+func (tx *Transaction) Set(key string, item storage.Storable) *Transaction {
+	return tx.SetWithTTL(key, item, 0)
+}
+
+// SetWithTTL works as Set but sets the item specific TTL. Non-positive ttl means the default TTL of the server
+func (tx *Transaction) SetWithTTL(key string, item storage.Storable, ttl time.Duration) *Transaction {
+	command := txCommand{Command: "set", Key: key, Value: item}
+	if ttl > 0 {
+		command.TTL = ttl.String()
+	}
+	switch item.(type) {
+	case storage.Set:
+		command.Type = "set"
+	case *storage.SortedSet:
+		command.Type = "zset"
+	}
+	return tx.add(command)
+}
+
+// Delete ...
+func (tx *Transaction) Delete(key string) *Transaction {
+	return tx.add(txCommand{Command: "delete", Key: key})
+}
+
+// IncrementBy ...
+func (tx *Transaction) IncrementBy(key string, delta int64) *Transaction {
+	return tx.add(txCommand{Command: "incr", Key: key, Value: delta})
+}
+
+// IncrementByFloat ...
+func (tx *Transaction) IncrementByFloat(key string, delta float64) *Transaction {
+	return tx.add(txCommand{Command: "incr", Key: key, Value: floatNumber(delta)})
+}
+
+// PushToList ...
+func (tx *Transaction) PushToList(key string, toHead bool, values ...string) *Transaction {
+	return tx.add(txCommand{Command: "push", Key: key, Value: values, Side: side(toHead)})
+}
+
+// PopFromList ...
+func (tx *Transaction) PopFromList(key string, fromHead bool) *Transaction {
+	return tx.add(txCommand{Command: "pop", Key: key, Side: side(fromHead)})
+}
+
+// SetDictEntry ...
+func (tx *Transaction) SetDictEntry(key string, subKey string, value string) *Transaction {
+	return tx.add(txCommand{Command: "hset", Key: key, Field: subKey, Value: value})
+}
+
+// DeleteDictEntry ...
+func (tx *Transaction) DeleteDictEntry(key string, subKey string) *Transaction {
+	return tx.add(txCommand{Command: "hdel", Key: key, Field: subKey})
+}
+
+// AddToSet ...
+func (tx *Transaction) AddToSet(key string, members ...string) *Transaction {
+	return tx.add(txCommand{Command: "sadd", Key: key, Value: members})
+}
+
+// RemoveFromSet ...
+func (tx *Transaction) RemoveFromSet(key string, members ...string) *Transaction {
+	return tx.add(txCommand{Command: "srem", Key: key, Value: members})
+}
+
+// AddToSortedSet ...
+func (tx *Transaction) AddToSortedSet(key string, members ...storage.ScoredMember) *Transaction {
+	scores := make(map[string]float64, len(members))
+	for _, member := range members {
+		scores[member.Member] = member.Score
+	}
+	return tx.add(txCommand{Command: "zadd", Key: key, Value: scores})
+}
+
+// IncrementSortedSetScore ...
+func (tx *Transaction) IncrementSortedSetScore(key string, member string, delta float64) *Transaction {
+	return tx.add(txCommand{Command: "zincrby", Key: key, Field: member, Value: delta})
+}
+
+// Exec call sends the transaction to the server. storage.ErrTransactionAborted is returned
+// if any of the watched items is changed, nothing is applied on any error
+func (tx *Transaction) Exec() (TxResults, error) {
+	return tx.ExecContext(context.Background())
+}
+
+// ExecContext ...
+func (tx *Transaction) ExecContext(ctx context.Context) (TxResults, error) {
+	results := TxResults{}
+	client := tx.client
+	if tx.sharded != nil {
+		var err error
+		if client, err = tx.sharded.clientForKeys(tx.keys()...); err != nil {
+			return results, err
+		}
+	}
+	_, err := client.call(ctx, http.MethodPost, "tx", tx.request, &results)
+	return results, err
+}
+
+// keys call returns the keys of the commands and the watched ones
+func (tx *Transaction) keys() []string {
+	var keys []string
+	for key := range tx.request.Watch {
+		keys = append(keys, key)
+	}
+	for _, command := range tx.request.Commands {
+		keys = append(keys, command.Key)
+	}
+	return keys
+}
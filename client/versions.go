@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// VersionConflictError is returned by conditional writes when the entry has been changed
+// since the expected version was read
+type VersionConflictError struct {
+	Key             string
+	ExpectedVersion uint64
+	// ActualVersion is zero if there is no such entry
+	ActualVersion uint64
+}
+
+func (err *VersionConflictError) Error() string {
+	if err.ActualVersion == 0 {
+		return "Entry " + err.Key + " of version " + strconv.FormatUint(err.ExpectedVersion, 10) + " does not exist anymore"
+	}
+	return "Entry " + err.Key + " has version " + strconv.FormatUint(err.ActualVersion, 10) +
+		" instead of " + strconv.FormatUint(err.ExpectedVersion, 10)
+}
+
+func formatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseETag call returns zero version if there is no ETag header
+func parseETag(header http.Header) (uint64, error) {
+	tag := header.Get("ETag")
+	if tag == "" {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`), 10, 64)
+	if err != nil {
+		return 0, errors.New("Unexpected ETag " + tag)
+	}
+	return version, nil
+}
+
+// conditionalWriteError call turns failed precondition into VersionConflictError
+func conditionalWriteError(key string, version uint64, statusCode int, header http.Header, body []byte) error {
+	if statusCode != http.StatusPreconditionFailed {
+		return statusError(statusCode, body)
+	}
+	actualVersion, err := parseETag(header)
+	if err != nil {
+		return err
+	}
+	return &VersionConflictError{key, version, actualVersion}
+}
+
+// GetItemWithVersion works as GetItem but also returns the version of the item
+// to be passed to conditional writes
+func (client *GedisClient) GetItemWithVersion(key string) (storage.Storable, uint64, bool, error) {
+	return client.GetItemWithVersionContext(context.Background(), key)
+}
+
+// GetItemWithVersionContext ...
+func (client *GedisClient) GetItemWithVersionContext(ctx context.Context, key string) (storage.Storable, uint64, bool, error) {
+	item, _, version, exists, err := client.getEntry(ctx, "entries/"+key)
+	return item, version, exists, err
+}
+
+// UpdateItemIfVersion call replaces the item only if it has not been changed since the version was read.
+// *VersionConflictError is returned otherwise. The new version of the item is returned on success
+func (client *GedisClient) UpdateItemIfVersion(key string, item storage.Storable, version uint64) (uint64, error) {
+	return client.UpdateItemIfVersionContext(context.Background(), key, item, version)
+}
+
+// UpdateItemIfVersionContext ...
+func (client *GedisClient) UpdateItemIfVersionContext(ctx context.Context, key string, item storage.Storable, version uint64) (uint64, error) {
+	return client.UpdateItemIfVersionWithTTLContext(ctx, key, item, version, 0)
+}
+
+// UpdateItemIfVersionWithTTL works as UpdateItemIfVersion but sets the item specific TTL.
+// Non-positive ttl means the default TTL of the server
+func (client *GedisClient) UpdateItemIfVersionWithTTL(key string, item storage.Storable, version uint64, ttl time.Duration) (uint64, error) {
+	return client.UpdateItemIfVersionWithTTLContext(context.Background(), key, item, version, ttl)
+}
+
+// UpdateItemIfVersionWithTTLContext ...
+func (client *GedisClient) UpdateItemIfVersionWithTTLContext(ctx context.Context, key string, item storage.Storable, version uint64, ttl time.Duration) (uint64, error) {
+	header := http.Header{"If-Match": {formatETag(version)}}
+	statusCode, responseHeader, responseBody, err := client.doWithHeaders(ctx, http.MethodPut, "entries/"+key+entryQuery(item, ttl), item, header)
+	if err != nil {
+		return 0, err
+	}
+	if statusCode != http.StatusNoContent {
+		return 0, conditionalWriteError(key, version, statusCode, responseHeader, responseBody)
+	}
+	return parseETag(responseHeader)
+}
+
+// DeleteItemIfVersion call removes the item only if it has not been changed since the version was read.
+// *VersionConflictError is returned otherwise
+func (client *GedisClient) DeleteItemIfVersion(key string, version uint64) error {
+	return client.DeleteItemIfVersionContext(context.Background(), key, version)
+}
+
+// DeleteItemIfVersionContext ...
+func (client *GedisClient) DeleteItemIfVersionContext(ctx context.Context, key string, version uint64) error {
+	header := http.Header{"If-Match": {formatETag(version)}}
+	statusCode, responseHeader, responseBody, err := client.doWithHeaders(ctx, http.MethodDelete, "entries/"+key, nil, header)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusNoContent {
+		return conditionalWriteError(key, version, statusCode, responseHeader, responseBody)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// watchTimeout is how long a single long poll waits for a change
+const watchTimeout = 30 * time.Second
+
+// WatchedItem is a state of the watched item. Version is zero if the item does not exist
+type WatchedItem struct {
+	Item    storage.Storable
+	Version uint64
+	Exists  bool
+}
+
+// WatchItem call sends the current state of the item to the returned channel and then every new one
+// until ctx is done, then the channel is closed. Failed polls are retried with a backoff.
+// States the item passes through between polls are skipped, so only the latest one is guaranteed to be sent
+func (client *GedisClient) WatchItem(ctx context.Context, key string) (<-chan WatchedItem, error) {
+	current, _, err := client.pollItem(ctx, "entries/"+key)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(chan WatchedItem)
+	go func() {
+		defer close(states)
+		delay := 100 * time.Millisecond
+		for {
+			select {
+			case states <- current:
+			case <-ctx.Done():
+				return
+			}
+
+			// versions always increase, so the same version means the same state
+			for previous := current.Version; current.Version == previous; {
+				query := url.Values{}
+				query.Set("watch", "true")
+				query.Set("version", strconv.FormatUint(previous, 10))
+				query.Set("timeout", watchTimeout.String())
+
+				state, changed, err := client.pollItem(ctx, "entries/"+key+"?"+query.Encode())
+				if err == nil {
+					delay = 100 * time.Millisecond
+					if changed {
+						current = state
+					}
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+				if delay *= 2; delay > maxReconnectDelay {
+					delay = maxReconnectDelay
+				}
+			}
+		}
+	}()
+	return states, nil
+}
+
+// pollItem call returns false if the item is not modified. Long polls are not limited by the timeout of the client
+func (client *GedisClient) pollItem(ctx context.Context, path string) (WatchedItem, bool, error) {
+	request, err := client.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return WatchedItem{}, false, err
+	}
+	response, err := client.longLiving().Do(request)
+	if err != nil {
+		return WatchedItem{}, false, err
+	}
+	body, err := readBody(response)
+	if err != nil {
+		return WatchedItem{}, false, err
+	}
+	if response.StatusCode == http.StatusNotModified {
+		return WatchedItem{}, false, nil
+	}
+
+	item, _, version, exists, err := parseEntry(response.StatusCode, response.Header, body)
+	if err != nil {
+		return WatchedItem{}, false, err
+	}
+	return WatchedItem{item, version, exists}, true, nil
+}
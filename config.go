@@ -1,9 +1,59 @@
 package main
 
-import "time"
+import (
+  "time"
+
+  "github.com/izhamoidsin/gedis/storage"
+)
 
 var ttl = time.Minute * 1
 
+// storage implementation: "syncmap" suits read-mostly workloads, "sharded" suits write-heavy ones
+var storageEngine = "syncmap"
+var shardsCount = 64
+
+// approximate limit of memory taken by entries in bytes, 0 means no limit
+var maxMemory int64 = 0
+var maxMemoryPolicy = storage.NoEviction
+
+// how often expired entries are reclaimed in background
+var vacuumInterval = time.Millisecond * 100
+
+// append-only file persistence, empty path disables it
+var aofPath = "gedis.aof"
+var aofFsyncPolicy = storage.FsyncEverySecond
+// the file is compacted when it grows past the threshold and doubles since the last compaction
+var aofRewriteThreshold int64 = 64 * 1024 * 1024
+
+// snapshot of the whole keyspace loaded on startup, empty path disables it
+var snapshotPath = "gedis.snapshot"
+var snapshotInterval = time.Minute * 5
+
+// port of Redis protocol (RESP) listener, 0 disables it
+var respPort = 6380
+
+// port of memcached text protocol listener, 0 disables it
+var memcachePort = 11211
+
+// address (host:port) of the leader to replicate, empty means the server is a leader itself.
+// Followers start with a full sync, so they do not persist anything and serve HTTP API only
+var replicaOf = ""
+
+// address (host:port) of the node in a Raft cluster, empty disables cluster mode. Members are the initial
+// members of the cluster including the node, a node joining an existing cluster has none.
+// Cluster nodes keep the term, the vote and the log in clusterDataDir, so they could be restarted under the same
+// address. The storage is restored from the log, other persistence settings are ignored. They serve HTTP API only
+var clusterNodeID = ""
+var clusterMembers = []string{}
+var clusterDataDir = "gedis.raft"
+
+// address (host:port) of the node in slot mode, empty disables it. The keyspace is split into hash slots
+// spread evenly over the nodes in their order, so all the nodes should have the same list.
+// The slot map is not persisted, so nodes start with the even split after a restart.
+// RESP and memcached listeners are disabled in slot mode as they do not redirect clients
+var slotNode = ""
+var slotNodes = []string{}
+
 const(
   port = 8081
 )
@@ -0,0 +1,124 @@
+// Package glob matches keys and channel names against glob patterns the way Redis does.
+// Unlike path.Match, `*` matches any sequence of bytes including `/`
+package glob
+
+import "errors"
+
+// ErrBadPattern is returned for patterns with an unterminated class or a trailing escape
+var ErrBadPattern = errors.New("syntax error in pattern")
+
+// Validate call checks the syntax of the pattern
+func Validate(pattern string) error {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i++; i == len(pattern) {
+				return ErrBadPattern
+			}
+		case '[':
+			end, closed := classEnd(pattern, i+1)
+			if !closed {
+				return ErrBadPattern
+			}
+			i = end
+		}
+	}
+	return nil
+}
+
+// Match call reports whether the whole name matches the pattern. `*` matches any sequence of bytes,
+// `?` any single byte, `[...]` any byte of the class (`[^...]` negates it, `a-z` is a range)
+// and `\` escapes the next byte
+func Match(pattern string, name string) (bool, error) {
+	if err := Validate(pattern); err != nil {
+		return false, err
+	}
+	return match(pattern, name), nil
+}
+
+// match call expects a valid pattern. After a mismatch it backtracks to the last star
+// letting it take one more byte of the name
+func match(pattern string, name string) bool {
+	p, n := 0, 0
+	starPattern, starName := -1, 0
+	for n < len(name) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starPattern, starName = p, n
+				p++
+				continue
+			case '?':
+				p++
+				n++
+				continue
+			case '[':
+				end, _ := classEnd(pattern, p+1)
+				if matchClass(pattern[p+1:end], name[n]) {
+					p = end + 1
+					n++
+					continue
+				}
+			case '\\':
+				if pattern[p+1] == name[n] {
+					p += 2
+					n++
+					continue
+				}
+			default:
+				if pattern[p] == name[n] {
+					p++
+					n++
+					continue
+				}
+			}
+		}
+		if starPattern < 0 {
+			return false
+		}
+		starName++
+		p, n = starPattern+1, starName
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// classEnd call returns the position of `]` closing the class which content starts at the position
+func classEnd(pattern string, start int) (int, bool) {
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i, true
+		}
+	}
+	return len(pattern), false
+}
+
+func matchClass(class string, c byte) bool {
+	negated := len(class) > 0 && class[0] == '^'
+	if negated {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class) && !matched; i++ {
+		switch {
+		case class[i] == '\\':
+			i++
+			matched = class[i] == c
+		case i+2 < len(class) && class[i+1] == '-':
+			low, high := class[i], class[i+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = low <= c && c <= high
+			i += 2
+		default:
+			matched = class[i] == c
+		}
+	}
+	return matched != negated
+}
@@ -0,0 +1,54 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	expectations := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"*", "", true},
+		{"*", "users/42/name", true},
+		{"users:*", "users:42", true},
+		{"users/*", "users/42/name", true},
+		{"*/name", "users/42/name", true},
+		{"*/age", "users/42/name", false},
+		{"a*b*c", "a/b/x/c", true},
+		{"a*b*c", "a/b/x/c/d", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "h/llo", true},
+		{"h?llo", "heello", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"h[\\]]llo", "h]llo", true},
+		{"\\*", "*", true},
+		{"\\*", "a", false},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+	}
+	for _, expectation := range expectations {
+		if matched, err := Match(expectation.pattern, expectation.name); err != nil || matched != expectation.matched {
+			t.Errorf("Match of %q against %q is %t (%v)", expectation.name, expectation.pattern, matched, err)
+		}
+	}
+}
+
+func TestBadPattern(t *testing.T) {
+	for _, pattern := range []string{"[abc", "abc\\", "a[\\]"} {
+		if _, err := Match(pattern, "abc"); err != ErrBadPattern {
+			t.Errorf("Malformed pattern %q is accepted", pattern)
+		}
+		if err := Validate(pattern); err != ErrBadPattern {
+			t.Errorf("Malformed pattern %q is validated", pattern)
+		}
+	}
+	if err := Validate("[a-z]*\\?"); err != nil {
+		t.Error("Valid pattern is rejected")
+	}
+}
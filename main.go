@@ -1,16 +1,157 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
+	"github.com/izhamoidsin/gedis/client"
+	"github.com/izhamoidsin/gedis/memcache"
+	"github.com/izhamoidsin/gedis/raft"
+	"github.com/izhamoidsin/gedis/resp"
 	"github.com/izhamoidsin/gedis/server"
+	"github.com/izhamoidsin/gedis/slots"
 	"github.com/izhamoidsin/gedis/storage"
 )
 
+// registry is a storage which could be persisted and expired in background
+type registry interface {
+	storage.PersistableStorage
+	storage.ActiveExpireStorage
+	storage.MemoryLimitedStorage
+}
+
 // Runs the server according to the config
 func main() {
-	var storage storage.Storage = storage.InitSyncStorage(ttl)
-	var server = server.CreateServer(storage)
+	var registry = createRegistry()
+	if replicaOf != "" {
+		startFollower(registry)
+		return
+	}
+	if clusterNodeID != "" {
+		startClusterNode(registry)
+		return
+	}
+	var closers []io.Closer
+	var snapshotter *storage.Snapshotter
+	if snapshotPath != "" {
+		snapshotter = storage.NewSnapshotter(registry, snapshotPath)
+	}
+	if aofPath != "" {
+		// the append-only file has every change, the snapshot is loaded only if there is no such file yet
+		var aof *storage.AppendOnlyFile
+		var err error
+		if snapshotter != nil {
+			aof, err = storage.OpenAppendOnlyFileWithSnapshot(registry, snapshotter, aofPath, aofFsyncPolicy, aofRewriteThreshold)
+		} else {
+			aof, err = storage.OpenAppendOnlyFile(registry, aofPath, aofFsyncPolicy, aofRewriteThreshold)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		closers = append(closers, aof)
+	} else if snapshotter != nil {
+		if err := snapshotter.Load(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if snapshotter != nil {
+		if err := snapshotter.StartPeriodicSnapshots(snapshotInterval); err != nil {
+			log.Fatal(err)
+		}
+		closers = append(closers, snapshotter)
+	}
+	registry.SetMaxMemory(maxMemory, maxMemoryPolicy)
+	if err := registry.StartVacuum(vacuumInterval); err != nil {
+		log.Fatal(err)
+	}
+	closeOnShutdown(closers...)
+	var server = server.CreateServer(registry)
+	if snapshotter != nil {
+		server.SetSnapshotter(snapshotter)
+	}
+	if slotNode != "" {
+		server.SetSlots(slotNode, slots.SplitEvenly(slotNodes...))
+	}
+
+	if respPort != 0 && slotNode == "" {
+		respServer := resp.CreateServer(registry)
+		go func() { log.Fatal(respServer.ListenAndServe(respPort)) }()
+	}
+	if memcachePort != 0 && slotNode == "" {
+		memcacheServer := memcache.CreateServer(registry)
+		go func() { log.Fatal(memcacheServer.ListenAndServe(memcachePort)) }()
+	}
+	log.Fatal(server.StartSerever(port))
+}
+
+// startFollower call runs the server as a follower of the leader at replicaOf address
+func startFollower(registry registry) {
+	host, strPort, err := net.SplitHostPort(replicaOf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	leaderPort, err := strconv.Atoi(strPort)
+	if err != nil {
+		log.Fatal("Malformed port of the leader: " + strPort)
+	}
+	registry.SetMaxMemory(maxMemory, maxMemoryPolicy)
+	if err := registry.StartVacuum(vacuumInterval); err != nil {
+		log.Fatal(err)
+	}
+
+	replica := storage.NewReplica(registry)
+	if err := client.CreateClient(host, leaderPort).Replicate(context.Background(), replica); err != nil {
+		log.Fatal(err)
+	}
+	var server = server.CreateServer(registry)
+	server.SetReplica(replica, "http://"+replicaOf)
+	log.Fatal(server.StartSerever(port))
+}
 
+// startClusterNode call runs the server as a node of Raft cluster
+func startClusterNode(registry registry) {
+	registry.SetMaxMemory(maxMemory, maxMemoryPolicy)
+
+	// there is no vacuum: expired entries are removed by the leader through the log
+	config := raft.Config{ID: clusterNodeID, Members: clusterMembers, ExpiryInterval: vacuumInterval}
+	node, err := raft.OpenNode(config, clusterDataDir, registry, raft.NewHTTPTransport(nil))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var server = server.CreateServer(registry)
+	server.SetRaftNode(node)
+	node.Start()
 	log.Fatal(server.StartSerever(port))
 }
+
+func createRegistry() registry {
+	switch storageEngine {
+	case "syncmap":
+		return storage.InitSyncMapStorage(ttl)
+	case "sharded":
+		return storage.InitShardedStorage(ttl, shardsCount)
+	}
+	log.Fatal("Unknown storage engine: " + storageEngine)
+	return nil
+}
+
+// closeOnShutdown call makes sure everything is flushed when the server is stopped by a signal
+func closeOnShutdown(closers ...io.Closer) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		for _, closer := range closers {
+			if err := closer.Close(); err != nil {
+				log.Println(err)
+			}
+		}
+		os.Exit(0)
+	}()
+}
@@ -0,0 +1,353 @@
+package memcache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+const (
+	// maxKeyLength and maxValueLength are the same as memcached has by default
+	maxKeyLength   = 250
+	maxValueLength = 1024 * 1024
+	// exptime greater than 30 days is an absolute unix time
+	maxRelativeExptime = 30 * 24 * 60 * 60
+	serverVersion      = "1.6.0-gedis"
+)
+
+const (
+	badFormat   = "bad command line format"
+	badChunk    = "bad data chunk"
+	nonNumeric  = "cannot increment or decrement non-numeric value"
+	outOfMemory = "out of memory storing object"
+)
+
+var (
+	errNotFound   = errors.New("NOT_FOUND")
+	errNonNumeric = errors.New(nonNumeric)
+)
+
+func (c *connection) reply(line string) {
+	c.writer.WriteString(line + "\r\n")
+}
+
+func (c *connection) clientError(message string) {
+	c.reply("CLIENT_ERROR " + message)
+}
+
+// serverError call reports an error of the storage
+func (c *connection) serverError(err error) {
+	if err == storage.ErrOutOfMemory {
+		c.reply("SERVER_ERROR " + outOfMemory)
+	} else {
+		c.reply("SERVER_ERROR " + err.Error())
+	}
+}
+
+// exec call runs the command, an error is returned if the connection should be closed
+func (c *connection) exec(args []string) error {
+	if len(args) == 0 {
+		c.reply("ERROR")
+		return nil
+	}
+	switch name := strings.ToLower(args[0]); name {
+	case "get", "gets":
+		c.get(args[1:], name == "gets")
+	case "set", "add", "replace", "cas":
+		return c.store(name, args[1:])
+	case "delete":
+		c.delete(args[1:])
+	case "incr", "decr":
+		c.increment(args[1:], name == "decr")
+	case "touch":
+		c.touch(args[1:])
+	case "stats":
+		c.stats()
+	case "version":
+		c.reply("VERSION " + serverVersion)
+	case "quit":
+		c.quit = true
+	default:
+		c.reply("ERROR")
+	}
+	return nil
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// noReply call checks if the optional last argument asks to suppress the reply
+func noReply(args []string, required int) (bool, bool) {
+	switch len(args) {
+	case required:
+		return false, true
+	case required + 1:
+		return true, args[required] == "noreply"
+	}
+	return false, false
+}
+
+// expiration call converts exptime to TTL of the storage. Zero exptime means the default TTL
+// of the storage, as there are no items living forever, and negative one means the item is expired
+func expiration(exptime int64) time.Duration {
+	if exptime > maxRelativeExptime {
+		if ttl := time.Until(time.Unix(exptime, 0)); ttl > 0 {
+			return ttl
+		}
+		exptime = -1
+	}
+	if exptime < 0 {
+		// the item is stored, but it is expired right away
+		return time.Nanosecond
+	}
+	return time.Duration(exptime) * time.Second
+}
+
+// get call replies with string items only along with the flags they were stored with
+func (c *connection) get(keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		c.reply("ERROR")
+		return
+	}
+	for _, key := range keys {
+		atomic.AddUint64(&c.server.stats.getCommands, 1)
+		val, exists := c.server.storage.GetValueByKey(key)
+		str, isString := "", false
+		if exists {
+			str, isString = val.Entity.(string)
+		}
+		if !isString {
+			atomic.AddUint64(&c.server.stats.getMisses, 1)
+			continue
+		}
+		atomic.AddUint64(&c.server.stats.getHits, 1)
+		header := "VALUE " + key + " " + strconv.FormatUint(uint64(val.Flags), 10) + " " + strconv.Itoa(len(str))
+		if withCAS {
+			header += " " + strconv.FormatUint(val.Version, 10)
+		}
+		c.reply(header)
+		c.reply(str)
+	}
+	c.reply("END")
+}
+
+// store call serves set, add, replace and cas: `<command> <key> <flags> <exptime> <bytes> [<cas unique>] [noreply]`
+// followed by the data block. Versions of the items are used as cas uniques
+func (c *connection) store(name string, args []string) error {
+	required := 4
+	if name == "cas" {
+		required = 5
+	}
+	if len(args) < 4 {
+		c.reply("ERROR")
+		return nil
+	}
+	length, err := strconv.Atoi(args[3])
+	if err != nil || length < 0 {
+		// the data block could not be skipped without its length
+		c.clientError(badFormat)
+		return errors.New(badFormat)
+	}
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return err
+	}
+	if data[length] != '\r' || data[length+1] != '\n' {
+		c.clientError(badChunk)
+		return errors.New(badChunk)
+	}
+
+	atomic.AddUint64(&c.server.stats.setCommands, 1)
+	silent, valid := noReply(args, required)
+	flags, flagsErr := strconv.ParseUint(args[1], 10, 32)
+	exptime, exptimeErr := strconv.ParseInt(args[2], 10, 64)
+	if !valid || !validKey(args[0]) || flagsErr != nil || exptimeErr != nil {
+		c.clientError(badFormat)
+		return nil
+	}
+	if length > maxValueLength {
+		c.reply("SERVER_ERROR object too large for cache")
+		return nil
+	}
+
+	precondition := storage.Precondition(storage.Always)
+	switch name {
+	case "add":
+		precondition = storage.IfAbsent
+	case "replace":
+		precondition = storage.IfExists
+	case "cas":
+		unique, err := strconv.ParseUint(args[4], 10, 64)
+		if err != nil {
+			c.clientError(badFormat)
+			return nil
+		}
+		precondition = storage.IfVersion(unique)
+	}
+
+	current, err := c.server.storage.SetFlaggedValueByKeyIf(args[0], string(data[:length]), uint32(flags), expiration(exptime), precondition)
+	result := "STORED"
+	switch {
+	case err == storage.ErrVersionConflict && name != "cas":
+		result = "NOT_STORED"
+	case err == storage.ErrVersionConflict && current == nil:
+		atomic.AddUint64(&c.server.stats.casMisses, 1)
+		result = "NOT_FOUND"
+	case err == storage.ErrVersionConflict:
+		atomic.AddUint64(&c.server.stats.casBadValue, 1)
+		result = "EXISTS"
+	case err != nil:
+		c.serverError(err)
+		return nil
+	case name == "cas":
+		atomic.AddUint64(&c.server.stats.casHits, 1)
+	}
+	if !silent {
+		c.reply(result)
+	}
+	return nil
+}
+
+func (c *connection) delete(args []string) {
+	silent, valid := noReply(args, 1)
+	if !valid {
+		c.reply("ERROR")
+		return
+	}
+	result := "NOT_FOUND"
+	if c.server.storage.DeleteValueByKey(args[0]) {
+		result = "DELETED"
+	}
+	if !silent {
+		c.reply(result)
+	}
+}
+
+// increment call serves incr and decr of unsigned 64-bit numbers: incr wraps around, decr stops at zero.
+// Absent items are not created, flags of the items are kept
+func (c *connection) increment(args []string, decrement bool) {
+	silent, valid := noReply(args, 2)
+	if !valid {
+		c.reply("ERROR")
+		return
+	}
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		c.clientError("invalid numeric delta argument")
+		return
+	}
+
+	var value uint64
+	err = c.server.storage.ExecTransaction(nil, func(tx *storage.Tx) error {
+		val, exists := tx.GetValueByKey(args[0])
+		if !exists {
+			return errNotFound
+		}
+		str, isString := val.Entity.(string)
+		current, err := strconv.ParseUint(strings.TrimSpace(str), 10, 64)
+		if !isString || err != nil {
+			return errNonNumeric
+		}
+		switch {
+		case !decrement:
+			value = current + delta
+		case current > delta:
+			value = current - delta
+		default:
+			value = 0
+		}
+		_, err = tx.SetFlaggedValueByKeyIf(args[0], strconv.FormatUint(value, 10), val.Flags, val.TTL, storage.Always)
+		return err
+	})
+
+	switch {
+	case err == errNotFound:
+		if !silent {
+			c.reply("NOT_FOUND")
+		}
+	case err == errNonNumeric:
+		c.clientError(nonNumeric)
+	case err != nil:
+		c.serverError(err)
+	case !silent:
+		c.reply(strconv.FormatUint(value, 10))
+	}
+}
+
+// touch call rewrites the item with the new TTL keeping its flags
+func (c *connection) touch(args []string) {
+	silent, valid := noReply(args, 2)
+	if !valid {
+		c.reply("ERROR")
+		return
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.clientError("invalid exptime argument")
+		return
+	}
+
+	err = c.server.storage.ExecTransaction(nil, func(tx *storage.Tx) error {
+		val, exists := tx.GetValueByKey(args[0])
+		if !exists {
+			return errNotFound
+		}
+		_, err := tx.SetFlaggedValueByKeyIf(args[0], val.Entity, val.Flags, expiration(exptime), storage.Always)
+		return err
+	})
+
+	result := "TOUCHED"
+	switch err {
+	case nil:
+	case errNotFound:
+		result = "NOT_FOUND"
+	default:
+		c.serverError(err)
+		return
+	}
+	if !silent {
+		c.reply(result)
+	}
+}
+
+func (c *connection) stats() {
+	server := c.server
+	counters := []struct {
+		name  string
+		value string
+	}{
+		{"pid", strconv.Itoa(os.Getpid())},
+		{"uptime", strconv.FormatInt(int64(time.Since(server.startTime)/time.Second), 10)},
+		{"time", strconv.FormatInt(time.Now().Unix(), 10)},
+		{"version", serverVersion},
+		{"curr_connections", strconv.Itoa(server.connectedClients())},
+		{"total_connections", strconv.FormatUint(atomic.LoadUint64(&server.stats.totalConnections), 10)},
+		{"cmd_get", strconv.FormatUint(atomic.LoadUint64(&server.stats.getCommands), 10)},
+		{"cmd_set", strconv.FormatUint(atomic.LoadUint64(&server.stats.setCommands), 10)},
+		{"get_hits", strconv.FormatUint(atomic.LoadUint64(&server.stats.getHits), 10)},
+		{"get_misses", strconv.FormatUint(atomic.LoadUint64(&server.stats.getMisses), 10)},
+		{"cas_hits", strconv.FormatUint(atomic.LoadUint64(&server.stats.casHits), 10)},
+		{"cas_misses", strconv.FormatUint(atomic.LoadUint64(&server.stats.casMisses), 10)},
+		{"cas_badval", strconv.FormatUint(atomic.LoadUint64(&server.stats.casBadValue), 10)},
+		{"curr_items", strconv.Itoa(len(server.storage.GetAllKeys()))},
+	}
+	for _, counter := range counters {
+		c.reply("STAT " + counter.name + " " + counter.value)
+	}
+	c.reply("END")
+}
@@ -0,0 +1,164 @@
+package memcache
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// Server speaks memcached ASCII protocol over TCP. Items are stored as strings of the storage,
+// so they are seen by HTTP API and other listeners as well
+type Server struct {
+	storage   storage.Storage
+	startTime time.Time
+
+	lock        sync.Mutex
+	listener    net.Listener
+	connections map[net.Conn]struct{}
+	closed      bool
+
+	stats stats
+}
+
+// stats are counters reported by `stats` command, they are updated atomically
+type stats struct {
+	totalConnections uint64
+	getCommands      uint64
+	setCommands      uint64
+	getHits          uint64
+	getMisses        uint64
+	casHits          uint64
+	casMisses        uint64
+	casBadValue      uint64
+}
+
+// CreateServer ...
+func CreateServer(registry storage.Storage) *Server {
+	server := new(Server)
+	server.storage = registry
+	server.connections = make(map[net.Conn]struct{})
+	return server
+}
+
+// ListenAndServe call accepts connections on the port until the server is closed
+func (server *Server) ListenAndServe(port int) error {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	log.Println("Starting memcached server @ port " + strconv.Itoa(port))
+	return server.Serve(listener)
+}
+
+// Serve call accepts connections of the listener until the server is closed
+func (server *Server) Serve(listener net.Listener) error {
+	server.lock.Lock()
+	if server.closed {
+		server.lock.Unlock()
+		listener.Close()
+		return errors.New("Server is closed")
+	}
+	server.listener = listener
+	server.startTime = time.Now()
+	server.lock.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			server.lock.Lock()
+			closed := server.closed
+			server.lock.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if !server.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go server.handle(conn)
+	}
+}
+
+// Close call stops accepting connections and closes the established ones
+func (server *Server) Close() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.closed = true
+	for conn := range server.connections {
+		conn.Close()
+	}
+	if server.listener != nil {
+		return server.listener.Close()
+	}
+	return nil
+}
+
+func (server *Server) track(conn net.Conn) bool {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.closed {
+		return false
+	}
+	server.connections[conn] = struct{}{}
+	atomic.AddUint64(&server.stats.totalConnections, 1)
+	return true
+}
+
+func (server *Server) untrack(conn net.Conn) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	delete(server.connections, conn)
+	conn.Close()
+}
+
+func (server *Server) connectedClients() int {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return len(server.connections)
+}
+
+// connection is a state of a single client
+type connection struct {
+	server *Server
+	reader *bufio.Reader
+	writer *bufio.Writer
+	quit   bool
+}
+
+// handle call serves commands of the client one by one, replies are flushed
+// once there are no more pipelined commands buffered
+func (server *Server) handle(conn net.Conn) {
+	defer server.untrack(conn)
+	c := &connection{server: server, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+
+	for !c.quit {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			if err != io.EOF && !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Println("Memcached connection failed: " + err.Error())
+			}
+			return
+		}
+		if err := c.exec(strings.Fields(line)); err != nil {
+			// the data block could not be read, so the rest of the stream could not be trusted
+			c.writer.Flush()
+			return
+		}
+		if c.reader.Buffered() == 0 || c.quit {
+			if err := c.writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package memcache
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// startTestServer call returns a connection to a new server over a fresh storage
+func startTestServer(t *testing.T) (net.Conn, *Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Can not listen: " + err.Error())
+	}
+	server := CreateServer(storage.InitSyncMapStorage(time.Minute))
+	go server.Serve(listener)
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("Can not connect: " + err.Error())
+	}
+	return conn, server
+}
+
+// readReply call reads lines until the last one of the expected reply is read
+func readReply(reader *bufio.Reader, lines int) (string, error) {
+	reply := ""
+	for i := 0; i < lines; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return reply, err
+		}
+		reply += line
+	}
+	return reply, nil
+}
+
+func TestCommands(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	expectations := []struct {
+		command string
+		reply   string
+	}{
+		{"set key 0 0 5\r\nvalue\r\n", "STORED\r\n"},
+		{"get key missing\r\n", "VALUE key 0 5\r\nvalue\r\nEND\r\n"},
+		{"add key 0 0 5\r\nother\r\n", "NOT_STORED\r\n"},
+		{"replace missing 0 0 5\r\nother\r\n", "NOT_STORED\r\n"},
+		{"add new 0 100 3\r\nnew\r\n", "STORED\r\n"},
+		{"replace new 0 0 5\r\nnewer\r\n", "STORED\r\n"},
+		{"cas missing 0 0 1 1\r\nx\r\n", "NOT_FOUND\r\n"},
+		{"cas key 0 0 1 0\r\nx\r\n", "EXISTS\r\n"},
+		{"set counter 0 0 2\r\n10\r\n", "STORED\r\n"},
+		{"incr counter 5\r\n", "15\r\n"},
+		{"decr counter 20\r\n", "0\r\n"},
+		{"incr key 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+		{"incr missing 1\r\n", "NOT_FOUND\r\n"},
+		{"touch key 100\r\n", "TOUCHED\r\n"},
+		{"touch missing 100\r\n", "NOT_FOUND\r\n"},
+		{"delete key\r\n", "DELETED\r\n"},
+		{"delete key\r\n", "NOT_FOUND\r\n"},
+		{"set expired 0 -1 1\r\nx\r\n", "STORED\r\n"},
+		{"get expired\r\n", "END\r\n"},
+		{"set quiet 0 0 1 noreply\r\nx\r\nget quiet\r\n", "VALUE quiet 0 1\r\nx\r\nEND\r\n"},
+		{"set key 0 0 5 extra\r\nvalue\r\n", "CLIENT_ERROR bad command line format\r\n"},
+		{"foo\r\n", "ERROR\r\n"},
+	}
+	for _, expectation := range expectations {
+		conn.Write([]byte(expectation.command))
+		reply, err := readReply(reader, strings.Count(expectation.reply, "\n"))
+		if err != nil || reply != expectation.reply {
+			t.Errorf("%q: expected %q, got %q", expectation.command, expectation.reply, reply)
+		}
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("set key 0 0 1\r\n1\r\ngets key\r\n"))
+	reply, _ := readReply(reader, 4)
+	fields := strings.Fields(strings.Split(reply, "\r\n")[1])
+	if len(fields) != 5 {
+		t.Fatalf("No cas unique in %q", reply)
+	}
+	unique := fields[4]
+
+	conn.Write([]byte("cas key 0 0 1 " + unique + "\r\n2\r\ncas key 0 0 1 " + unique + "\r\n3\r\nget key\r\n"))
+	expected := "STORED\r\nEXISTS\r\nVALUE key 0 1\r\n2\r\nEND\r\n"
+	if reply, err := readReply(reader, 5); err != nil || reply != expected {
+		t.Errorf("Expected %q, got %q", expected, reply)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("set key 42 0 2\r\n10\r\nincr key 1\r\ntouch key 100\r\nget key\r\n"))
+	expected := "STORED\r\n11\r\nTOUCHED\r\nVALUE key 42 2\r\n11\r\nEND\r\n"
+	if reply, err := readReply(reader, 6); err != nil || reply != expected {
+		t.Errorf("Expected %q, got %q", expected, reply)
+	}
+}
+
+func TestStatsAndQuit(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("get missing\r\nstats\r\n"))
+	readReply(reader, 1)
+	stats := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil || line == "END\r\n" {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "STAT" {
+			stats[fields[1]] = fields[2]
+		}
+	}
+	if stats["cmd_get"] != "1" || stats["get_misses"] != "1" || stats["curr_connections"] != "1" {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+
+	conn.Write([]byte("quit\r\n"))
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Error("Connection is not closed on quit")
+	}
+}
+
+func TestBadDataChunk(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("set key 0 0 1\r\ntoo long\r\n"))
+	if reply, _ := readReply(reader, 1); reply != "CLIENT_ERROR bad data chunk\r\n" {
+		t.Errorf("Malformed data block is not reported: %q", reply)
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Error("Connection is not closed after malformed data block")
+	}
+}
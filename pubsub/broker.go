@@ -0,0 +1,147 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/izhamoidsin/gedis/glob"
+)
+
+// subscriptionBuffer is the number of messages a subscriber could lag behind before
+// it is disconnected, like Redis does with slow pub/sub clients
+const subscriptionBuffer = 256
+
+// Message is a message published to a channel. Pattern is set if the message
+// is received by a pattern subscription
+type Message struct {
+	Channel string `json:"channel"`
+	Pattern string `json:"pattern,omitempty"`
+	Payload string `json:"payload"`
+}
+
+// Subscription receives messages of a channel or of channels matching a glob pattern.
+// Messages channel is closed once the subscription is closed or the subscriber is too slow
+type Subscription struct {
+	Messages <-chan Message
+
+	broker   *Broker
+	channel  string
+	pattern  string
+	messages chan Message
+	closed   bool
+}
+
+// Close call stops the subscription
+func (subscription *Subscription) Close() {
+	subscription.broker.unsubscribe(subscription)
+}
+
+// Broker delivers published messages to subscribers. Messages are not stored, so only
+// the subscribers connected at the moment of publishing receive them
+type Broker struct {
+	lock     sync.RWMutex
+	channels map[string]map[*Subscription]struct{}
+	patterns map[*Subscription]struct{}
+}
+
+// NewBroker ...
+func NewBroker() *Broker {
+	broker := new(Broker)
+	broker.channels = make(map[string]map[*Subscription]struct{})
+	broker.patterns = make(map[*Subscription]struct{})
+	return broker
+}
+
+func (broker *Broker) newSubscription(channel string, pattern string) *Subscription {
+	messages := make(chan Message, subscriptionBuffer)
+	return &Subscription{Messages: messages, broker: broker, channel: channel, pattern: pattern, messages: messages}
+}
+
+// Subscribe call subscribes to the channel
+func (broker *Broker) Subscribe(channel string) *Subscription {
+	subscription := broker.newSubscription(channel, "")
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	subscribers, exists := broker.channels[channel]
+	if !exists {
+		subscribers = make(map[*Subscription]struct{})
+		broker.channels[channel] = subscribers
+	}
+	subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+// SubscribePattern call subscribes to all the channels matching the glob pattern
+// (`*`, `?`, `[...]` and `\` escapes are supported, `*` matches `/` as well)
+func (broker *Broker) SubscribePattern(pattern string) (*Subscription, error) {
+	if err := glob.Validate(pattern); err != nil {
+		return nil, errors.New("Malformed pattern " + pattern)
+	}
+	subscription := broker.newSubscription("", pattern)
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	broker.patterns[subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Publish call delivers the message to all the subscribers and returns their number.
+// It never blocks: subscribers which could not keep up are disconnected
+func (broker *Broker) Publish(channel string, payload string) int {
+	var slow []*Subscription
+	received := 0
+
+	broker.lock.RLock()
+	for subscription := range broker.channels[channel] {
+		if subscription.deliver(Message{Channel: channel, Payload: payload}) {
+			received++
+		} else {
+			slow = append(slow, subscription)
+		}
+	}
+	for subscription := range broker.patterns {
+		if matched, _ := glob.Match(subscription.pattern, channel); !matched {
+			continue
+		}
+		if subscription.deliver(Message{Channel: channel, Pattern: subscription.pattern, Payload: payload}) {
+			received++
+		} else {
+			slow = append(slow, subscription)
+		}
+	}
+	broker.lock.RUnlock()
+
+	for _, subscription := range slow {
+		broker.unsubscribe(subscription)
+	}
+	return received
+}
+
+// deliver call is done under the read lock of the broker, so the subscription could not be closed meanwhile
+func (subscription *Subscription) deliver(message Message) bool {
+	select {
+	case subscription.messages <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+func (broker *Broker) unsubscribe(subscription *Subscription) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	if subscription.closed {
+		return
+	}
+	subscription.closed = true
+	close(subscription.messages)
+
+	if subscription.pattern != "" {
+		delete(broker.patterns, subscription)
+		return
+	}
+	subscribers := broker.channels[subscription.channel]
+	delete(subscribers, subscription)
+	if len(subscribers) == 0 {
+		delete(broker.channels, subscription.channel)
+	}
+}
@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPublishToSubscribers(t *testing.T) {
+	broker := NewBroker()
+	news := broker.Subscribe("news")
+	other := broker.Subscribe("other")
+	all, err := broker.SubscribePattern("n?ws*")
+	if err != nil {
+		t.Fatal("Can not subscribe to the pattern")
+	}
+
+	if received := broker.Publish("news", "hello"); received != 2 {
+		t.Error("Message is received by " + strconv.Itoa(received) + " subscribers instead of 2")
+	}
+	if message := <-news.Messages; message != (Message{Channel: "news", Payload: "hello"}) {
+		t.Error("Channel subscriber receives wrong message")
+	}
+	if message := <-all.Messages; message != (Message{Channel: "news", Pattern: "n?ws*", Payload: "hello"}) {
+		t.Error("Pattern subscriber receives wrong message")
+	}
+	if len(other.Messages) != 0 {
+		t.Error("Message is received by a subscriber of other channel")
+	}
+
+	news.Close()
+	news.Close()
+	if _, open := <-news.Messages; open {
+		t.Error("Messages are not closed with the subscription")
+	}
+	if received := broker.Publish("news", "bye"); received != 1 {
+		t.Error("Closed subscription still receives messages")
+	}
+	if received := broker.Publish("news/sport", "goal"); received != 1 {
+		t.Error("Pattern subscriber does not receive messages of channels with slashes")
+	}
+	if _, err := broker.SubscribePattern("[a-"); err == nil {
+		t.Error("Malformed pattern is accepted")
+	}
+}
+
+func TestSlowSubscriberIsDisconnected(t *testing.T) {
+	broker := NewBroker()
+	slow := broker.Subscribe("channel")
+	for i := 0; i < subscriptionBuffer; i++ {
+		broker.Publish("channel", strconv.Itoa(i))
+	}
+	if received := broker.Publish("channel", "overflow"); received != 0 {
+		t.Error("Message is delivered to the overflowed subscriber")
+	}
+
+	count := 0
+	for range slow.Messages {
+		count++
+	}
+	if count != subscriptionBuffer {
+		t.Error("Buffered messages are lost on disconnect")
+	}
+}
@@ -0,0 +1,106 @@
+package raft
+
+import (
+	"errors"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// Op ...
+type Op string
+
+// operations of the commands, conditional ones are meant for locks and the like
+const (
+	OpSet              Op = "set"
+	OpSetIfAbsent      Op = "setnx"
+	OpCompareAndSwap   Op = "cas"
+	OpDelete           Op = "delete"
+	OpCompareAndDelete Op = "cad"
+	// OpExpire is proposed by the leader to remove the entry expired as of the time of the command
+	OpExpire Op = "expire"
+)
+
+// Command is a write of a string value replicated through the log. Prev is the value expected
+// by compare-and-swap and compare-and-delete. Versions of the entries are not used in conditions,
+// as every node assigns its own ones.
+//
+// The leader fixes Time of the command and turns TTL into ExpireAt when the command is proposed,
+// so every node applying it gets the same outcome and the same expiration regardless of its clock
+// and of the moment it applies the command
+type Command struct {
+	Op       Op            `json:"op"`
+	Key      string        `json:"key"`
+	Value    string        `json:"value,omitempty"`
+	Prev     string        `json:"prev,omitempty"`
+	TTL      time.Duration `json:"ttl,omitempty"`
+	Time     time.Time     `json:"time"`
+	ExpireAt time.Time     `json:"expireAt,omitempty"`
+}
+
+// stamp call is done by the leader. Non-positive TTL means the default one of the storage
+func (command *Command) stamp(now time.Time, defaultTTL time.Duration) {
+	command.Time = now
+	if command.TTL > 0 {
+		command.ExpireAt = now.Add(command.TTL)
+	} else {
+		command.ExpireAt = now.Add(defaultTTL)
+	}
+}
+
+// alive call tells if the entry exists as of the time of the command. Expired entries are kept until
+// the leader removes them, so the entry could be expired, yet stored
+func (command *Command) alive(current *storage.StorableWithMeta) bool {
+	return current != nil && !current.ExpireAt().Before(command.Time)
+}
+
+// holds call evaluates the condition of the command against the entry alive as of the time of the command
+func (command *Command) holds(current *storage.StorableWithMeta) (bool, error) {
+	switch command.Op {
+	case OpSet:
+		return true, nil
+	case OpSetIfAbsent:
+		return current == nil, nil
+	case OpCompareAndSwap, OpCompareAndDelete:
+		return current != nil && current.Entity == command.Prev, nil
+	case OpDelete:
+		return current != nil, nil
+	case OpExpire:
+		return current == nil, nil
+	}
+	return false, errors.New("Unknown operation " + string(command.Op))
+}
+
+// apply call tells if the command took effect, e.g. false is returned if the condition is not met.
+// The condition does not depend on the clock of the node, so every node gets the same outcome
+func (command *Command) apply(registry storage.Storage) (bool, error) {
+	var applied bool
+	err := registry.ExecTransaction(nil, func(tx *storage.Tx) error {
+		stored := tx.GetStoredValueByKey(command.Key)
+		current := stored
+		if !command.alive(current) {
+			current = nil
+		}
+		var err error
+		if applied, err = command.holds(current); err != nil {
+			return err
+		}
+		switch {
+		case command.Op == OpDelete && stored != nil:
+			// the entry expired by the time of the command is removed as well, but it is not reported
+			tx.DeleteValueByKey(command.Key)
+		case command.Op == OpExpire:
+			applied = applied && stored != nil
+			if applied {
+				tx.DeleteValueByKey(command.Key)
+			}
+		case !applied:
+		case command.Op == OpCompareAndDelete:
+			tx.DeleteValueByKey(command.Key)
+		default:
+			_, err = tx.SetValueByKeyUntil(command.Key, command.Value, command.ExpireAt, storage.Always)
+		}
+		return err
+	})
+	return applied && err == nil, err
+}
@@ -0,0 +1,115 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// paths of RPCs served by ServeHTTP
+const (
+	requestVotePath     = "/raft/vote"
+	appendEntriesPath   = "/raft/append"
+	installSnapshotPath = "/raft/snapshot"
+)
+
+// HTTPTransport sends RPCs as JSON over HTTP. Node IDs are their addresses (`host:port`)
+type HTTPTransport struct {
+	client *http.Client
+}
+
+// NewHTTPTransport call creates a transport using the HTTP client, nil means http.DefaultClient
+func NewHTTPTransport(client *http.Client) *HTTPTransport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPTransport{client}
+}
+
+func (transport *HTTPTransport) call(ctx context.Context, target string, rpcPath string, args interface{}, reply interface{}) error {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, "http://"+target+rpcPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := transport.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return errors.New("RPC to " + target + " failed with " + strconv.Itoa(response.StatusCode) + ": " + string(message))
+	}
+	return json.NewDecoder(response.Body).Decode(reply)
+}
+
+// RequestVote ...
+func (transport *HTTPTransport) RequestVote(ctx context.Context, target string, args *RequestVoteArgs) (*RequestVoteReply, error) {
+	reply := new(RequestVoteReply)
+	return reply, transport.call(ctx, target, requestVotePath, args, reply)
+}
+
+// AppendEntries ...
+func (transport *HTTPTransport) AppendEntries(ctx context.Context, target string, args *AppendEntriesArgs) (*AppendEntriesReply, error) {
+	reply := new(AppendEntriesReply)
+	return reply, transport.call(ctx, target, appendEntriesPath, args, reply)
+}
+
+// InstallSnapshot ...
+func (transport *HTTPTransport) InstallSnapshot(ctx context.Context, target string, args *InstallSnapshotArgs) (*InstallSnapshotReply, error) {
+	reply := new(InstallSnapshotReply)
+	return reply, transport.call(ctx, target, installSnapshotPath, args, reply)
+}
+
+// ServeHTTP call handles RPCs sent by HTTPTransport, they are POSTs to `/raft/vote`, `/raft/append` and `/raft/snapshot`
+func (node *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reply interface{}
+	var err error
+	decoder := json.NewDecoder(r.Body)
+	switch path.Clean(r.URL.Path) {
+	case requestVotePath:
+		args := new(RequestVoteArgs)
+		if err = decoder.Decode(args); err == nil {
+			reply, err = node.RequestVote(args)
+		}
+	case appendEntriesPath:
+		args := new(AppendEntriesArgs)
+		if err = decoder.Decode(args); err == nil {
+			reply, err = node.AppendEntries(args)
+		}
+	case installSnapshotPath:
+		args := new(InstallSnapshotArgs)
+		if err = decoder.Decode(args); err == nil {
+			reply, err = node.InstallSnapshot(args)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case err == ErrStopped:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reply)
+	}
+}
@@ -0,0 +1,873 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+const (
+	defaultElectionTimeout   = 300 * time.Millisecond
+	defaultHeartbeatInterval = 50 * time.Millisecond
+	defaultSnapshotThreshold = 1000
+	defaultExpiryInterval    = 100 * time.Millisecond
+	// maxEntriesPerRequest limits the size of a single AppendEntries request
+	maxEntriesPerRequest = 256
+)
+
+// State ...
+type State string
+
+// roles of a node
+const (
+	Follower  State = "follower"
+	Candidate State = "candidate"
+	Leader    State = "leader"
+)
+
+// errors of proposals. ErrLeadershipLost and ErrStopped mean the outcome is unknown:
+// the entry could be committed by the next leader
+var (
+	ErrNotLeader        = errors.New("Node is not the leader")
+	ErrLeadershipLost   = errors.New("Leadership is lost before the entry is committed")
+	ErrMembershipChange = errors.New("Another membership change is in progress")
+	ErrLeaderNotReady   = errors.New("Leader has not committed an entry of its term yet")
+	ErrStopped          = errors.New("Node is stopped")
+)
+
+// Config of a node. Members are the initial members of the cluster including the node itself,
+// a node joining an existing cluster has none and waits until the leader adds it.
+// Zero durations and threshold mean the defaults
+type Config struct {
+	ID                string
+	Members           []string
+	ElectionTimeout   time.Duration
+	HeartbeatInterval time.Duration
+	// SnapshotThreshold is the number of applied entries after which the log is compacted
+	SnapshotThreshold uint64
+	// ExpiryInterval is how often the leader looks for expired entries to remove them through the log
+	ExpiryInterval time.Duration
+}
+
+// Status describes the node as it sees itself
+type Status struct {
+	ID            string   `json:"id"`
+	State         State    `json:"state"`
+	Term          uint64   `json:"term"`
+	Leader        string   `json:"leader,omitempty"`
+	Members       []string `json:"members"`
+	LastIndex     uint64   `json:"lastIndex"`
+	CommitIndex   uint64   `json:"commitIndex"`
+	LastApplied   uint64   `json:"lastApplied"`
+	SnapshotIndex uint64   `json:"snapshotIndex"`
+}
+
+type result struct {
+	applied bool
+	err     error
+}
+
+// waiter is a proposer waiting for its entry to be applied
+type waiter struct {
+	term uint64
+	done chan result
+}
+
+// Node is a member of a Raft cluster replicating commands to the storage. Committed commands are applied
+// to the storage in the log order, so the storage should not be written by anyone else. Storages which
+// expiry could be delegated keep expired entries until the leader removes them with replicated commands.
+// Nodes created by NewNode keep the term, the vote and the log in memory: a restarted node should join
+// the cluster as a new one. Nodes created by OpenNode persist them, so they could be restarted under the same ID
+type Node struct {
+	id                string
+	transport         Transport
+	storage           storage.PersistableStorage
+	electionTimeout   time.Duration
+	heartbeatInterval time.Duration
+	snapshotThreshold uint64
+	expiry            storage.DelegatedExpireStorage // nil if the storage expires entries on its own
+	expiryInterval    time.Duration
+	dir               string // the persisted state is kept in, empty if it is not persisted
+
+	lock             sync.Mutex
+	state            State
+	currentTerm      uint64
+	votedFor         string
+	votes            map[string]bool
+	leaderID         string
+	lastContact      time.Time // of the leader
+	electionDeadline time.Time
+	lastHeartbeat    time.Time
+
+	log             []Entry // log[0] is the last entry included into the snapshot
+	snapshot        []byte
+	snapshotMembers []string
+	members         []string
+	commitIndex     uint64
+	lastApplied     uint64
+
+	nextIndex  map[string]uint64
+	matchIndex map[string]uint64
+	inflight   map[string]bool
+	waiters    map[uint64]*waiter
+
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewNode ...
+func NewNode(config Config, registry storage.PersistableStorage, transport Transport) *Node {
+	node := new(Node)
+	node.id = config.ID
+	node.transport = transport
+	node.storage = registry
+	node.electionTimeout = config.ElectionTimeout
+	if node.electionTimeout <= 0 {
+		node.electionTimeout = defaultElectionTimeout
+	}
+	node.heartbeatInterval = config.HeartbeatInterval
+	if node.heartbeatInterval <= 0 {
+		node.heartbeatInterval = defaultHeartbeatInterval
+	}
+	node.snapshotThreshold = config.SnapshotThreshold
+	if node.snapshotThreshold == 0 {
+		node.snapshotThreshold = defaultSnapshotThreshold
+	}
+
+	node.expiryInterval = config.ExpiryInterval
+	if node.expiryInterval <= 0 {
+		node.expiryInterval = defaultExpiryInterval
+	}
+	// entries expired by the clocks of the nodes would be removed at different points of the log
+	if delegated, ok := registry.(storage.DelegatedExpireStorage); ok {
+		delegated.KeepExpired()
+		node.expiry = delegated
+	}
+
+	node.state = Follower
+	node.log = []Entry{{}}
+	node.snapshotMembers = append([]string(nil), config.Members...)
+	node.members = node.snapshotMembers
+	node.waiters = make(map[uint64]*waiter)
+	node.resetElectionDeadline()
+	return node
+}
+
+// ID ...
+func (node *Node) ID() string {
+	return node.id
+}
+
+// Start call starts elections and heartbeats in background
+func (node *Node) Start() {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.stop != nil || node.stopped {
+		return
+	}
+	node.stop = make(chan struct{})
+	node.done = make(chan struct{})
+	go node.run(node.stop, node.done)
+}
+
+// Stop call stops the node, it does not respond to RPCs after that
+func (node *Node) Stop() {
+	node.lock.Lock()
+	node.stopped = true
+	node.failWaiters(0, ErrStopped)
+	stop, done := node.stop, node.done
+	node.stop = nil
+	node.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (node *Node) run(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	expiryDone := make(chan struct{})
+	go node.runExpiry(stop, expiryDone)
+	defer func() { <-expiryDone }()
+	ticker := time.NewTicker(node.heartbeatInterval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			node.tick()
+		}
+	}
+}
+
+func (node *Node) tick() {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	now := time.Now()
+	switch {
+	case node.state == Leader && now.Sub(node.lastHeartbeat) >= node.heartbeatInterval:
+		node.broadcast()
+	case node.state != Leader && now.After(node.electionDeadline) && node.isMember(node.id):
+		node.startElection()
+	}
+}
+
+// runExpiry call removes expired entries on behalf of the storage while the node is the leader
+func (node *Node) runExpiry(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	if node.expiry == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticker := time.NewTicker(node.expiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			node.expireEntries(ctx)
+		}
+	}
+}
+
+// expireEntries call proposes removal of the entries expired by the clock of the leader. The removal
+// is conditional, so an entry written anew after it has been found expired is kept
+func (node *Node) expireEntries(ctx context.Context) {
+	node.lock.Lock()
+	leader := node.state == Leader
+	node.lock.Unlock()
+	if !leader {
+		return
+	}
+	for _, key := range node.expiry.ExpiredKeys(maxEntriesPerRequest) {
+		if _, err := node.Propose(ctx, Command{Op: OpExpire, Key: key}); err != nil {
+			return
+		}
+	}
+}
+
+// Status ...
+func (node *Node) Status() Status {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return Status{
+		ID:            node.id,
+		State:         node.state,
+		Term:          node.currentTerm,
+		Leader:        node.leaderID,
+		Members:       append([]string(nil), node.members...),
+		LastIndex:     node.lastIndex(),
+		CommitIndex:   node.commitIndex,
+		LastApplied:   node.lastApplied,
+		SnapshotIndex: node.snapshotIndex(),
+	}
+}
+
+// Leader call returns ID of the leader known to the node, empty if there is none
+func (node *Node) Leader() string {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.leaderID
+}
+
+// Propose call replicates the command and waits until it is applied by the leader.
+// It tells if the command took effect, e.g. false is returned if the condition of the command is not met
+func (node *Node) Propose(ctx context.Context, command Command) (bool, error) {
+	return node.propose(ctx, func() (*Entry, error) {
+		command.stamp(time.Now(), storage.DefaultTTL(node.storage))
+		return &Entry{Type: EntryCommand, Command: &command}, nil
+	})
+}
+
+// Barrier call waits until all the entries committed before the call are applied by the leader,
+// so reads of the storage made after it are linearizable
+func (node *Node) Barrier(ctx context.Context) error {
+	_, err := node.propose(ctx, func() (*Entry, error) {
+		return &Entry{Type: EntryNoop}, nil
+	})
+	return err
+}
+
+// AddMember call adds the node to the cluster. Members are changed one at a time
+func (node *Node) AddMember(ctx context.Context, id string) error {
+	return node.changeMembers(ctx, func(members []string) []string {
+		for _, member := range members {
+			if member == id {
+				return nil
+			}
+		}
+		return append(members, id)
+	})
+}
+
+// RemoveMember call removes the node from the cluster. The leader removing itself steps down
+// once the change is committed
+func (node *Node) RemoveMember(ctx context.Context, id string) error {
+	return node.changeMembers(ctx, func(members []string) []string {
+		for i, member := range members {
+			if member == id {
+				return append(members[:i], members[i+1:]...)
+			}
+		}
+		return nil
+	})
+}
+
+// changeMembers call appends a configuration entry made by change, nil means there is nothing to change.
+// The leader changes members only once an entry of its term is committed: until then a configuration
+// entry of the previous leader could still be uncommitted, and two changes at once break the overlap of majorities
+func (node *Node) changeMembers(ctx context.Context, change func(members []string) []string) error {
+	_, err := node.propose(ctx, func() (*Entry, error) {
+		if node.entry(node.commitIndex).Term != node.currentTerm {
+			return nil, ErrLeaderNotReady
+		}
+		for index := node.commitIndex + 1; index <= node.lastIndex(); index++ {
+			if node.entry(index).Type == EntryConfiguration {
+				return nil, ErrMembershipChange
+			}
+		}
+		members := change(append([]string(nil), node.members...))
+		if members == nil {
+			return nil, nil
+		}
+		return &Entry{Type: EntryConfiguration, Members: members}, nil
+	})
+	return err
+}
+
+// propose call appends the entry made by newEntry under the lock and waits until it is applied.
+// Nil entry means there is nothing to append
+func (node *Node) propose(ctx context.Context, newEntry func() (*Entry, error)) (bool, error) {
+	node.lock.Lock()
+	if node.stopped {
+		node.lock.Unlock()
+		return false, ErrStopped
+	}
+	if node.state != Leader {
+		node.lock.Unlock()
+		return false, ErrNotLeader
+	}
+	entry, err := newEntry()
+	if err != nil || entry == nil {
+		node.lock.Unlock()
+		return false, err
+	}
+	index := node.appendEntry(*entry)
+	if err := node.persistState(); err != nil {
+		// the leader could not count the entry as its own, so it gives up the leadership
+		node.becomeFollower(node.currentTerm)
+		node.lock.Unlock()
+		return false, err
+	}
+	proposer := &waiter{term: node.currentTerm, done: make(chan result, 1)}
+	node.waiters[index] = proposer
+	node.broadcast()
+	node.lock.Unlock()
+
+	select {
+	case result := <-proposer.done:
+		return result.applied, result.err
+	case <-ctx.Done():
+		node.lock.Lock()
+		delete(node.waiters, index)
+		node.lock.Unlock()
+		return false, ctx.Err()
+	}
+}
+
+// the rest of the calls should be done under the lock
+
+func (node *Node) snapshotIndex() uint64 {
+	return node.log[0].Index
+}
+
+func (node *Node) lastIndex() uint64 {
+	return node.log[len(node.log)-1].Index
+}
+
+// entry call returns the entry of the log, the index should not be less than the snapshot one
+func (node *Node) entry(index uint64) *Entry {
+	return &node.log[index-node.snapshotIndex()]
+}
+
+func (node *Node) isMember(id string) bool {
+	for _, member := range node.members {
+		if member == id {
+			return true
+		}
+	}
+	return false
+}
+
+// majority call tells if the nodes make a majority of the members
+func (node *Node) majority(nodes func(member string) bool) bool {
+	count := 0
+	for _, member := range node.members {
+		if nodes(member) {
+			count++
+		}
+	}
+	return count > len(node.members)/2
+}
+
+// membersAt call returns the configuration in effect at the index: the last configuration entry up to it
+func (node *Node) membersAt(index uint64) []string {
+	for ; index > node.snapshotIndex(); index-- {
+		if entry := node.entry(index); entry.Type == EntryConfiguration {
+			return entry.Members
+		}
+	}
+	return node.snapshotMembers
+}
+
+func (node *Node) resetElectionDeadline() {
+	node.electionDeadline = time.Now().Add(node.electionTimeout + time.Duration(rand.Int63n(int64(node.electionTimeout))))
+}
+
+func (node *Node) appendEntry(entry Entry) uint64 {
+	entry.Index = node.lastIndex() + 1
+	entry.Term = node.currentTerm
+	node.log = append(node.log, entry)
+	if entry.Type == EntryConfiguration {
+		node.members = entry.Members
+	}
+	return entry.Index
+}
+
+// failWaiters call fails the proposers of the entries starting from the index
+func (node *Node) failWaiters(from uint64, err error) {
+	for index, proposer := range node.waiters {
+		if index >= from {
+			proposer.done <- result{false, err}
+			delete(node.waiters, index)
+		}
+	}
+}
+
+func (node *Node) becomeFollower(term uint64) {
+	if term > node.currentTerm {
+		node.currentTerm = term
+		node.votedFor = ""
+		node.leaderID = ""
+		if err := node.persistState(); err != nil {
+			log.Println("Can not persist the term: " + err.Error())
+		}
+	}
+	if node.state == Leader {
+		node.failWaiters(0, ErrLeadershipLost)
+		node.leaderID = ""
+	}
+	node.state = Follower
+	node.resetElectionDeadline()
+}
+
+func (node *Node) startElection() {
+	node.state = Candidate
+	node.currentTerm++
+	node.votedFor = node.id
+	node.leaderID = ""
+	node.votes = map[string]bool{node.id: true}
+	node.resetElectionDeadline()
+	if err := node.persistState(); err != nil {
+		log.Println("Can not persist the vote: " + err.Error())
+		return
+	}
+	if node.majority(func(member string) bool { return node.votes[member] }) {
+		node.becomeLeader()
+		return
+	}
+
+	args := &RequestVoteArgs{
+		Term:         node.currentTerm,
+		CandidateID:  node.id,
+		LastLogIndex: node.lastIndex(),
+		LastLogTerm:  node.entry(node.lastIndex()).Term,
+	}
+	for _, member := range node.members {
+		if member != node.id {
+			go node.requestVote(member, args)
+		}
+	}
+}
+
+func (node *Node) requestVote(peer string, args *RequestVoteArgs) {
+	ctx, cancel := context.WithTimeout(context.Background(), node.electionTimeout)
+	defer cancel()
+	reply, err := node.transport.RequestVote(ctx, peer, args)
+	if err != nil {
+		return
+	}
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if reply.Term > node.currentTerm {
+		node.becomeFollower(reply.Term)
+		return
+	}
+	if node.state != Candidate || node.currentTerm != args.Term || !reply.VoteGranted {
+		return
+	}
+	node.votes[peer] = true
+	if node.majority(func(member string) bool { return node.votes[member] }) {
+		node.becomeLeader()
+	}
+}
+
+// becomeLeader call appends a noop entry, so entries of the previous terms get committed
+func (node *Node) becomeLeader() {
+	node.state = Leader
+	node.leaderID = node.id
+	node.nextIndex = make(map[string]uint64)
+	node.matchIndex = make(map[string]uint64)
+	node.inflight = make(map[string]bool)
+	node.appendEntry(Entry{Type: EntryNoop})
+	if err := node.persistState(); err != nil {
+		log.Println("Can not persist the log: " + err.Error())
+		node.becomeFollower(node.currentTerm)
+		return
+	}
+	node.broadcast()
+}
+
+// broadcast call sends the new entries or heartbeats to the members which are not being sent to already
+func (node *Node) broadcast() {
+	node.lastHeartbeat = time.Now()
+	for _, member := range node.members {
+		if member != node.id && !node.inflight[member] {
+			node.inflight[member] = true
+			go node.replicateTo(member, node.currentTerm)
+		}
+	}
+	node.advanceCommit()
+}
+
+// replicateTo call sends entries to the peer until it catches up with the log or the term is over
+func (node *Node) replicateTo(peer string, term uint64) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	defer func() {
+		if node.currentTerm == term {
+			node.inflight[peer] = false
+		}
+	}()
+
+	for node.state == Leader && node.currentTerm == term && !node.stopped && node.isMember(peer) {
+		next := node.nextIndex[peer]
+		if next == 0 {
+			next = node.lastIndex() + 1
+		}
+
+		if next <= node.snapshotIndex() {
+			args := &InstallSnapshotArgs{
+				Term:              term,
+				LeaderID:          node.id,
+				LastIncludedIndex: node.snapshotIndex(),
+				LastIncludedTerm:  node.log[0].Term,
+				Members:           node.snapshotMembers,
+				Data:              node.snapshot,
+			}
+			node.lock.Unlock()
+			reply, err := node.installSnapshot(peer, args)
+			node.lock.Lock()
+			if err != nil || !node.handleReplyTerm(reply.Term, term) {
+				return
+			}
+			node.acknowledge(peer, args.LastIncludedIndex)
+			continue
+		}
+
+		last := node.lastIndex()
+		if last >= next+maxEntriesPerRequest {
+			last = next + maxEntriesPerRequest - 1
+		}
+		args := &AppendEntriesArgs{
+			Term:         term,
+			LeaderID:     node.id,
+			PrevLogIndex: next - 1,
+			PrevLogTerm:  node.entry(next - 1).Term,
+			Entries:      append([]Entry(nil), node.log[next-node.snapshotIndex():last-node.snapshotIndex()+1]...),
+			LeaderCommit: node.commitIndex,
+		}
+		node.lock.Unlock()
+		reply, err := node.appendEntries(peer, args)
+		node.lock.Lock()
+		if err != nil || !node.handleReplyTerm(reply.Term, term) {
+			return
+		}
+		if !reply.Success {
+			if reply.ConflictIndex > 0 && reply.ConflictIndex < next {
+				node.nextIndex[peer] = reply.ConflictIndex
+			} else if next > 1 {
+				node.nextIndex[peer] = next - 1
+			}
+			continue
+		}
+		node.acknowledge(peer, last)
+		if last == node.lastIndex() {
+			return
+		}
+	}
+}
+
+func (node *Node) appendEntries(peer string, args *AppendEntriesArgs) (*AppendEntriesReply, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), node.electionTimeout)
+	defer cancel()
+	return node.transport.AppendEntries(ctx, peer, args)
+}
+
+func (node *Node) installSnapshot(peer string, args *InstallSnapshotArgs) (*InstallSnapshotReply, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), node.electionTimeout)
+	defer cancel()
+	return node.transport.InstallSnapshot(ctx, peer, args)
+}
+
+// handleReplyTerm call steps down if the peer knows a newer term and tells if the leader could go on
+func (node *Node) handleReplyTerm(replyTerm uint64, term uint64) bool {
+	if replyTerm > node.currentTerm {
+		node.becomeFollower(replyTerm)
+		return false
+	}
+	return node.state == Leader && node.currentTerm == term
+}
+
+// acknowledge call records the peer has the log up to the index
+func (node *Node) acknowledge(peer string, index uint64) {
+	if index > node.matchIndex[peer] {
+		node.matchIndex[peer] = index
+	}
+	node.nextIndex[peer] = node.matchIndex[peer] + 1
+	node.advanceCommit()
+}
+
+// advanceCommit call commits the last entry of the current term replicated to the majority
+func (node *Node) advanceCommit() {
+	for index := node.lastIndex(); index > node.commitIndex && index > node.snapshotIndex(); index-- {
+		if node.entry(index).Term != node.currentTerm {
+			return
+		}
+		replicated := node.majority(func(member string) bool {
+			return member == node.id || node.matchIndex[member] >= index
+		})
+		if replicated {
+			node.commitIndex = index
+			node.applyCommitted()
+			return
+		}
+	}
+}
+
+// applyCommitted call applies the committed entries to the storage and notifies their proposers
+func (node *Node) applyCommitted() {
+	for node.lastApplied < node.commitIndex {
+		node.lastApplied++
+		entry := node.entry(node.lastApplied)
+		var outcome result
+		switch entry.Type {
+		case EntryCommand:
+			outcome.applied, outcome.err = entry.Command.apply(node.storage)
+		case EntryNoop, EntryConfiguration:
+			outcome.applied = true
+		}
+		if proposer, exists := node.waiters[entry.Index]; exists {
+			delete(node.waiters, entry.Index)
+			if proposer.term != entry.Term {
+				outcome = result{false, ErrLeadershipLost}
+			}
+			proposer.done <- outcome
+		}
+		if entry.Type == EntryConfiguration && node.state == Leader && !node.isMember(node.id) {
+			node.becomeFollower(node.currentTerm)
+		}
+	}
+	node.compact()
+}
+
+// compact call replaces the applied part of the log with a snapshot of the storage once it is long enough
+func (node *Node) compact() {
+	if node.lastApplied-node.snapshotIndex() < node.snapshotThreshold {
+		return
+	}
+	var data bytes.Buffer
+	if err := storage.WriteSnapshot(node.storage, &data); err != nil {
+		log.Println("Can not take snapshot: " + err.Error())
+		return
+	}
+	last := *node.entry(node.lastApplied)
+	snapshot := persistentSnapshot{last.Index, last.Term, node.membersAt(last.Index), data.Bytes()}
+	if err := node.persistSnapshot(snapshot); err != nil {
+		log.Println("Can not persist snapshot: " + err.Error())
+		return
+	}
+	node.snapshotMembers = snapshot.Members
+	node.log = append([]Entry{{Index: last.Index, Term: last.Term}}, node.log[last.Index-node.snapshotIndex()+1:]...)
+	node.snapshot = snapshot.Data
+	if err := node.persistState(); err != nil {
+		log.Println("Can not persist the log: " + err.Error())
+	}
+}
+
+// RequestVote call handles the RPC of a candidate
+func (node *Node) RequestVote(args *RequestVoteArgs) (*RequestVoteReply, error) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.stopped {
+		return nil, ErrStopped
+	}
+	reply := &RequestVoteReply{Term: node.currentTerm}
+	if args.Term < node.currentTerm {
+		return reply, nil
+	}
+	// nodes which hear from the leader ignore candidates, so removed members could not disrupt the cluster
+	if node.state == Leader || (node.leaderID != "" && time.Since(node.lastContact) < node.electionTimeout) {
+		return reply, nil
+	}
+	if args.Term > node.currentTerm {
+		node.becomeFollower(args.Term)
+		reply.Term = node.currentTerm
+	}
+
+	lastTerm := node.entry(node.lastIndex()).Term
+	upToDate := args.LastLogTerm > lastTerm || (args.LastLogTerm == lastTerm && args.LastLogIndex >= node.lastIndex())
+	if (node.votedFor == "" || node.votedFor == args.CandidateID) && upToDate {
+		node.votedFor = args.CandidateID
+		node.resetElectionDeadline()
+		if err := node.persistState(); err != nil {
+			return nil, err
+		}
+		reply.VoteGranted = true
+	}
+	return reply, nil
+}
+
+// followLeader call is done by a node receiving a valid RPC of the leader
+func (node *Node) followLeader(term uint64, leaderID string) {
+	if term > node.currentTerm || node.state != Follower {
+		node.becomeFollower(term)
+	}
+	node.leaderID = leaderID
+	node.lastContact = time.Now()
+	node.resetElectionDeadline()
+}
+
+// AppendEntries call handles the RPC of the leader
+func (node *Node) AppendEntries(args *AppendEntriesArgs) (*AppendEntriesReply, error) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.stopped {
+		return nil, ErrStopped
+	}
+	reply := &AppendEntriesReply{Term: node.currentTerm}
+	if args.Term < node.currentTerm {
+		return reply, nil
+	}
+	node.followLeader(args.Term, args.LeaderID)
+	reply.Term = node.currentTerm
+
+	prev, entries := args.PrevLogIndex, args.Entries
+	if prev < node.snapshotIndex() {
+		// entries up to the snapshot are committed and compacted already
+		skip := node.snapshotIndex() - prev
+		if uint64(len(entries)) < skip {
+			reply.Success = true
+			return reply, nil
+		}
+		prev, entries = node.snapshotIndex(), entries[skip:]
+	} else if prev > node.lastIndex() {
+		reply.ConflictIndex = node.lastIndex() + 1
+		return reply, nil
+	} else if term := node.entry(prev).Term; term != args.PrevLogTerm {
+		// the whole conflicting term is skipped at once
+		index := prev
+		for index > node.snapshotIndex()+1 && node.entry(index-1).Term == term {
+			index--
+		}
+		reply.ConflictIndex = index
+		return reply, nil
+	}
+
+	for i, entry := range entries {
+		if entry.Index <= node.lastIndex() {
+			if node.entry(entry.Index).Term == entry.Term {
+				continue
+			}
+			node.log = node.log[:entry.Index-node.snapshotIndex()]
+			node.failWaiters(entry.Index, ErrLeadershipLost)
+		}
+		node.log = append(node.log, entries[i:]...)
+		// the leader counts the entries as replicated once they are acknowledged
+		if err := node.persistState(); err != nil {
+			return nil, err
+		}
+		break
+	}
+	node.members = node.membersAt(node.lastIndex())
+
+	if lastNew := prev + uint64(len(entries)); args.LeaderCommit > node.commitIndex {
+		node.commitIndex = args.LeaderCommit
+		if lastNew < node.commitIndex {
+			node.commitIndex = lastNew
+		}
+		node.applyCommitted()
+	}
+	reply.Success = true
+	return reply, nil
+}
+
+// InstallSnapshot call handles the RPC of the leader replacing the content of the storage with the snapshot
+func (node *Node) InstallSnapshot(args *InstallSnapshotArgs) (*InstallSnapshotReply, error) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.stopped {
+		return nil, ErrStopped
+	}
+	reply := &InstallSnapshotReply{Term: node.currentTerm}
+	if args.Term < node.currentTerm {
+		return reply, nil
+	}
+	node.followLeader(args.Term, args.LeaderID)
+	reply.Term = node.currentTerm
+	if args.LastIncludedIndex <= node.lastApplied {
+		return reply, nil
+	}
+
+	if err := node.persistSnapshot(persistentSnapshot{args.LastIncludedIndex, args.LastIncludedTerm, args.Members, args.Data}); err != nil {
+		return nil, err
+	}
+	snapshotEntry := Entry{Index: args.LastIncludedIndex, Term: args.LastIncludedTerm}
+	if args.LastIncludedIndex <= node.lastIndex() && node.entry(args.LastIncludedIndex).Term == args.LastIncludedTerm {
+		// the log following the snapshot is kept
+		node.log = append([]Entry{snapshotEntry}, node.log[args.LastIncludedIndex-node.snapshotIndex()+1:]...)
+	} else {
+		node.log = []Entry{snapshotEntry}
+		node.failWaiters(0, ErrLeadershipLost)
+	}
+	node.snapshot = args.Data
+	node.snapshotMembers = args.Members
+	node.members = node.membersAt(node.lastIndex())
+	if err := node.persistState(); err != nil {
+		log.Println("Can not persist the log: " + err.Error())
+	}
+
+	for _, key := range node.storage.GetAllKeys() {
+		node.storage.DeleteValueByKey(key)
+	}
+	if err := storage.ReadSnapshot(node.storage, bytes.NewReader(args.Data)); err != nil {
+		log.Println("Can not restore snapshot: " + err.Error())
+	}
+	node.lastApplied = args.LastIncludedIndex
+	if node.commitIndex < node.lastApplied {
+		node.commitIndex = node.lastApplied
+	}
+	return reply, nil
+}
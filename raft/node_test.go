@@ -0,0 +1,452 @@
+package raft
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+type testCluster struct {
+	network  *InMemoryNetwork
+	nodes    map[string]*Node
+	storages map[string]*storage.SyncMapStorage
+}
+
+func testConfig(id string, members []string) Config {
+	return Config{ID: id, Members: members, ElectionTimeout: 50 * time.Millisecond, HeartbeatInterval: 10 * time.Millisecond, SnapshotThreshold: 20, ExpiryInterval: 10 * time.Millisecond}
+}
+
+// startTestCluster call starts a cluster of n nodes connected by in-memory network
+func startTestCluster(n int) *testCluster {
+	cluster := &testCluster{NewInMemoryNetwork(), make(map[string]*Node), make(map[string]*storage.SyncMapStorage)}
+	var members []string
+	for i := 1; i <= n; i++ {
+		members = append(members, "node"+strconv.Itoa(i))
+	}
+	for _, id := range members {
+		cluster.start(testConfig(id, members))
+	}
+	return cluster
+}
+
+func (cluster *testCluster) start(config Config) *Node {
+	registry := storage.InitSyncMapStorage(time.Minute)
+	node := NewNode(config, registry, cluster.network.Transport(config.ID))
+	cluster.network.Register(node)
+	cluster.nodes[config.ID] = node
+	cluster.storages[config.ID] = registry
+	node.Start()
+	return node
+}
+
+func (cluster *testCluster) stop() {
+	for _, node := range cluster.nodes {
+		node.Stop()
+	}
+}
+
+// eventually call waits up to 5 seconds for the condition
+func eventually(condition func() bool) bool {
+	for i := 0; i < 500; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// leader call waits for a single leader among the connected nodes
+func (cluster *testCluster) leader(t *testing.T, except ...string) *Node {
+	var leader *Node
+	found := eventually(func() bool {
+		leader = nil
+		for id, node := range cluster.nodes {
+			excluded := false
+			for _, other := range except {
+				excluded = excluded || other == id
+			}
+			if excluded || node.Status().State != Leader {
+				continue
+			}
+			if leader != nil {
+				return false
+			}
+			leader = node
+		}
+		return leader != nil
+	})
+	if !found {
+		t.Fatal("Leader is not elected")
+	}
+	return leader
+}
+
+// replicated call waits until all the nodes (except the passed ones) have the value of the key
+func (cluster *testCluster) replicated(key string, value string, except ...string) bool {
+	return eventually(func() bool {
+		for id, registry := range cluster.storages {
+			excluded := false
+			for _, other := range except {
+				excluded = excluded || other == id
+			}
+			if val, exists := registry.GetValueByKey(key); !excluded && (!exists || val.Entity != value) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func propose(node *Node, command Command) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return node.Propose(ctx, command)
+}
+
+func TestReplication(t *testing.T) {
+	cluster := startTestCluster(3)
+	defer cluster.stop()
+	leader := cluster.leader(t)
+
+	if applied, err := propose(leader, Command{Op: OpSet, Key: "key", Value: "value"}); err != nil || !applied {
+		t.Fatal("Command is not applied")
+	}
+	if !cluster.replicated("key", "value") {
+		t.Error("Command is not replicated to all the nodes")
+	}
+	expireAt, _ := cluster.storages[leader.ID()].GetValueByKey("key")
+	for id, registry := range cluster.storages {
+		if val, _ := registry.GetValueByKey("key"); !val.ExpireAt().Equal(expireAt.ExpireAt()) {
+			t.Error("Expiration of the entry differs on " + id)
+		}
+	}
+	for id, node := range cluster.nodes {
+		if node != leader {
+			if _, err := propose(node, Command{Op: OpSet, Key: "key", Value: "other"}); err != ErrNotLeader {
+				t.Error("Follower accepts commands")
+			}
+			if node.Leader() != leader.ID() {
+				t.Error("Follower " + id + " does not know the leader")
+			}
+		}
+	}
+	if err := leader.Barrier(context.Background()); err != nil {
+		t.Error("Barrier failed. " + err.Error())
+	}
+}
+
+func TestConditionalCommands(t *testing.T) {
+	cluster := startTestCluster(3)
+	defer cluster.stop()
+	leader := cluster.leader(t)
+
+	expectations := []struct {
+		command Command
+		applied bool
+	}{
+		{Command{Op: OpSetIfAbsent, Key: "lock", Value: "owner1", TTL: time.Hour}, true},
+		{Command{Op: OpSetIfAbsent, Key: "lock", Value: "owner2"}, false},
+		{Command{Op: OpCompareAndSwap, Key: "lock", Value: "owner2", Prev: "owner2"}, false},
+		{Command{Op: OpCompareAndSwap, Key: "lock", Value: "owner2", Prev: "owner1"}, true},
+		{Command{Op: OpCompareAndDelete, Key: "lock", Prev: "owner1"}, false},
+		{Command{Op: OpCompareAndDelete, Key: "lock", Prev: "owner2"}, true},
+		{Command{Op: OpDelete, Key: "lock"}, false},
+	}
+	for _, expectation := range expectations {
+		if applied, err := propose(leader, expectation.command); err != nil || applied != expectation.applied {
+			t.Errorf("%+v: expected %v, got %v (%v)", expectation.command, expectation.applied, applied, err)
+		}
+	}
+	if _, err := propose(leader, Command{Op: "unknown", Key: "lock"}); err == nil {
+		t.Error("Unknown operation is applied")
+	}
+}
+
+func TestConditionsAsOfCommandTime(t *testing.T) {
+	registry := storage.InitSyncMapStorage(time.Minute)
+	now := time.Now()
+	// the node applies the command a minute after it is proposed, the entry is expired by the clock of the node
+	registry.SetValueByKeyUntil("lock", "owner1", now.Add(-time.Second), storage.Always)
+	command := Command{Op: OpCompareAndSwap, Key: "lock", Value: "owner2", Prev: "owner1", TTL: time.Hour}
+	command.stamp(now.Add(-time.Minute), time.Minute)
+	if applied, err := command.apply(registry); err != nil || !applied {
+		t.Error("Entry alive at the time of the command is considered expired")
+	}
+	if val, exists := registry.GetValueByKey("lock"); !exists || val.Entity != "owner2" || !val.ExpireAt().Equal(command.ExpireAt) {
+		t.Error("Entry does not expire an hour after the time of the command")
+	}
+
+	// and the other way around: the entry is alive by the clock of the node, but expired at the time of the command
+	command = Command{Op: OpSetIfAbsent, Key: "lock", Value: "owner3"}
+	command.stamp(now.Add(2*time.Hour), time.Hour)
+	if applied, err := command.apply(registry); err != nil || !applied {
+		t.Error("Entry expired at the time of the command is considered alive")
+	}
+	command = Command{Op: OpDelete, Key: "lock"}
+	command.stamp(now.Add(4*time.Hour), time.Hour)
+	if applied, err := command.apply(registry); err != nil || applied {
+		t.Error("Removal of the entry expired at the time of the command is reported")
+	}
+	if _, exists := registry.GetValueByKey("lock"); exists {
+		t.Error("Entry is not removed")
+	}
+}
+
+func TestExpiryThroughLog(t *testing.T) {
+	cluster := startTestCluster(3)
+	defer cluster.stop()
+	leader := cluster.leader(t)
+
+	if applied, err := propose(leader, Command{Op: OpSet, Key: "lease", Value: "owner", TTL: 30 * time.Millisecond}); err != nil || !applied {
+		t.Fatal("Command is not applied")
+	}
+	if !cluster.replicated("lease", "owner") {
+		t.Fatal("Command is not replicated")
+	}
+	if !eventually(func() bool {
+		for _, registry := range cluster.storages {
+			if registry.MemoryStats().Entries != 0 {
+				return false
+			}
+		}
+		return true
+	}) {
+		t.Error("Expired entry is not removed by the nodes")
+	}
+	for id, registry := range cluster.storages {
+		if stats := registry.VacuumStats(); stats.LazyExpiredKeys != 0 || stats.ExpiredKeys != 0 {
+			t.Errorf("%s removes expired entry on its own: %+v", id, stats)
+		}
+	}
+
+	// the entry written anew after it has been found expired is kept
+	propose(leader, Command{Op: OpSet, Key: "lease", Value: "owner", TTL: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	propose(leader, Command{Op: OpSet, Key: "lease", Value: "new owner", TTL: time.Hour})
+	if applied, err := propose(leader, Command{Op: OpExpire, Key: "lease"}); err != nil || applied {
+		t.Error("Entry alive at the time of the command is expired")
+	}
+	if !cluster.replicated("lease", "new owner") {
+		t.Error("Entry alive at the time of the command is removed")
+	}
+}
+
+func TestLeaderFailover(t *testing.T) {
+	cluster := startTestCluster(3)
+	defer cluster.stop()
+	oldLeader := cluster.leader(t)
+	propose(oldLeader, Command{Op: OpSet, Key: "key", Value: "before"})
+
+	cluster.network.Disconnect(oldLeader.ID())
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := oldLeader.Propose(ctx, Command{Op: OpSet, Key: "key", Value: "lost"}); err == nil {
+		t.Error("Isolated leader commits commands")
+	}
+
+	newLeader := cluster.leader(t, oldLeader.ID())
+	if applied, err := propose(newLeader, Command{Op: OpSet, Key: "key", Value: "after"}); err != nil || !applied {
+		t.Fatal("New leader does not apply commands")
+	}
+
+	cluster.network.Connect(oldLeader.ID())
+	if !cluster.replicated("key", "after") {
+		t.Error("Old leader does not catch up with the new one")
+	}
+	if !eventually(func() bool { return oldLeader.Status().State == Follower }) {
+		t.Error("Old leader does not step down")
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	cluster := startTestCluster(3)
+	defer cluster.stop()
+	leader := cluster.leader(t)
+	var lagging string
+	for id := range cluster.nodes {
+		if id != leader.ID() {
+			lagging = id
+			break
+		}
+	}
+
+	cluster.network.Disconnect(lagging)
+	propose(leader, Command{Op: OpSet, Key: "stale", Value: "removed later"})
+	for i := 0; i < 50; i++ {
+		if _, err := propose(leader, Command{Op: OpSet, Key: "key" + strconv.Itoa(i), Value: strconv.Itoa(i)}); err != nil {
+			t.Fatal("Command is not applied. " + err.Error())
+		}
+	}
+	propose(leader, Command{Op: OpDelete, Key: "stale"})
+	if leader.Status().SnapshotIndex == 0 {
+		t.Fatal("Log is not compacted")
+	}
+
+	cluster.network.Connect(lagging)
+	if !cluster.replicated("key49", "49") || !cluster.replicated("key0", "0") {
+		t.Error("Lagging node is not restored from the snapshot")
+	}
+	// the delete could follow the snapshot, so the lagging node applies it a bit later
+	if !eventually(func() bool {
+		_, exists := cluster.storages[lagging].GetValueByKey("stale")
+		return !exists
+	}) {
+		t.Error("Entry removed on the leader is kept")
+	}
+	var status Status
+	if !eventually(func() bool {
+		status = cluster.nodes[lagging].Status()
+		return status.SnapshotIndex != 0 && status.LastApplied == leader.Status().LastApplied
+	}) {
+		t.Errorf("Unexpected status of the lagging node: %+v", status)
+	}
+}
+
+func TestMembershipChanges(t *testing.T) {
+	cluster := startTestCluster(3)
+	defer cluster.stop()
+	leader := cluster.leader(t)
+	propose(leader, Command{Op: OpSet, Key: "key", Value: "value"})
+
+	// a new node has no members until the leader adds it
+	cluster.start(testConfig("node4", nil))
+	if err := leader.AddMember(context.Background(), "node4"); err != nil {
+		t.Fatal("Can not add member. " + err.Error())
+	}
+	if !cluster.replicated("key", "value") {
+		t.Error("New member does not catch up")
+	}
+	if members := cluster.nodes["node4"].Status().Members; len(members) != 4 {
+		t.Errorf("New member does not know the cluster: %v", members)
+	}
+
+	if err := leader.RemoveMember(context.Background(), leader.ID()); err != nil {
+		t.Fatal("Can not remove the leader. " + err.Error())
+	}
+	removed := leader.ID()
+	leader.Stop()
+	newLeader := cluster.leader(t, removed)
+	if members := newLeader.Status().Members; len(members) != 3 {
+		t.Errorf("Removed member is still in the cluster: %v", members)
+	}
+	if applied, err := propose(newLeader, Command{Op: OpSet, Key: "key", Value: "after removal"}); err != nil || !applied {
+		t.Error("Cluster does not work after the leader is removed")
+	}
+	if !cluster.replicated("key", "after removal", removed) {
+		t.Error("Command is not replicated to the remaining members")
+	}
+}
+
+func TestMembershipChangeWaitsForCommitOfTerm(t *testing.T) {
+	network := NewInMemoryNetwork()
+	node := NewNode(testConfig("node1", []string{"node1", "node2", "node3"}), storage.InitSyncMapStorage(time.Minute), network.Transport("node1"))
+	network.Register(node)
+	defer node.Stop()
+
+	// the other members are unreachable, so the noop entry of the new leader is never committed
+	node.lock.Lock()
+	node.currentTerm++
+	node.becomeLeader()
+	node.lock.Unlock()
+	if err := node.AddMember(context.Background(), "node4"); err != ErrLeaderNotReady {
+		t.Errorf("Membership is changed before an entry of the term is committed: %v", err)
+	}
+}
+
+func TestRestartUnderSameID(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	cluster := &testCluster{NewInMemoryNetwork(), make(map[string]*Node), make(map[string]*storage.SyncMapStorage)}
+	defer cluster.stop()
+	members := []string{"node1", "node2", "node3"}
+	open := func(id string) *Node {
+		registry := storage.InitSyncMapStorage(time.Minute)
+		node, err := OpenNode(testConfig(id, members), filepath.Join(dir, id), registry, cluster.network.Transport(id))
+		if err != nil {
+			t.Fatal("Can not open node. " + err.Error())
+		}
+		cluster.network.Register(node)
+		cluster.nodes[id] = node
+		cluster.storages[id] = registry
+		return node
+	}
+	for _, id := range members {
+		open(id).Start()
+	}
+
+	leader := cluster.leader(t)
+	// more entries than the snapshot threshold, so the restarted node has both the snapshot and the log
+	for i := 0; i < 30; i++ {
+		propose(leader, Command{Op: OpSet, Key: "key" + strconv.Itoa(i), Value: "value"})
+	}
+	var restarted string
+	for _, id := range members {
+		if id != leader.ID() {
+			restarted = id
+		}
+	}
+	if !cluster.replicated("key29", "value") {
+		t.Fatal("Commands are not replicated")
+	}
+	cluster.nodes[restarted].Stop()
+	before := cluster.nodes[restarted].Status()
+	votedFor := cluster.nodes[restarted].votedFor
+
+	node := open(restarted)
+	if after := node.Status(); after.Term != before.Term || after.LastIndex != before.LastIndex || after.SnapshotIndex != before.SnapshotIndex || len(after.Members) != 3 {
+		t.Errorf("State of the node is not restored: %+v instead of %+v", after, before)
+	}
+	if node.votedFor != votedFor {
+		t.Error("Vote of the node is not restored")
+	}
+	if _, exists := cluster.storages[restarted].GetValueByKey("key0"); !exists {
+		t.Error("Storage is not restored from the snapshot")
+	}
+	node.Start()
+	propose(leader, Command{Op: OpSet, Key: "key", Value: "after restart"})
+	if !cluster.replicated("key", "after restart") || !cluster.replicated("key29", "value") {
+		t.Error("Restarted node does not catch up")
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+	var listeners []net.Listener
+	var members []string
+	for i := 0; i < 3; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("Can not listen: " + err.Error())
+		}
+		defer listener.Close()
+		listeners = append(listeners, listener)
+		members = append(members, listener.Addr().String())
+	}
+
+	cluster := &testCluster{nil, make(map[string]*Node), make(map[string]*storage.SyncMapStorage)}
+	defer cluster.stop()
+	for i, id := range members {
+		registry := storage.InitSyncMapStorage(time.Minute)
+		node := NewNode(testConfig(id, members), registry, NewHTTPTransport(nil))
+		cluster.nodes[id] = node
+		cluster.storages[id] = registry
+		go http.Serve(listeners[i], node)
+		node.Start()
+	}
+
+	leader := cluster.leader(t)
+	if applied, err := propose(leader, Command{Op: OpSet, Key: "key", Value: "value"}); err != nil || !applied {
+		t.Fatal("Command is not applied")
+	}
+	if !cluster.replicated("key", "value") {
+		t.Error("Command is not replicated over HTTP")
+	}
+}
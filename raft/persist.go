@@ -0,0 +1,146 @@
+package raft
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// names of the files in the directory of the node
+const (
+	stateFileName    = "raft.state"
+	snapshotFileName = "raft.snapshot"
+)
+
+// persistentState is what the node should not forget across restarts: it votes once per term
+// and entries it has acknowledged could be committed already. The log starts with the snapshot entry
+type persistentState struct {
+	Term     uint64  `json:"term"`
+	VotedFor string  `json:"votedFor,omitempty"`
+	Log      []Entry `json:"log"`
+}
+
+// persistentSnapshot is kept in a separate file, so it is not rewritten along with every change of the log
+type persistentSnapshot struct {
+	Index   uint64   `json:"index"`
+	Term    uint64   `json:"term"`
+	Members []string `json:"members"`
+	Data    []byte   `json:"data"`
+}
+
+// OpenNode call creates a node keeping its term, vote, log and snapshot in the directory, so it could be
+// restarted under the same ID. The persisted state (if any) is restored, the storage is expected to be empty
+func OpenNode(config Config, dir string, registry storage.PersistableStorage, transport Transport) (*Node, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	node := NewNode(config, registry, transport)
+	node.dir = dir
+
+	var snapshot persistentSnapshot
+	if exists, err := readPersisted(filepath.Join(dir, snapshotFileName), &snapshot); err != nil {
+		return nil, err
+	} else if exists {
+		if err := storage.ReadSnapshot(registry, bytes.NewReader(snapshot.Data)); err != nil {
+			return nil, errors.New("Can not restore snapshot: " + err.Error())
+		}
+		node.log = []Entry{{Index: snapshot.Index, Term: snapshot.Term}}
+		node.snapshot = snapshot.Data
+		node.snapshotMembers = snapshot.Members
+		node.lastApplied = snapshot.Index
+		node.commitIndex = snapshot.Index
+	}
+
+	var state persistentState
+	if exists, err := readPersisted(filepath.Join(dir, stateFileName), &state); err != nil {
+		return nil, err
+	} else if exists {
+		node.currentTerm = state.Term
+		node.votedFor = state.VotedFor
+		// the snapshot is saved before the log is compacted, so the log could start before it
+		following := false
+		for _, entry := range state.Log {
+			if following && entry.Index == node.lastIndex()+1 {
+				node.log = append(node.log, entry)
+			} else if entry.Index == node.snapshotIndex() {
+				following = entry.Term == node.log[0].Term
+			}
+		}
+	}
+	node.members = node.membersAt(node.lastIndex())
+	return node, nil
+}
+
+func readPersisted(path string, v interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, errors.New("Corrupted " + path + ": " + err.Error())
+	}
+	return true, nil
+}
+
+// writePersisted call replaces the file at once: the content is written to a temporary file,
+// which is synced and renamed afterwards
+func writePersisted(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// the rename is durable only once the directory is synced
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// the rest of the calls should be done under the lock
+
+// persistState call saves the term, the vote and the log. It is done before the node replies
+// or acts on them, nodes created by NewNode do not persist anything
+func (node *Node) persistState() error {
+	if node.dir == "" {
+		return nil
+	}
+	return writePersisted(filepath.Join(node.dir, stateFileName), persistentState{node.currentTerm, node.votedFor, node.log})
+}
+
+// persistSnapshot call is done before the snapshot replaces the beginning of the log in memory,
+// so the persisted log never starts after the persisted snapshot
+func (node *Node) persistSnapshot(snapshot persistentSnapshot) error {
+	if node.dir == "" {
+		return nil
+	}
+	return writePersisted(filepath.Join(node.dir, snapshotFileName), snapshot)
+}
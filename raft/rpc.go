@@ -0,0 +1,79 @@
+package raft
+
+import "context"
+
+// EntryType ...
+type EntryType string
+
+// kinds of log entries. Noop entry is appended by every new leader, so entries of the previous
+// terms get committed, and by Barrier calls
+const (
+	EntryCommand       EntryType = "command"
+	EntryConfiguration EntryType = "configuration"
+	EntryNoop          EntryType = "noop"
+)
+
+// Entry is a single record of the replicated log. Configuration entries carry the whole
+// new set of members which takes effect as soon as the entry is appended
+type Entry struct {
+	Index   uint64    `json:"index"`
+	Term    uint64    `json:"term"`
+	Type    EntryType `json:"type"`
+	Command *Command  `json:"command,omitempty"`
+	Members []string  `json:"members,omitempty"`
+}
+
+// RequestVoteArgs ...
+type RequestVoteArgs struct {
+	Term         uint64 `json:"term"`
+	CandidateID  string `json:"candidateId"`
+	LastLogIndex uint64 `json:"lastLogIndex"`
+	LastLogTerm  uint64 `json:"lastLogTerm"`
+}
+
+// RequestVoteReply ...
+type RequestVoteReply struct {
+	Term        uint64 `json:"term"`
+	VoteGranted bool   `json:"voteGranted"`
+}
+
+// AppendEntriesArgs are sent by the leader to replicate the log, with no entries they are heartbeats
+type AppendEntriesArgs struct {
+	Term         uint64  `json:"term"`
+	LeaderID     string  `json:"leaderId"`
+	PrevLogIndex uint64  `json:"prevLogIndex"`
+	PrevLogTerm  uint64  `json:"prevLogTerm"`
+	Entries      []Entry `json:"entries,omitempty"`
+	LeaderCommit uint64  `json:"leaderCommit"`
+}
+
+// AppendEntriesReply tells the leader where the logs diverge if the entries are rejected,
+// so it does not have to step back one entry at a time
+type AppendEntriesReply struct {
+	Term          uint64 `json:"term"`
+	Success       bool   `json:"success"`
+	ConflictIndex uint64 `json:"conflictIndex,omitempty"`
+}
+
+// InstallSnapshotArgs are sent by the leader to the followers lagging behind its compacted log.
+// Data is written by storage.WriteSnapshot, snapshots are sent at once as they are not expected to be large
+type InstallSnapshotArgs struct {
+	Term              uint64   `json:"term"`
+	LeaderID          string   `json:"leaderId"`
+	LastIncludedIndex uint64   `json:"lastIncludedIndex"`
+	LastIncludedTerm  uint64   `json:"lastIncludedTerm"`
+	Members           []string `json:"members"`
+	Data              []byte   `json:"data"`
+}
+
+// InstallSnapshotReply ...
+type InstallSnapshotReply struct {
+	Term uint64 `json:"term"`
+}
+
+// Transport delivers RPCs to other nodes identified by their IDs
+type Transport interface {
+	RequestVote(ctx context.Context, target string, args *RequestVoteArgs) (*RequestVoteReply, error)
+	AppendEntries(ctx context.Context, target string, args *AppendEntriesArgs) (*AppendEntriesReply, error)
+	InstallSnapshot(ctx context.Context, target string, args *InstallSnapshotArgs) (*InstallSnapshotReply, error)
+}
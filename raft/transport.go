@@ -0,0 +1,97 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// InMemoryNetwork connects nodes of a single process, so clusters could be tested without sockets.
+// Nodes could be disconnected to simulate network partitions
+type InMemoryNetwork struct {
+	lock         sync.RWMutex
+	nodes        map[string]*Node
+	disconnected map[string]bool
+}
+
+// NewInMemoryNetwork ...
+func NewInMemoryNetwork() *InMemoryNetwork {
+	network := new(InMemoryNetwork)
+	network.nodes = make(map[string]*Node)
+	network.disconnected = make(map[string]bool)
+	return network
+}
+
+// Register call makes the node reachable by its ID
+func (network *InMemoryNetwork) Register(node *Node) {
+	network.lock.Lock()
+	defer network.lock.Unlock()
+	network.nodes[node.ID()] = node
+}
+
+// Disconnect call drops all the RPCs from and to the node
+func (network *InMemoryNetwork) Disconnect(id string) {
+	network.lock.Lock()
+	defer network.lock.Unlock()
+	network.disconnected[id] = true
+}
+
+// Connect call restores connectivity of the node
+func (network *InMemoryNetwork) Connect(id string) {
+	network.lock.Lock()
+	defer network.lock.Unlock()
+	delete(network.disconnected, id)
+}
+
+// Transport call creates a transport sending RPCs on behalf of the node
+func (network *InMemoryNetwork) Transport(id string) Transport {
+	return &inMemoryTransport{network, id}
+}
+
+type inMemoryTransport struct {
+	network *InMemoryNetwork
+	source  string
+}
+
+func (transport *inMemoryTransport) target(ctx context.Context, id string) (*Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	transport.network.lock.RLock()
+	defer transport.network.lock.RUnlock()
+	node, exists := transport.network.nodes[id]
+	if !exists || transport.network.disconnected[id] || transport.network.disconnected[transport.source] {
+		return nil, errors.New("Node " + id + " is unreachable")
+	}
+	return node, nil
+}
+
+func (transport *inMemoryTransport) RequestVote(ctx context.Context, target string, args *RequestVoteArgs) (*RequestVoteReply, error) {
+	node, err := transport.target(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	copied := *args
+	return node.RequestVote(&copied)
+}
+
+// AppendEntries call copies the entries, so nodes do not share the memory like they would not over the network
+func (transport *inMemoryTransport) AppendEntries(ctx context.Context, target string, args *AppendEntriesArgs) (*AppendEntriesReply, error) {
+	node, err := transport.target(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	copied := *args
+	copied.Entries = append([]Entry(nil), args.Entries...)
+	return node.AppendEntries(&copied)
+}
+
+func (transport *inMemoryTransport) InstallSnapshot(ctx context.Context, target string, args *InstallSnapshotArgs) (*InstallSnapshotReply, error) {
+	node, err := transport.target(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	copied := *args
+	copied.Members = append([]string(nil), args.Members...)
+	return node.InstallSnapshot(&copied)
+}
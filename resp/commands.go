@@ -0,0 +1,589 @@
+package resp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/izhamoidsin/gedis/glob"
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// command describes a supported command, the number of its arguments (without the name)
+// should be from minArgs to maxArgs, negative maxArgs means there is no upper limit
+type command struct {
+	minArgs int
+	maxArgs int
+	handler func(c *connection, args []string)
+}
+
+// commands are looked up by upper cased names. The table is filled in init,
+// so the handlers could refer to it (e.g. COMMAND COUNT)
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"PING":    {0, 1, ping},
+		"ECHO":    {1, 1, echo},
+		"QUIT":    {0, 0, quit},
+		"HELLO":   {0, -1, hello},
+		"SELECT":  {1, 1, selectDB},
+		"CLIENT":  {1, -1, client},
+		"COMMAND": {0, -1, commandInfo},
+		"INFO":    {0, 1, info},
+		"DBSIZE":  {0, 0, dbSize},
+
+		"GET":         {1, 1, get},
+		"SET":         {2, -1, set},
+		"SETNX":       {2, 2, setNX},
+		"SETEX":       {3, 3, setEX},
+		"MGET":        {1, -1, mget},
+		"MSET":        {2, -1, mset},
+		"DEL":         {1, -1, del},
+		"UNLINK":      {1, -1, del},
+		"EXISTS":      {1, -1, exists},
+		"TYPE":        {1, 1, keyType},
+		"KEYS":        {1, 1, keys},
+		"SCAN":        {1, -1, scan},
+		"EXPIRE":      {2, 2, expire(time.Second)},
+		"PEXPIRE":     {2, 2, expire(time.Millisecond)},
+		"TTL":         {1, 1, ttl(time.Second)},
+		"PTTL":        {1, 1, ttl(time.Millisecond)},
+		"INCR":        {1, 1, increment(1)},
+		"DECR":        {1, 1, increment(-1)},
+		"INCRBY":      {2, 2, incrementBy(1)},
+		"DECRBY":      {2, 2, incrementBy(-1)},
+		"INCRBYFLOAT": {2, 2, incrementByFloat},
+
+		"LPUSH":  {2, -1, push(true)},
+		"RPUSH":  {2, -1, push(false)},
+		"LPOP":   {1, 1, pop(true)},
+		"RPOP":   {1, 1, pop(false)},
+		"LRANGE": {3, 3, listRange},
+		"LINDEX": {2, 2, listIndex},
+		"LLEN":   {1, 1, listLength},
+		"LSET":   {3, 3, listSet},
+		"LTRIM":  {3, 3, listTrim},
+
+		"HGET":    {2, 2, dictGet},
+		"HSET":    {3, -1, dictSet},
+		"HDEL":    {2, -1, dictDelete},
+		"HGETALL": {1, 1, dictGetAll},
+		"HEXISTS": {2, 2, dictExists},
+		"HLEN":    {1, 1, dictLength},
+		"HKEYS":   {1, 1, dictKeys},
+
+		"SADD":      {2, -1, setAdd},
+		"SREM":      {2, -1, setRemove},
+		"SMEMBERS":  {1, 1, setMembers},
+		"SISMEMBER": {2, 2, setIsMember},
+		"SCARD":     {1, 1, setCardinality},
+
+		"ZADD":    {3, -1, sortedSetAdd},
+		"ZREM":    {2, -1, sortedSetRemove},
+		"ZSCORE":  {2, 2, sortedSetScore},
+		"ZINCRBY": {3, 3, sortedSetIncrement},
+		"ZCARD":   {1, 1, sortedSetCardinality},
+		"ZRANGE":  {3, 4, sortedSetRange},
+	}
+}
+
+// wrongTypeMessages are errors of the storage caused by the type of the stored value
+var wrongTypeMessages = map[string]bool{
+	"Stored value is not a string":     true,
+	"Stored value is not an array":     true,
+	"Stored value is not a dictionary": true,
+	"Stored value is not a set":        true,
+	"Stored value is not a sorted set": true,
+}
+
+const (
+	wrongType      = "Operation against a key holding the wrong kind of value"
+	notInteger     = "value is not an integer or out of range"
+	notFloat       = "value is not a valid float"
+	syntaxError    = "syntax error"
+	outOfMemory    = "command not allowed when used memory > 'maxmemory'"
+	gedisVersion   = "7.0.0-gedis"
+	respRedisMode  = "standalone"
+	respRedisRole  = "master"
+	maxRESPVersion = 3
+)
+
+// exec call checks the number of arguments and runs the command
+func (c *connection) exec(args []string) {
+	name := strings.ToUpper(args[0])
+	cmd, exists := commands[name]
+	if !exists {
+		c.writer.errorReply("ERR", "unknown command '"+args[0]+"'")
+		return
+	}
+	if args = args[1:]; len(args) < cmd.minArgs || (cmd.maxArgs >= 0 && len(args) > cmd.maxArgs) {
+		c.wrongArguments(name)
+		return
+	}
+	cmd.handler(c, args)
+}
+
+func (c *connection) wrongArguments(name string) {
+	c.writer.errorReply("ERR", "wrong number of arguments for '"+strings.ToLower(name)+"' command")
+}
+
+// replyError call reports an error of the storage
+func (c *connection) replyError(err error) {
+	switch {
+	case err == storage.ErrOutOfMemory:
+		c.writer.errorReply("OOM", outOfMemory)
+	case wrongTypeMessages[err.Error()]:
+		c.writer.errorReply("WRONGTYPE", wrongType)
+	default:
+		c.writer.errorReply("ERR", err.Error())
+	}
+}
+
+// replyInteger call sends the number or the error
+func (c *connection) replyInteger(value int, err error) {
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.integer(int64(value))
+}
+
+// replyStrings call sends the array or the error
+func (c *connection) replyStrings(values []string, err error) {
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.strings(values)
+}
+
+func parseInt(c *connection, arg string) (int64, bool) {
+	value, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		c.writer.errorReply("ERR", notInteger)
+		return 0, false
+	}
+	return value, true
+}
+
+func parseFloat(c *connection, arg string) (float64, bool) {
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(value) {
+		c.writer.errorReply("ERR", notFloat)
+		return 0, false
+	}
+	return value, true
+}
+
+// getString call replies with the error itself if the entry is not a string
+func getString(c *connection, key string) (string, bool, bool) {
+	val, exists := c.server.storage.GetValueByKey(key)
+	if !exists {
+		return "", false, true
+	}
+	str, isString := val.Entity.(string)
+	if !isString {
+		c.writer.errorReply("WRONGTYPE", wrongType)
+		return "", false, false
+	}
+	return str, true, true
+}
+
+// typeName call converts the type of the value to the name Redis uses
+func typeName(entity storage.Storable) string {
+	switch kind := storage.TypeOf(entity); kind {
+	case "dict":
+		return "hash"
+	default:
+		return kind
+	}
+}
+
+func ping(c *connection, args []string) {
+	if len(args) == 0 {
+		c.writer.simpleString("PONG")
+	} else {
+		c.writer.bulk(args[0])
+	}
+}
+
+func echo(c *connection, args []string) {
+	c.writer.bulk(args[0])
+}
+
+func quit(c *connection, args []string) {
+	c.writer.ok()
+	c.quit = true
+}
+
+// hello call switches the protocol version and replies with the server properties.
+// Authentication is not supported, so AUTH option is rejected
+func hello(c *connection, args []string) {
+	protocol := c.writer.protocol
+	if len(args) > 0 {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			c.writer.errorReply("ERR", "Protocol version is not an integer or out of range")
+			return
+		}
+		if version < 2 || version > maxRESPVersion {
+			c.writer.errorReply("NOPROTO", "unsupported protocol version")
+			return
+		}
+		protocol = version
+	}
+	for i := 1; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "SETNAME" && i+1 < len(args):
+			i++
+			c.name = args[i]
+		case option == "AUTH":
+			c.writer.errorReply("ERR", "AUTH is not supported")
+			return
+		default:
+			c.writer.errorReply("ERR", syntaxError)
+			return
+		}
+	}
+
+	c.writer.protocol = protocol
+	c.writer.mapHeader(7)
+	c.writer.bulk("server")
+	c.writer.bulk("gedis")
+	c.writer.bulk("version")
+	c.writer.bulk(gedisVersion)
+	c.writer.bulk("proto")
+	c.writer.integer(int64(protocol))
+	c.writer.bulk("id")
+	c.writer.integer(c.id)
+	c.writer.bulk("mode")
+	c.writer.bulk(respRedisMode)
+	c.writer.bulk("role")
+	c.writer.bulk(respRedisRole)
+	c.writer.bulk("modules")
+	c.writer.arrayHeader(0)
+}
+
+// selectDB call accepts the only database there is
+func selectDB(c *connection, args []string) {
+	if args[0] != "0" {
+		c.writer.errorReply("ERR", "DB index is out of range")
+		return
+	}
+	c.writer.ok()
+}
+
+func client(c *connection, args []string) {
+	switch strings.ToUpper(args[0]) {
+	case "SETNAME":
+		if len(args) != 2 {
+			c.writer.errorReply("ERR", syntaxError)
+			return
+		}
+		c.name = args[1]
+		c.writer.ok()
+	case "GETNAME":
+		if c.name == "" {
+			c.writer.null()
+		} else {
+			c.writer.bulk(c.name)
+		}
+	case "ID":
+		c.writer.integer(c.id)
+	case "SETINFO":
+		c.writer.ok()
+	default:
+		c.writer.errorReply("ERR", "unknown subcommand '"+args[0]+"'")
+	}
+}
+
+// commandInfo call is needed by redis-cli on startup. Command docs are not provided
+func commandInfo(c *connection, args []string) {
+	if len(args) > 0 && strings.ToUpper(args[0]) == "COUNT" {
+		c.writer.integer(int64(len(commands)))
+		return
+	}
+	if len(args) > 0 && strings.ToUpper(args[0]) == "DOCS" {
+		c.writer.mapHeader(0)
+		return
+	}
+	c.writer.arrayHeader(0)
+}
+
+func info(c *connection, args []string) {
+	server := c.server
+	sections := []string{
+		"# Server\r\n" +
+			"redis_version:" + gedisVersion + "\r\n" +
+			"redis_mode:" + respRedisMode + "\r\n" +
+			"uptime_in_seconds:" + strconv.FormatInt(int64(time.Since(server.startTime)/time.Second), 10) + "\r\n",
+		"# Clients\r\n" +
+			"connected_clients:" + strconv.Itoa(server.connectedClients()) + "\r\n",
+		"# Stats\r\n" +
+			"total_connections_received:" + strconv.FormatUint(atomic.LoadUint64(&server.totalConnections), 10) + "\r\n" +
+			"total_commands_processed:" + strconv.FormatUint(atomic.LoadUint64(&server.commandsProcessed), 10) + "\r\n",
+		"# Replication\r\n" +
+			"role:" + respRedisRole + "\r\n",
+		"# Keyspace\r\n" +
+			"db0:keys=" + strconv.Itoa(len(server.storage.GetAllKeys())) + "\r\n",
+	}
+	if memoryLimited, ok := server.storage.(storage.MemoryLimitedStorage); ok {
+		stats := memoryLimited.MemoryStats()
+		sections = append(sections, "# Memory\r\n"+
+			"used_memory:"+strconv.FormatInt(stats.UsedMemory, 10)+"\r\n"+
+			"maxmemory:"+strconv.FormatInt(stats.MaxMemory, 10)+"\r\n")
+	}
+
+	var selected []string
+	for _, section := range sections {
+		title := strings.ToLower(strings.TrimPrefix(strings.SplitN(section, "\r\n", 2)[0], "# "))
+		if len(args) == 0 || strings.ToLower(args[0]) == "all" || strings.ToLower(args[0]) == title {
+			selected = append(selected, section)
+		}
+	}
+	c.writer.bulk(strings.Join(selected, "\r\n"))
+}
+
+func dbSize(c *connection, args []string) {
+	c.writer.integer(int64(len(c.server.storage.GetAllKeys())))
+}
+
+func get(c *connection, args []string) {
+	if str, exists, ok := getString(c, args[0]); ok && exists {
+		c.writer.bulk(str)
+	} else if ok {
+		c.writer.null()
+	}
+}
+
+// set call supports EX, PX, NX and XX options
+func set(c *connection, args []string) {
+	key, value := args[0], args[1]
+	var ttl time.Duration
+	precondition := storage.Precondition(storage.Always)
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			precondition = storage.IfAbsent
+		case "XX":
+			precondition = storage.IfExists
+		case "EX", "PX":
+			if i+1 == len(args) {
+				c.writer.errorReply("ERR", syntaxError)
+				return
+			}
+			i++
+			amount, ok := parseInt(c, args[i])
+			if !ok {
+				return
+			}
+			if amount <= 0 {
+				c.writer.errorReply("ERR", "invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Second
+			if option == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(amount) * unit
+		default:
+			c.writer.errorReply("ERR", syntaxError)
+			return
+		}
+	}
+
+	switch _, err := c.server.storage.SetValueByKeyIf(key, value, ttl, precondition); err {
+	case nil:
+		c.writer.ok()
+	case storage.ErrVersionConflict:
+		c.writer.null()
+	default:
+		c.replyError(err)
+	}
+}
+
+func setNX(c *connection, args []string) {
+	_, err := c.server.storage.SetValueByKeyIf(args[0], args[1], 0, storage.IfAbsent)
+	if err != nil && err != storage.ErrVersionConflict {
+		c.replyError(err)
+		return
+	}
+	c.writer.boolean(err == nil)
+}
+
+func setEX(c *connection, args []string) {
+	seconds, ok := parseInt(c, args[1])
+	if !ok {
+		return
+	}
+	if seconds <= 0 {
+		c.writer.errorReply("ERR", "invalid expire time in 'setex' command")
+		return
+	}
+	if _, err := c.server.storage.SetValueByKeyIf(args[0], args[2], time.Duration(seconds)*time.Second, storage.Always); err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.ok()
+}
+
+// mget call replies with null for absent keys and keys holding other types than string
+func mget(c *connection, args []string) {
+	c.writer.arrayHeader(len(args))
+	for _, key := range args {
+		if val, exists := c.server.storage.GetValueByKey(key); exists {
+			if str, isString := val.Entity.(string); isString {
+				c.writer.bulk(str)
+				continue
+			}
+		}
+		c.writer.null()
+	}
+}
+
+// mset call stores the pairs atomically
+func mset(c *connection, args []string) {
+	if len(args)%2 != 0 {
+		c.wrongArguments("MSET")
+		return
+	}
+	err := c.server.storage.ExecTransaction(nil, func(tx *storage.Tx) error {
+		for i := 0; i < len(args); i += 2 {
+			tx.SetValueByKey(args[i], args[i+1], 0)
+		}
+		return nil
+	})
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.ok()
+}
+
+func del(c *connection, args []string) {
+	deleted := 0
+	for _, key := range args {
+		if c.server.storage.DeleteValueByKey(key) {
+			deleted++
+		}
+	}
+	c.writer.integer(int64(deleted))
+}
+
+func exists(c *connection, args []string) {
+	existing := 0
+	for _, key := range args {
+		if _, exists := c.server.storage.GetValueByKey(key); exists {
+			existing++
+		}
+	}
+	c.writer.integer(int64(existing))
+}
+
+func keyType(c *connection, args []string) {
+	if val, exists := c.server.storage.GetValueByKey(args[0]); exists {
+		c.writer.simpleString(typeName(val.Entity))
+	} else {
+		c.writer.simpleString("none")
+	}
+}
+
+func keys(c *connection, args []string) {
+	if err := glob.Validate(args[0]); err != nil {
+		c.writer.errorReply("ERR", "Malformed pattern "+args[0])
+		return
+	}
+	var matching []string
+	for _, key := range c.server.storage.GetAllKeys() {
+		if matched, _ := glob.Match(args[0], key); matched {
+			matching = append(matching, key)
+		}
+	}
+	sort.Strings(matching)
+	c.writer.strings(matching)
+}
+
+// scan call supports MATCH, COUNT and TYPE options, the cursor is the same as HTTP API uses
+func scan(c *connection, args []string) {
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		c.writer.errorReply("ERR", "invalid cursor")
+		return
+	}
+	count := 10
+	var filter storage.ScanFilter
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			c.writer.errorReply("ERR", syntaxError)
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			filter.Match = args[i+1]
+		case "COUNT":
+			value, ok := parseInt(c, args[i+1])
+			if !ok {
+				return
+			}
+			if value < 1 {
+				c.writer.errorReply("ERR", syntaxError)
+				return
+			}
+			count = int(value)
+		case "TYPE":
+			filter.Type = args[i+1]
+			if filter.Type == "hash" {
+				filter.Type = "dict"
+			}
+		default:
+			c.writer.errorReply("ERR", syntaxError)
+			return
+		}
+	}
+
+	page, next, err := c.server.storage.Scan(cursor, count, filter)
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.arrayHeader(2)
+	c.writer.bulk(strconv.FormatUint(next, 10))
+	c.writer.strings(page)
+}
+
+// expire call creates the handler of EXPIRE (seconds) or PEXPIRE (milliseconds)
+func expire(unit time.Duration) func(c *connection, args []string) {
+	return func(c *connection, args []string) {
+		amount, ok := parseInt(c, args[1])
+		if !ok {
+			return
+		}
+		existed, err := c.server.storage.Expire(args[0], time.Duration(amount)*unit)
+		if err != nil {
+			c.replyError(err)
+			return
+		}
+		c.writer.boolean(existed)
+	}
+}
+
+// ttl call creates the handler of TTL (seconds) or PTTL (milliseconds), -2 is replied for absent keys.
+// There are no keys without expiration, so -1 is never replied
+func ttl(unit time.Duration) func(c *connection, args []string) {
+	return func(c *connection, args []string) {
+		val, exists := c.server.storage.GetValueByKey(args[0])
+		if !exists {
+			c.writer.integer(-2)
+			return
+		}
+		expireIn := val.ExpireIn()
+		if expireIn < 0 {
+			expireIn = 0
+		}
+		c.writer.integer(int64((expireIn + unit/2) / unit))
+	}
+}
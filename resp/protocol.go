@@ -0,0 +1,177 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBulkLength limits the size of a single argument of a command
+	maxBulkLength = 16 * 1024 * 1024
+	// maxArguments limits the number of arguments of a command
+	maxArguments = 1024 * 1024
+)
+
+// protocolError is a malformed request, the connection is closed after it is reported
+type protocolError string
+
+func (err protocolError) Error() string {
+	return "Protocol error: " + string(err)
+}
+
+// readLine call reads a line terminated by CRLF (or LF for inline commands) without the terminator
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readLength call parses the length following the prefix of an array or a bulk string header
+func readLength(reader *bufio.Reader, prefix byte, limit int) (int, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return 0, err
+	}
+	if len(line) == 0 || line[0] != prefix {
+		return 0, protocolError("expected '" + string(prefix) + "', got '" + line + "'")
+	}
+	length, err := strconv.Atoi(line[1:])
+	if err != nil || length < 0 || length > limit {
+		return 0, protocolError("invalid length " + line[1:])
+	}
+	return length, nil
+}
+
+// readCommand call reads either an array of bulk strings, as clients send commands,
+// or an inline command (space separated words) as typed in telnet. Empty inline commands are skipped
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	for {
+		first, err := reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if first[0] != '*' {
+			line, err := readLine(reader)
+			if err != nil {
+				return nil, err
+			}
+			if args := strings.Fields(line); len(args) > 0 {
+				return args, nil
+			}
+			continue
+		}
+
+		count, err := readLength(reader, '*', maxArguments)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			continue
+		}
+		args := make([]string, count)
+		for i := range args {
+			length, err := readLength(reader, '$', maxBulkLength)
+			if err != nil {
+				return nil, err
+			}
+			bulk := make([]byte, length+2)
+			if _, err := io.ReadFull(reader, bulk); err != nil {
+				return nil, err
+			}
+			if bulk[length] != '\r' || bulk[length+1] != '\n' {
+				return nil, protocolError("bulk string is not terminated by CRLF")
+			}
+			args[i] = string(bulk[:length])
+		}
+		return args, nil
+	}
+}
+
+// writer encodes replies either in RESP2 or in RESP3 (chosen by HELLO command).
+// RESP3 only types are downgraded to their RESP2 equivalents
+type writer struct {
+	*bufio.Writer
+	protocol int
+}
+
+func (w *writer) simpleString(str string) {
+	w.WriteString("+" + str + "\r\n")
+}
+
+func (w *writer) ok() {
+	w.simpleString("OK")
+}
+
+// errorReply call sends the message with the error code like ERR or WRONGTYPE
+func (w *writer) errorReply(code string, message string) {
+	w.WriteString("-" + code + " " + strings.Replace(strings.Replace(message, "\r", " ", -1), "\n", " ", -1) + "\r\n")
+}
+
+func (w *writer) integer(value int64) {
+	w.WriteString(":" + strconv.FormatInt(value, 10) + "\r\n")
+}
+
+func (w *writer) boolean(value bool) {
+	if value {
+		w.integer(1)
+	} else {
+		w.integer(0)
+	}
+}
+
+func (w *writer) bulk(str string) {
+	w.WriteString("$" + strconv.Itoa(len(str)) + "\r\n" + str + "\r\n")
+}
+
+func (w *writer) null() {
+	if w.protocol >= 3 {
+		w.WriteString("_\r\n")
+	} else {
+		w.WriteString("$-1\r\n")
+	}
+}
+
+func (w *writer) double(value float64) {
+	formatted := strconv.FormatFloat(value, 'g', -1, 64)
+	if w.protocol >= 3 {
+		w.WriteString("," + formatted + "\r\n")
+	} else {
+		w.bulk(formatted)
+	}
+}
+
+func (w *writer) arrayHeader(length int) {
+	w.WriteString("*" + strconv.Itoa(length) + "\r\n")
+}
+
+// mapHeader call is followed by length pairs of keys and values, RESP2 gets them as a flat array
+func (w *writer) mapHeader(length int) {
+	if w.protocol >= 3 {
+		w.WriteString("%" + strconv.Itoa(length) + "\r\n")
+	} else {
+		w.arrayHeader(length * 2)
+	}
+}
+
+// setHeader call is followed by members of a set, RESP2 gets them as an array
+func (w *writer) setHeader(length int) {
+	if w.protocol >= 3 {
+		w.WriteString("~" + strconv.Itoa(length) + "\r\n")
+	} else {
+		w.arrayHeader(length)
+	}
+}
+
+func (w *writer) strings(values []string) {
+	w.arrayHeader(len(values))
+	for _, value := range values {
+		w.bulk(value)
+	}
+}
@@ -0,0 +1,166 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// Server speaks Redis serialization protocol (RESP2, and RESP3 after HELLO 3) over TCP,
+// so redis-cli and Redis client libraries could work with the storage without HTTP
+type Server struct {
+	storage   storage.Storage
+	startTime time.Time
+
+	lock        sync.Mutex
+	listener    net.Listener
+	connections map[net.Conn]struct{}
+	closed      bool
+
+	lastClientID      int64
+	totalConnections  uint64
+	commandsProcessed uint64
+}
+
+// CreateServer ...
+func CreateServer(registry storage.Storage) *Server {
+	server := new(Server)
+	server.storage = registry
+	server.connections = make(map[net.Conn]struct{})
+	return server
+}
+
+// ListenAndServe call accepts connections on the port until the server is closed
+func (server *Server) ListenAndServe(port int) error {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	log.Println("Starting RESP server @ port " + strconv.Itoa(port))
+	return server.Serve(listener)
+}
+
+// Serve call accepts connections of the listener until the server is closed
+func (server *Server) Serve(listener net.Listener) error {
+	server.lock.Lock()
+	if server.closed {
+		server.lock.Unlock()
+		listener.Close()
+		return errors.New("Server is closed")
+	}
+	server.listener = listener
+	server.startTime = time.Now()
+	server.lock.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			server.lock.Lock()
+			closed := server.closed
+			server.lock.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if !server.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go server.handle(conn)
+	}
+}
+
+// Close call stops accepting connections and closes the established ones
+func (server *Server) Close() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.closed = true
+	for conn := range server.connections {
+		conn.Close()
+	}
+	if server.listener != nil {
+		return server.listener.Close()
+	}
+	return nil
+}
+
+func (server *Server) track(conn net.Conn) bool {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.closed {
+		return false
+	}
+	server.connections[conn] = struct{}{}
+	atomic.AddUint64(&server.totalConnections, 1)
+	return true
+}
+
+func (server *Server) untrack(conn net.Conn) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	delete(server.connections, conn)
+	conn.Close()
+}
+
+func (server *Server) connectedClients() int {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return len(server.connections)
+}
+
+// connection is a state of a single client
+type connection struct {
+	server *Server
+	id     int64
+	name   string
+	reader *bufio.Reader
+	writer *writer
+	quit   bool
+}
+
+// handle call serves commands of the client one by one. Replies are flushed once there are no
+// more pipelined commands buffered, so a pipeline is answered with as few writes as possible
+func (server *Server) handle(conn net.Conn) {
+	defer server.untrack(conn)
+	c := &connection{
+		server: server,
+		id:     atomic.AddInt64(&server.lastClientID, 1),
+		reader: bufio.NewReader(conn),
+		writer: &writer{bufio.NewWriter(conn), 2},
+	}
+
+	for !c.quit {
+		args, err := readCommand(c.reader)
+		if err != nil {
+			if _, malformed := err.(protocolError); malformed {
+				c.writer.errorReply("ERR", err.Error())
+				c.writer.Flush()
+			} else if err != io.EOF && !isClosedConnection(err) {
+				log.Println("RESP connection failed: " + err.Error())
+			}
+			return
+		}
+		atomic.AddUint64(&server.commandsProcessed, 1)
+		c.exec(args)
+
+		if c.reader.Buffered() == 0 || c.quit {
+			if err := c.writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func isClosedConnection(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
@@ -0,0 +1,150 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// startTestServer call returns a connection to a new server over a fresh storage
+func startTestServer(t *testing.T) (net.Conn, *Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Can not listen: " + err.Error())
+	}
+	server := CreateServer(storage.InitSyncMapStorage(time.Minute))
+	go server.Serve(listener)
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("Can not connect: " + err.Error())
+	}
+	return conn, server
+}
+
+// encodeCommand call encodes the command the way clients do
+func encodeCommand(args ...string) string {
+	encoded := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		encoded += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	return encoded
+}
+
+// readReply call reads a whole reply as raw text, aggregates are read recursively
+func readReply(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	switch line[0] {
+	case '$':
+		length, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if length < 0 {
+			return line, nil
+		}
+		bulk := make([]byte, length+2)
+		_, err := io.ReadFull(reader, bulk)
+		return line + string(bulk), err
+	case '*', '%', '~':
+		count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if line[0] == '%' {
+			count *= 2
+		}
+		for i := 0; i < count; i++ {
+			element, err := readReply(reader)
+			if err != nil {
+				return "", err
+			}
+			line += element
+		}
+	}
+	return line, nil
+}
+
+func TestCommands(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	expectations := []struct {
+		command []string
+		reply   string
+	}{
+		{[]string{"PING"}, "+PONG\r\n"},
+		{[]string{"SET", "key", "value"}, "+OK\r\n"},
+		{[]string{"GET", "key"}, "$5\r\nvalue\r\n"},
+		{[]string{"SET", "key", "other", "NX"}, "$-1\r\n"},
+		{[]string{"SET", "new", "value", "XX"}, "$-1\r\n"},
+		{[]string{"EXISTS", "key", "new", "key"}, ":2\r\n"},
+		{[]string{"EXPIRE", "key", "100"}, ":1\r\n"},
+		{[]string{"TTL", "key"}, ":100\r\n"},
+		{[]string{"TTL", "new"}, ":-2\r\n"},
+		{[]string{"INCR", "counter"}, ":1\r\n"},
+		{[]string{"INCRBY", "counter", "10"}, ":11\r\n"},
+		{[]string{"INCR", "key"}, "-ERR Stored value is not an integer or out of range\r\n"},
+		{[]string{"LPUSH", "list", "a", "b"}, ":2\r\n"},
+		{[]string{"RPUSH", "list", "c"}, ":3\r\n"},
+		{[]string{"LRANGE", "list", "0", "-1"}, "*3\r\n$1\r\nb\r\n$1\r\na\r\n$1\r\nc\r\n"},
+		{[]string{"LINDEX", "list", "-1"}, "$1\r\nc\r\n"},
+		{[]string{"GET", "list"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"HSET", "hash", "f1", "v1", "f2", "v2"}, ":2\r\n"},
+		{[]string{"HGET", "hash", "f2"}, "$2\r\nv2\r\n"},
+		{[]string{"HGET", "hash", "f3"}, "$-1\r\n"},
+		{[]string{"HGETALL", "hash"}, "*4\r\n$2\r\nf1\r\n$2\r\nv1\r\n$2\r\nf2\r\n$2\r\nv2\r\n"},
+		{[]string{"TYPE", "hash"}, "+hash\r\n"},
+		{[]string{"KEYS", "*e*"}, "*2\r\n$7\r\ncounter\r\n$3\r\nkey\r\n"},
+		{[]string{"DEL", "key", "missing"}, ":1\r\n"},
+		{[]string{"SET", "users/1/name", "v"}, "+OK\r\n"},
+		{[]string{"KEYS", "users*"}, "*1\r\n$12\r\nusers/1/name\r\n"},
+		{[]string{"DEL", "users/1/name"}, ":1\r\n"},
+		{[]string{"get"}, "-ERR wrong number of arguments for 'get' command\r\n"},
+		{[]string{"FOO"}, "-ERR unknown command 'FOO'\r\n"},
+		{[]string{"HELLO", "3"}, "%7\r\n"},
+		{[]string{"GET", "missing"}, "_\r\n"},
+		{[]string{"HGETALL", "hash"}, "%2\r\n$2\r\nf1\r\n$2\r\nv1\r\n$2\r\nf2\r\n$2\r\nv2\r\n"},
+	}
+	for _, expectation := range expectations {
+		conn.Write([]byte(encodeCommand(expectation.command...)))
+		reply, err := readReply(reader)
+		if err != nil || !strings.HasPrefix(reply, expectation.reply) {
+			t.Errorf("%v: expected %q, got %q", expectation.command, expectation.reply, reply)
+		}
+	}
+}
+
+func TestInlineAndPipelinedCommands(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("SET inline value\r\nGET inline\r\n" + encodeCommand("ECHO", "pipelined") + encodeCommand("QUIT")))
+	expected := []string{"+OK\r\n", "$5\r\nvalue\r\n", "$9\r\npipelined\r\n", "+OK\r\n"}
+	for _, reply := range expected {
+		if actual, err := readReply(reader); err != nil || actual != reply {
+			t.Errorf("Expected %q, got %q", reply, actual)
+		}
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Error("Connection is not closed on QUIT")
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	conn, server := startTestServer(t)
+	defer server.Close()
+	reader := bufio.NewReader(conn)
+
+	conn.Write([]byte("*1\r\n+PING\r\n"))
+	if reply, _ := readReply(reader); !strings.HasPrefix(reply, "-ERR Protocol error") {
+		t.Error("Malformed command is not reported")
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Error("Connection is not closed after protocol error")
+	}
+}
@@ -0,0 +1,343 @@
+package resp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// increment call creates the handler of INCR or DECR
+func increment(delta int64) func(c *connection, args []string) {
+	return func(c *connection, args []string) {
+		value, err := c.server.storage.IncrementBy(args[0], delta)
+		if err != nil {
+			c.replyError(err)
+			return
+		}
+		c.writer.integer(value)
+	}
+}
+
+// incrementBy call creates the handler of INCRBY or DECRBY, sign is applied to the passed increment
+func incrementBy(sign int64) func(c *connection, args []string) {
+	return func(c *connection, args []string) {
+		delta, ok := parseInt(c, args[1])
+		if !ok {
+			return
+		}
+		increment(sign*delta)(c, args)
+	}
+}
+
+// incrementByFloat call replies with a bulk string like Redis does
+func incrementByFloat(c *connection, args []string) {
+	delta, ok := parseFloat(c, args[1])
+	if !ok {
+		return
+	}
+	value, err := c.server.storage.IncrementByFloat(args[0], delta)
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.bulk(strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// push call creates the handler of LPUSH or RPUSH
+func push(toHead bool) func(c *connection, args []string) {
+	return func(c *connection, args []string) {
+		c.replyInteger(c.server.storage.PushToList(args[0], toHead, args[1:]...))
+	}
+}
+
+// pop call creates the handler of LPOP or RPOP
+func pop(fromHead bool) func(c *connection, args []string) {
+	return func(c *connection, args []string) {
+		element, found, err := c.server.storage.PopFromList(args[0], fromHead)
+		switch {
+		case err != nil:
+			c.replyError(err)
+		case found:
+			c.writer.bulk(element)
+		default:
+			c.writer.null()
+		}
+	}
+}
+
+// parseRange call parses start and stop indexes of a range
+func parseRange(c *connection, startArg string, stopArg string) (int, int, bool) {
+	start, ok := parseInt(c, startArg)
+	if !ok {
+		return 0, 0, false
+	}
+	stop, ok := parseInt(c, stopArg)
+	return int(start), int(stop), ok
+}
+
+func listRange(c *connection, args []string) {
+	if start, stop, ok := parseRange(c, args[1], args[2]); ok {
+		c.replyStrings(c.server.storage.GetListRange(args[0], start, stop))
+	}
+}
+
+func listIndex(c *connection, args []string) {
+	index, ok := parseInt(c, args[1])
+	if !ok {
+		return
+	}
+	// a range of a single element supports negative indexes and does not fail if the index is out of range
+	elements, err := c.server.storage.GetListRange(args[0], int(index), int(index))
+	switch {
+	case err != nil:
+		c.replyError(err)
+	case len(elements) == 1:
+		c.writer.bulk(elements[0])
+	default:
+		c.writer.null()
+	}
+}
+
+func listLength(c *connection, args []string) {
+	c.replyInteger(c.server.storage.GetListLength(args[0]))
+}
+
+func listSet(c *connection, args []string) {
+	index, ok := parseInt(c, args[1])
+	if !ok {
+		return
+	}
+	if err := c.server.storage.SetListElement(args[0], int(index), args[2]); err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.ok()
+}
+
+func listTrim(c *connection, args []string) {
+	start, stop, ok := parseRange(c, args[1], args[2])
+	if !ok {
+		return
+	}
+	if err := c.server.storage.TrimList(args[0], start, stop); err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.ok()
+}
+
+func dictGet(c *connection, args []string) {
+	val, exists, err := c.server.storage.GetNestedValueByKeyAndSubkey(args[0], args[1])
+	switch {
+	case err != nil:
+		c.replyError(err)
+	case exists:
+		c.writer.bulk(val.Entity.(string))
+	default:
+		c.writer.null()
+	}
+}
+
+// dictSet call sets all the passed fields atomically and replies with the number of new ones
+func dictSet(c *connection, args []string) {
+	if len(args)%2 != 1 {
+		c.wrongArguments("HSET")
+		return
+	}
+	created := 0
+	err := c.server.storage.ExecTransaction(nil, func(tx *storage.Tx) error {
+		created = 0
+		for i := 1; i < len(args); i += 2 {
+			isNew, err := tx.SetDictEntry(args[0], args[i], args[i+1])
+			if err != nil {
+				return err
+			}
+			if isNew {
+				created++
+			}
+		}
+		return nil
+	})
+	c.replyInteger(created, err)
+}
+
+// dictDelete call removes all the passed fields atomically and replies with the number of removed ones
+func dictDelete(c *connection, args []string) {
+	removed := 0
+	err := c.server.storage.ExecTransaction(nil, func(tx *storage.Tx) error {
+		removed = 0
+		for _, field := range args[1:] {
+			existed, err := tx.DeleteDictEntry(args[0], field)
+			if err != nil {
+				return err
+			}
+			if existed {
+				removed++
+			}
+		}
+		return nil
+	})
+	c.replyInteger(removed, err)
+}
+
+// dictGetAll call replies with fields sorted, so the reply does not depend on the map iteration order
+func dictGetAll(c *connection, args []string) {
+	dict, err := c.server.storage.GetDictEntries(args[0])
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	fields := make([]string, 0, len(dict))
+	for field := range dict {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	c.writer.mapHeader(len(fields))
+	for _, field := range fields {
+		c.writer.bulk(field)
+		c.writer.bulk(dict[field])
+	}
+}
+
+func dictExists(c *connection, args []string) {
+	exists, err := c.server.storage.DictEntryExists(args[0], args[1])
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.boolean(exists)
+}
+
+func dictLength(c *connection, args []string) {
+	c.replyInteger(c.server.storage.GetDictLength(args[0]))
+}
+
+func dictKeys(c *connection, args []string) {
+	c.replyStrings(c.server.storage.GetDictKeys(args[0]))
+}
+
+func setAdd(c *connection, args []string) {
+	c.replyInteger(c.server.storage.AddToSet(args[0], args[1:]...))
+}
+
+func setRemove(c *connection, args []string) {
+	c.replyInteger(c.server.storage.RemoveFromSet(args[0], args[1:]...))
+}
+
+func setMembers(c *connection, args []string) {
+	members, err := c.server.storage.GetSetMembers(args[0])
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.setHeader(len(members))
+	for _, member := range members {
+		c.writer.bulk(member)
+	}
+}
+
+func setIsMember(c *connection, args []string) {
+	isMember, err := c.server.storage.IsSetMember(args[0], args[1])
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.boolean(isMember)
+}
+
+func setCardinality(c *connection, args []string) {
+	c.replyInteger(c.server.storage.GetSetCardinality(args[0]))
+}
+
+// sortedSetAdd call accepts pairs of scores and members, ZADD options are not supported
+func sortedSetAdd(c *connection, args []string) {
+	if len(args)%2 != 1 {
+		c.writer.errorReply("ERR", syntaxError)
+		return
+	}
+	members := make([]storage.ScoredMember, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		score, ok := parseFloat(c, args[i])
+		if !ok {
+			return
+		}
+		members = append(members, storage.ScoredMember{Member: args[i+1], Score: score})
+	}
+	c.replyInteger(c.server.storage.AddToSortedSet(args[0], members...))
+}
+
+func sortedSetRemove(c *connection, args []string) {
+	c.replyInteger(c.server.storage.RemoveFromSortedSet(args[0], args[1:]...))
+}
+
+func sortedSetScore(c *connection, args []string) {
+	score, exists, err := c.server.storage.GetSortedSetScore(args[0], args[1])
+	switch {
+	case err != nil:
+		c.replyError(err)
+	case exists:
+		c.writer.double(score)
+	default:
+		c.writer.null()
+	}
+}
+
+func sortedSetIncrement(c *connection, args []string) {
+	delta, ok := parseFloat(c, args[1])
+	if !ok {
+		return
+	}
+	score, err := c.server.storage.IncrementSortedSetScore(args[0], args[2], delta)
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	c.writer.double(score)
+}
+
+func sortedSetCardinality(c *connection, args []string) {
+	c.replyInteger(c.server.storage.GetSortedSetCardinality(args[0]))
+}
+
+// sortedSetRange call supports a range by rank with optional WITHSCORES. With RESP3 members
+// and scores are replied as pairs, RESP2 gets them as a flat array
+func sortedSetRange(c *connection, args []string) {
+	withScores := len(args) == 4
+	if withScores && strings.ToUpper(args[3]) != "WITHSCORES" {
+		c.writer.errorReply("ERR", syntaxError)
+		return
+	}
+	start, stop, ok := parseRange(c, args[1], args[2])
+	if !ok {
+		return
+	}
+	members, err := c.server.storage.GetSortedSetRangeByRank(args[0], start, stop, false)
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	if !withScores {
+		names := make([]string, len(members))
+		for i, member := range members {
+			names[i] = member.Member
+		}
+		c.writer.strings(names)
+		return
+	}
+	if c.writer.protocol >= 3 {
+		c.writer.arrayHeader(len(members))
+		for _, member := range members {
+			c.writer.arrayHeader(2)
+			c.writer.bulk(member.Member)
+			c.writer.double(member.Score)
+		}
+		return
+	}
+	c.writer.arrayHeader(len(members) * 2)
+	for _, member := range members {
+		c.writer.bulk(member.Member)
+		c.writer.double(member.Score)
+	}
+}
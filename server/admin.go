@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type snapshotStatus struct {
+	LastSnapshotTime *time.Time `json:"lastSnapshotTime"`
+	Error            string     `json:"error,omitempty"`
+}
+
+func newSnapshotStatus(lastSnapshotTime time.Time, err error) *snapshotStatus {
+	status := new(snapshotStatus)
+	if !lastSnapshotTime.IsZero() {
+		status.LastSnapshotTime = &lastSnapshotTime
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+func (server *GedisServer) snapshotInfo(w http.ResponseWriter, r *http.Request) {
+	if server.snapshotter == nil {
+		http.Error(w, "Snapshots are disabled", http.StatusNotImplemented)
+		return
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(newSnapshotStatus(server.snapshotter.LastSnapshotTime(), nil))
+}
+
+func (server *GedisServer) takeSnapshot(w http.ResponseWriter, r *http.Request) {
+	if server.snapshotter == nil {
+		http.Error(w, "Snapshots are disabled", http.StatusNotImplemented)
+		return
+	}
+	lastSnapshotTime, err := server.snapshotter.Save()
+	respondWithJSON(w)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(newSnapshotStatus(lastSnapshotTime, err))
+}
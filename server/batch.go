@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// maxBatchSize limits the number of keys of a single batch request
+const maxBatchSize = 1000
+
+type batchKeysRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// batchEntry is an entry to store, type should be passed for sets and sorted sets
+type batchEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+	Type  string          `json:"type,omitempty"`
+	TTL   string          `json:"ttl,omitempty"`
+}
+
+// batchSetRequest is a body of the batch write. With `nx` the entries are stored only if their keys are absent
+type batchSetRequest struct {
+	Entries      []batchEntry `json:"entries"`
+	OnlyIfAbsent bool         `json:"nx"`
+}
+
+type batchGetResult struct {
+	Key     string           `json:"key"`
+	Found   bool             `json:"found"`
+	Value   storage.Storable `json:"value,omitempty"`
+	Type    string           `json:"type,omitempty"`
+	Version uint64           `json:"version,omitempty"`
+}
+
+type batchSetResult struct {
+	Key     string `json:"key"`
+	Stored  bool   `json:"stored"`
+	Version uint64 `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type batchDeleteResult struct {
+	Key     string `json:"key"`
+	Deleted bool   `json:"deleted"`
+}
+
+// decodeBatchRequest call responds with 400 itself if the body is malformed or contains too many keys
+func decodeBatchRequest(w http.ResponseWriter, r *http.Request, request interface{}, size func() int) bool {
+	if err := decodeJSONRequestBody(r, request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if size() > maxBatchSize {
+		http.Error(w, "Batch should contain at most "+strconv.Itoa(maxBatchSize)+" keys", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// batchGet handler responds with the entries in the order of the requested keys
+func (server *GedisServer) batchGet(w http.ResponseWriter, r *http.Request) {
+	var request batchKeysRequest
+	if !decodeBatchRequest(w, r, &request, func() int { return len(request.Keys) }) {
+		return
+	}
+	results := make([]batchGetResult, len(request.Keys))
+	for i, key := range request.Keys {
+		results[i].Key = key
+		if val, exists := server.storage.GetValueByKey(key); exists {
+			results[i].Found = true
+			results[i].Value = val.Entity
+			results[i].Type = storage.TypeOf(val.Entity)
+			results[i].Version = val.Version
+		}
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(results)
+}
+
+// batchSet handler stores the entries one by one, so failure of one does not prevent others from being stored.
+// Invalid entries (malformed value, type or TTL) fail the whole request before anything is stored
+func (server *GedisServer) batchSet(w http.ResponseWriter, r *http.Request) {
+	var request batchSetRequest
+	if !decodeBatchRequest(w, r, &request, func() int { return len(request.Entries) }) {
+		return
+	}
+	values := make([]storage.Storable, len(request.Entries))
+	ttls := make([]time.Duration, len(request.Entries))
+	for i, entry := range request.Entries {
+		var err error
+		if values[i], err = parseEntry(entry.Value, entry.Type); err == nil {
+			ttls[i], err = parseTTL(entry.TTL)
+		}
+		if err != nil {
+			http.Error(w, "Entry "+strconv.Itoa(i)+" ("+entry.Key+") is invalid: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	precondition := storage.Precondition(storage.Always)
+	if request.OnlyIfAbsent {
+		precondition = storage.IfAbsent
+	}
+	results := make([]batchSetResult, len(request.Entries))
+	for i, entry := range request.Entries {
+		results[i].Key = entry.Key
+		stored, err := server.storage.SetValueByKeyIf(entry.Key, values[i], ttls[i], precondition)
+		switch err {
+		case nil:
+			results[i].Stored = true
+			results[i].Version = stored.Version
+		case storage.ErrVersionConflict: // the key exists in nx mode
+		default:
+			results[i].Error = err.Error()
+		}
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(results)
+}
+
+// batchDelete handler removes the keys and reports which of them existed
+func (server *GedisServer) batchDelete(w http.ResponseWriter, r *http.Request) {
+	var request batchKeysRequest
+	if !decodeBatchRequest(w, r, &request, func() int { return len(request.Keys) }) {
+		return
+	}
+	results := make([]batchDeleteResult, len(request.Keys))
+	for i, key := range request.Keys {
+		results[i] = batchDeleteResult{key, server.storage.DeleteValueByKey(key)}
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(results)
+}
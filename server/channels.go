@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/izhamoidsin/gedis/pubsub"
+)
+
+// heartbeatInterval is how often a comment line is sent to idle streams,
+// so proxies and clients do not consider the connection dead
+const heartbeatInterval = 15 * time.Second
+
+// publish handler sends the message (a JSON string) to the channel and responds with
+// the number of subscribers received it
+func (server *GedisServer) publish(w http.ResponseWriter, r *http.Request) {
+	channel := mux.Vars(r)["channel"]
+	if isReservedChannel(channel) {
+		http.Error(w, "Channel "+channel+" is reserved for notifications of the server", http.StatusForbidden)
+		return
+	}
+	var message string
+	if err := decodeJSONRequestBody(r, &message); err != nil {
+		http.Error(w, "Message should be a string", http.StatusBadRequest)
+		return
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(server.broker.Publish(channel, message))
+}
+
+// subscribe handler streams messages of the channel as Server-Sent Events until the client disconnects.
+// With `pattern=true` the name is treated as a glob pattern matching channel names
+func (server *GedisServer) subscribe(w http.ResponseWriter, r *http.Request) {
+	channel := mux.Vars(r)["channel"]
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var subscription *pubsub.Subscription
+	if r.URL.Query().Get("pattern") == "true" {
+		var err error
+		if subscription, err = server.broker.SubscribePattern(channel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		subscription = server.broker.Subscribe(channel)
+	}
+	defer subscription.Close()
+
+	streamEvents(w, r, flusher, subscription.Messages, func(message pubsub.Message) interface{} {
+		return message
+	})
+}
+
+// streamEvents call writes an SSE `data` line holding JSON of the event made of every received message.
+// Messages the event function returns nil for are skipped
+func streamEvents(w http.ResponseWriter, r *http.Request, flusher http.Flusher, messages <-chan pubsub.Message, event func(message pubsub.Message) interface{}) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case message, open := <-messages:
+			if !open {
+				return // the subscriber is too slow and has been disconnected
+			}
+			if event := event(message); event != nil {
+				data, _ := json.Marshal(event)
+				fmt.Fprintf(w, "data: %s\n\n", data)
+			}
+		}
+		flusher.Flush()
+	}
+}
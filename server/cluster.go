@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/izhamoidsin/gedis/raft"
+)
+
+// clusterTimeout limits the time a request waits for its entry to be committed
+const clusterTimeout = 5 * time.Second
+
+// SetRaftNode call enables cluster mode: writes go through /cluster/entries endpoints and are replicated
+// by the node, other writes are rejected. RPCs of the node are served on /raft/ paths
+func (server *GedisServer) SetRaftNode(node *raft.Node) {
+	server.node = node
+}
+
+// rejectWrites call tells if the request writes the storage bypassing the cluster. Only writes of string values
+// are replicated through the log, so the rest of the writes are not served in cluster mode at all
+func (server *GedisServer) rejectWrites(r *http.Request) bool {
+	return server.node != nil && isWrite(r) &&
+		!strings.HasPrefix(r.URL.Path, "/cluster/") && !strings.HasPrefix(r.URL.Path, "/raft/")
+}
+
+// respondWithClusterError call redirects the client to the leader if the node is not the one
+func (server *GedisServer) respondWithClusterError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case raft.ErrNotLeader:
+		if leader := server.node.Leader(); leader != "" {
+			// node IDs are their addresses
+			http.Redirect(w, r, "http://"+leader+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		} else {
+			http.Error(w, "There is no leader at the moment", http.StatusServiceUnavailable)
+		}
+	case raft.ErrLeadershipLost, raft.ErrLeaderNotReady, raft.ErrStopped, context.DeadlineExceeded:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case raft.ErrMembershipChange:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) clusterEnabled(w http.ResponseWriter) bool {
+	if server.node == nil {
+		http.Error(w, "Cluster mode is disabled", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// getClusterItem handler reads the entry after the barrier on the leader, so the read is linearizable.
+// With `stale=true` the node responds with its own state at once
+func (server *GedisServer) getClusterItem(w http.ResponseWriter, r *http.Request) {
+	if !server.clusterEnabled(w) {
+		return
+	}
+	if r.URL.Query().Get("stale") != "true" {
+		ctx, cancel := context.WithTimeout(r.Context(), clusterTimeout)
+		defer cancel()
+		if err := server.node.Barrier(ctx); err != nil {
+			server.respondWithClusterError(w, r, err)
+			return
+		}
+	}
+	key, _, _ := getPathVars(r)
+	if val, ok := server.storage.GetValueByKey(key); ok {
+		respondWithEntry(w, val)
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+// putClusterItem handler stores a string value. `nx=true` stores it only if the key is absent,
+// `prev` param stores it only if the current value is equal to it. 412 is returned if the condition is not met
+func (server *GedisServer) putClusterItem(w http.ResponseWriter, r *http.Request) {
+	if !server.clusterEnabled(w) {
+		return
+	}
+	key, _, _ := getPathVars(r)
+	ttl, err := getTTL(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var value string
+	if err := decodeJSONRequestBody(r, &value); err != nil {
+		http.Error(w, "Value should be a string", http.StatusBadRequest)
+		return
+	}
+
+	command := raft.Command{Op: raft.OpSet, Key: key, Value: value, TTL: ttl}
+	query := r.URL.Query()
+	if query.Get("nx") == "true" {
+		command.Op = raft.OpSetIfAbsent
+	} else if _, conditional := query["prev"]; conditional {
+		command.Op = raft.OpCompareAndSwap
+		command.Prev = query.Get("prev")
+	}
+	server.proposeClusterCommand(w, r, command, http.StatusPreconditionFailed)
+}
+
+// deleteClusterItem handler removes the entry, only if its value is equal to `prev` param if it is passed
+func (server *GedisServer) deleteClusterItem(w http.ResponseWriter, r *http.Request) {
+	if !server.clusterEnabled(w) {
+		return
+	}
+	key, _, _ := getPathVars(r)
+	command := raft.Command{Op: raft.OpDelete, Key: key}
+	notApplied := http.StatusNotFound
+	if prev, conditional := r.URL.Query()["prev"]; conditional {
+		command.Op = raft.OpCompareAndDelete
+		command.Prev = prev[0]
+		notApplied = http.StatusPreconditionFailed
+	}
+	server.proposeClusterCommand(w, r, command, notApplied)
+}
+
+func (server *GedisServer) proposeClusterCommand(w http.ResponseWriter, r *http.Request, command raft.Command, notApplied int) {
+	ctx, cancel := context.WithTimeout(r.Context(), clusterTimeout)
+	defer cancel()
+	applied, err := server.node.Propose(ctx, command)
+	switch {
+	case err != nil:
+		server.respondWithClusterError(w, r, err)
+	case !applied:
+		w.WriteHeader(notApplied)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (server *GedisServer) clusterInfo(w http.ResponseWriter, r *http.Request) {
+	if !server.clusterEnabled(w) {
+		return
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(server.node.Status())
+}
+
+// changeClusterMembers handler adds (PUT) or removes (DELETE) the member, the leader is the one to do it
+func (server *GedisServer) changeClusterMembers(w http.ResponseWriter, r *http.Request) {
+	if !server.clusterEnabled(w) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	ctx, cancel := context.WithTimeout(r.Context(), clusterTimeout)
+	defer cancel()
+	var err error
+	if r.Method == http.MethodPut {
+		err = server.node.AddMember(ctx, id)
+	} else {
+		err = server.node.RemoveMember(ctx, id)
+	}
+	if err != nil {
+		server.respondWithClusterError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *GedisServer) raftRPC(w http.ResponseWriter, r *http.Request) {
+	if server.clusterEnabled(w) {
+		server.node.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// increment handler adds a number passed as a body (1 if there is no body) to the counter
+func (server *GedisServer) increment(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	delta := json.Number("1")
+	if r.ContentLength != 0 {
+		if err := decodeJSONRequestBody(r, &delta); err != nil {
+			http.Error(w, "Increment should be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value, err := incrementBy(server.storage, key, delta); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(value)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// incrementBy call adds numbers with fraction or exponent as floats, others as integers
+func incrementBy(counters storage.CounterStorage, key string, delta json.Number) (interface{}, error) {
+	if strings.ContainsAny(delta.String(), ".eE") {
+		floatDelta, err := delta.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return counters.IncrementByFloat(key, floatDelta)
+	}
+	intDelta, err := delta.Int64()
+	if err != nil {
+		return nil, err
+	}
+	return counters.IncrementBy(key, intDelta)
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// getDictEntries handler returns either all the entries of the dictionary,
+// its sorted keys (view=keys) or its length (view=length)
+func (server *GedisServer) getDictEntries(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	var result interface{}
+	var err error
+	switch r.URL.Query().Get("view") {
+	case "":
+		result, err = server.storage.GetDictEntries(key)
+	case "keys":
+		result, err = server.storage.GetDictKeys(key)
+	case "length":
+		result, err = server.storage.GetDictLength(key)
+	default:
+		http.Error(w, "View should be either keys or length", http.StatusBadRequest)
+		return
+	}
+
+	if err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(result)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) checkDictEntryPresence(w http.ResponseWriter, r *http.Request) {
+	key, subKey, _ := getPathVars(r)
+	if exists, err := server.storage.DictEntryExists(key, subKey); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+	} else if !exists {
+		http.NotFound(w, r)
+	}
+}
+
+func (server *GedisServer) setDictEntry(w http.ResponseWriter, r *http.Request) {
+	key, subKey, _ := getPathVars(r)
+	var str string
+	if err := decodeJSONRequestBody(r, &str); err != nil {
+		http.Error(w, "Dictionary value should be a string", http.StatusBadRequest)
+		return
+	}
+
+	if created, err := server.storage.SetDictEntry(key, subKey, str); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+	} else if created {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (server *GedisServer) deleteDictEntry(w http.ResponseWriter, r *http.Request) {
+	key, subKey, _ := getPathVars(r)
+	if deleted, err := server.storage.DeleteDictEntry(key, subKey); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+	} else if deleted {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		http.NotFound(w, r)
+	}
+}
@@ -7,25 +7,19 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/izhamoidsin/gedis/storage"
 )
 
-func parseJSONFormRequestBody(r *http.Request) (storage.Storable, error) {
+// parseStorable call accepts a string, an array of strings or a dictionary of strings
+func parseStorable(body []byte) (storage.Storable, error) {
 	var luckyString string
 	var luckyArray []string
 	var luckyDict map[string]string
 
-	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
-	if err != nil {
-		panic(err)
-	}
-	if err := r.Body.Close(); err != nil {
-		panic(err)
-	}
-
 	if err := json.Unmarshal(body, &luckyString); err == nil {
 		return luckyString, nil
 	}
@@ -41,6 +35,21 @@ func parseJSONFormRequestBody(r *http.Request) (storage.Storable, error) {
 	return nil, errors.New("Entity is unprocessable")
 }
 
+// decodeJSONRequestBody call is used when the body is neither a string nor an array or a dictionary of strings
+func decodeJSONRequestBody(r *http.Request, v interface{}) error {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		return err
+	}
+	if err := r.Body.Close(); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.New("Entity is unprocessable")
+	}
+	return nil
+}
+
 func getPathVars(r *http.Request) (key string, subKey string, index int) {
 	vars := mux.Vars(r)
 	key = vars["key"]
@@ -58,7 +67,63 @@ func respondWithJSON(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 }
 
-func respondWithExpireIn(w http.ResponseWriter) {
-	// TODO add TTL based header, e.g. Expire-At or Expire-In
-	w.Header().Set("Expire-At", "")
+// getIntQuery call parses integer query param falling back to the default value if it is absent
+func getIntQuery(r *http.Request, name string, defaultValue int) (int, error) {
+	valueStr := r.URL.Query().Get(name)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return 0, errors.New("Query param " + name + " should be an integer")
+	}
+	return value, nil
+}
+
+// errorStatus call maps an error of a write operation to the response status code
+func errorStatus(err error) int {
+	if err == storage.ErrOutOfMemory {
+		return http.StatusInsufficientStorage
+	}
+	return http.StatusBadRequest
+}
+
+// getTTL call extracts per-key TTL from `ttl` query param or `Expire-In` header.
+// Both accept either number of seconds or go duration string (e.g. 1m30s).
+// Zero duration is returned when TTL is not specified
+func getTTL(r *http.Request) (time.Duration, error) {
+	ttlStr := r.URL.Query().Get("ttl")
+	if ttlStr == "" {
+		ttlStr = r.Header.Get("Expire-In")
+	}
+	return parseTTL(ttlStr)
+}
+
+func parseTTL(ttlStr string) (time.Duration, error) {
+	if ttlStr == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(ttlStr); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if ttl, err := time.ParseDuration(ttlStr); err == nil && ttl > 0 {
+		return ttl, nil
+	}
+	return 0, errors.New("TTL should be a positive number of seconds or a duration")
+}
+
+// respondWithExpireIn call should be done before the body is written
+func respondWithExpireIn(w http.ResponseWriter, val *storage.StorableWithMeta) {
+	expireIn := val.ExpireIn()
+	if expireIn < 0 {
+		expireIn = 0
+	}
+	w.Header().Set("Expire-At", val.ExpireAt().UTC().Format(http.TimeFormat))
+	w.Header().Set("Expire-In", strconv.FormatInt(int64(expireIn/time.Second), 10))
+}
+
+// respondWithEntryType call lets clients distinguish sets from lists, as both are encoded as JSON arrays
+func respondWithEntryType(w http.ResponseWriter, val *storage.StorableWithMeta) {
+	w.Header().Set("Entry-Type", storage.TypeOf(val.Entity))
 }
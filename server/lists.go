@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// isHeadSide call checks `side` query param of push & pop requests, right side is the default one
+func isHeadSide(r *http.Request) (bool, error) {
+	return parseSide(r.URL.Query().Get("side"))
+}
+
+func parseSide(side string) (bool, error) {
+	switch side {
+	case "", "right":
+		return false, nil
+	case "left":
+		return true, nil
+	}
+	return false, errors.New("Side should be either left or right")
+}
+
+// parseListElements call accepts either a single string or an array of them
+func parseListElements(r *http.Request) ([]string, error) {
+	var body json.RawMessage
+	if err := decodeJSONRequestBody(r, &body); err != nil {
+		return nil, err
+	}
+	var element string
+	if err := json.Unmarshal(body, &element); err == nil {
+		return []string{element}, nil
+	}
+	var elements []string
+	if err := json.Unmarshal(body, &elements); err == nil {
+		return elements, nil
+	}
+	return nil, errors.New("List elements should be a string or an array of strings")
+}
+
+func (server *GedisServer) pushToList(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	toHead, err := isHeadSide(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	elements, err := parseListElements(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if length, err := server.storage.PushToList(key, toHead, elements...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(length)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) popFromList(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	fromHead, err := isHeadSide(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if element, exists, err := server.storage.PopFromList(key, fromHead); err == nil && exists {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(element)
+	} else if err == nil {
+		http.NotFound(w, r)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) getListRange(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	start, err := getIntQuery(r, "start", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stop, err := getIntQuery(r, "stop", -1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if elements, err := server.storage.GetListRange(key, start, stop); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(elements)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) setListElement(w http.ResponseWriter, r *http.Request) {
+	key, _, index := getPathVars(r)
+	var element string
+	if err := decodeJSONRequestBody(r, &element); err != nil {
+		http.Error(w, "List element should be a string", http.StatusBadRequest)
+		return
+	}
+
+	if err := server.storage.SetListElement(key, index, element); err == nil {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) trimList(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	start, err := getIntQuery(r, "start", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stop, err := getIntQuery(r, "stop", -1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := server.storage.TrimList(key, start, stop); err == nil {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) getListLength(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	if length, err := server.storage.GetListLength(key); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(length)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/pubsub"
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// keyspaceChannelPrefix is a prefix of the channels changes of keys are published to, like Redis
+// does every change is published to `__keyspace__:{key}` channel with the type of the change as the payload
+const keyspaceChannelPrefix = "__keyspace__:"
+
+// reservedChannelPrefixes are prefixes of the channels only the server publishes to
+var reservedChannelPrefixes = []string{keyspaceChannelPrefix, "__keyevent__:"}
+
+// isReservedChannel call checks if the channel is kept for notifications of the server
+func isReservedChannel(channel string) bool {
+	for _, prefix := range reservedChannelPrefixes {
+		if strings.HasPrefix(channel, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var mutationTypes = []storage.MutationType{
+	storage.MutationAppend,
+	storage.MutationUpdate,
+	storage.MutationDelete,
+	storage.MutationExpire,
+	storage.MutationEvict,
+}
+
+// keyspaceEvent is a single notification streamed to the subscribers
+type keyspaceEvent struct {
+	Key   string               `json:"key"`
+	Event storage.MutationType `json:"event"`
+}
+
+// notifyKeyspace is a mutation listener of the storage. It never blocks as publishing does not
+func (server *GedisServer) notifyKeyspace(mutation storage.Mutation) {
+	server.broker.Publish(keyspaceChannelPrefix+mutation.Key, string(mutation.Type))
+}
+
+// parseMutationTypes call accepts comma separated list of types, all of them are allowed if the list is empty
+func parseMutationTypes(list string) (map[storage.MutationType]bool, error) {
+	allowed := make(map[storage.MutationType]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		known := false
+		for _, mutationType := range mutationTypes {
+			known = known || string(mutationType) == name
+		}
+		if !known {
+			return nil, errors.New("Unknown event " + name)
+		}
+		allowed[storage.MutationType(name)] = true
+	}
+	if len(allowed) == 0 {
+		for _, mutationType := range mutationTypes {
+			allowed[mutationType] = true
+		}
+	}
+	return allowed, nil
+}
+
+// streamNotifications handler streams changes of the keys matching `match` glob pattern (all keys by default)
+// as Server-Sent Events, optionally only the `events` types (comma separated) of them
+func (server *GedisServer) streamNotifications(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	match := r.URL.Query().Get("match")
+	if match == "" {
+		match = "*"
+	}
+	allowed, err := parseMutationTypes(r.URL.Query().Get("events"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	subscription, err := server.broker.SubscribePattern(keyspaceChannelPrefix + match)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer subscription.Close()
+
+	streamEvents(w, r, flusher, subscription.Messages, func(message pubsub.Message) interface{} {
+		if mutationType := storage.MutationType(message.Payload); allowed[mutationType] {
+			return keyspaceEvent{strings.TrimPrefix(message.Channel, keyspaceChannelPrefix), mutationType}
+		}
+		return nil
+	})
+}
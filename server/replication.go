@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// replicationStatus is reported by /admin/replication, follower fields are omitted by the leader
+type replicationStatus struct {
+	Role         string     `json:"role"`
+	Offset       uint64     `json:"offset"`
+	Followers    *int       `json:"followers,omitempty"`
+	Leader       string     `json:"leader,omitempty"`
+	Synced       *bool      `json:"synced,omitempty"`
+	LeaderOffset *uint64    `json:"leaderOffset,omitempty"`
+	Lag          *uint64    `json:"lag,omitempty"`
+	LastContact  *time.Time `json:"lastContact,omitempty"`
+}
+
+// SetReplica call makes the server a follower of the leader at leaderURL (e.g. `http://host:8081`).
+// Records of the leader should be applied to the replica by the caller, see client.Replicate.
+// Writes are redirected to the leader, so the follower serves reads only
+func (server *GedisServer) SetReplica(replica *storage.Replica, leaderURL string) {
+	server.replica = replica
+	server.leaderURL = strings.TrimSuffix(leaderURL, "/")
+}
+
+// isWrite call tells if the request changes the storage. Publishing is local to the server
+// as well as admin requests, so they are not considered as writes
+func isWrite(r *http.Request) bool {
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return false
+	case r.URL.Path == "/batch/get":
+		return false
+	case strings.HasPrefix(r.URL.Path, "/channels/") || strings.HasPrefix(r.URL.Path, "/admin/"):
+		return false
+	}
+	return true
+}
+
+// redirectWrites call wraps the handler, so a follower responds to writes with 307 Temporary Redirect
+// to the same path of the leader. Unlike 301 and 302 it makes clients repeat the method and the body.
+// In cluster mode writes bypassing the cluster are rejected
+func (server *GedisServer) redirectWrites(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server.replica != nil && isWrite(r) {
+			http.Redirect(w, r, server.leaderURL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+			return
+		}
+		if server.rejectWrites(r) {
+			http.Error(w, "Writes go through /cluster/entries in cluster mode", http.StatusNotImplemented)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// replicationStream handler sends a full sync of the storage followed by its mutations as Server-Sent Events
+// until the follower disconnects. Instead of heartbeat comments the current offset is sent
+func (server *GedisServer) replicationStream(w http.ResponseWriter, r *http.Request) {
+	if server.replica != nil {
+		// entries restored by a replica are not reported to its listeners, so they could not be streamed
+		http.Error(w, "Chained replication is not supported", http.StatusNotImplemented)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	replication := server.leaderLog(true)
+	if replication == nil {
+		http.Error(w, "Replication is not supported by the storage", http.StatusNotImplemented)
+		return
+	}
+	stream := replication.Follow()
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	send := func(record storage.ReplicationRecord) bool {
+		data, _ := json.Marshal(record)
+		_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+		return err == nil
+	}
+	if !stream.Sync(send) {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			send(stream.Ping())
+		case record, open := <-stream.Records:
+			if !open {
+				return // the follower is too slow and has to sync from scratch
+			}
+			if !send(record) {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// replicable call tells if the storage could be streamed to followers
+func (server *GedisServer) replicable() bool {
+	_, persistable := server.storage.(storage.PersistableStorage)
+	return persistable
+}
+
+// leaderLog call returns the replication log of the server, it is created on demand when the first follower
+// connects, so mutations are not encoded for nobody. Nil is returned if the storage could not be replicated
+func (server *GedisServer) leaderLog(create bool) *storage.ReplicationLog {
+	server.replicationLock.Lock()
+	defer server.replicationLock.Unlock()
+	if server.replication == nil && create && server.replicable() {
+		server.replication = storage.NewReplicationLog(server.storage.(storage.PersistableStorage))
+	}
+	return server.replication
+}
+
+func (server *GedisServer) replicationInfo(w http.ResponseWriter, r *http.Request) {
+	status := new(replicationStatus)
+	switch {
+	case server.replica != nil:
+		replica := server.replica.Status()
+		lag := replica.LeaderOffset - replica.Offset
+		status.Role = "follower"
+		status.Leader = server.leaderURL
+		status.Offset = replica.Offset
+		status.Synced = &replica.Synced
+		status.LeaderOffset = &replica.LeaderOffset
+		status.Lag = &lag
+		if !replica.LastContact.IsZero() {
+			status.LastContact = &replica.LastContact
+		}
+	case server.replicable():
+		followers := 0
+		if replication := server.leaderLog(false); replication != nil {
+			followers = replication.Followers()
+			status.Offset = replication.Offset()
+		}
+		status.Role = "leader"
+		status.Followers = &followers
+	default:
+		http.Error(w, "Replication is not supported by the storage", http.StatusNotImplemented)
+		return
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+const (
+	defaultScanCount = 10
+	maxScanCount     = 1000
+)
+
+// scanPage is a response of the scan. Cursor is a string as it does not fit into a JSON number
+type scanPage struct {
+	Cursor string   `json:"cursor"`
+	Keys   []string `json:"keys"`
+}
+
+// scanKeys handler visits `count` keys starting at `cursor` and returns the ones matching `match` glob pattern and of `type`
+// along with the cursor of the next page, which is "0" once the iteration is complete
+func (server *GedisServer) scanKeys(w http.ResponseWriter, r *http.Request) {
+	cursor := uint64(0)
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(cursorStr, 10, 64); err != nil {
+			http.Error(w, "Cursor should be a value returned by the previous scan", http.StatusBadRequest)
+			return
+		}
+	}
+	count, err := getIntQuery(r, "count", defaultScanCount)
+	if err == nil && (count < 1 || count > maxScanCount) {
+		err = errors.New("Count should be from 1 to " + strconv.Itoa(maxScanCount))
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := storage.ScanFilter{Match: r.URL.Query().Get("match"), Type: r.URL.Query().Get("type")}
+	if keys, next, err := server.storage.Scan(cursor, count, filter); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(scanPage{strconv.FormatUint(next, 10), keys})
+	} else {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
@@ -7,28 +7,57 @@ import (
 
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/izhamoidsin/gedis/pubsub"
+	"github.com/izhamoidsin/gedis/raft"
 	"github.com/izhamoidsin/gedis/storage"
 )
 
 // GedisServer ...
 type GedisServer struct {
-	startTime time.Time
-	storage   storage.Storage
+	startTime   time.Time
+	storage     storage.Storage
+	snapshotter *storage.Snapshotter
+	broker      *pubsub.Broker
+	replica     *storage.Replica
+	leaderURL   string
+	node        *raft.Node
+	slots       *slotState
+
+	// replication log of the leader is created when the first follower connects, see leaderLog
+	replicationLock sync.Mutex
+	replication     *storage.ReplicationLog
 }
 
 // CreateServer ...
-func CreateServer(storage storage.Storage) *GedisServer {
+func CreateServer(registry storage.Storage) *GedisServer {
 	server := new(GedisServer)
-	server.storage = storage
+	server.storage = registry
+	server.broker = pubsub.NewBroker()
+	if observable, ok := registry.(storage.ObservableStorage); ok {
+		observable.AddMutationListener(server.notifyKeyspace)
+	}
 	return server
 }
 
+// SetSnapshotter call enables /admin/snapshot endpoint
+func (server *GedisServer) SetSnapshotter(snapshotter *storage.Snapshotter) {
+	server.snapshotter = snapshotter
+}
+
 // StartSerever ...
 func (server *GedisServer) StartSerever(port int) error {
+	log.Println("Starting server @ port " + strconv.Itoa(port))
+	return http.ListenAndServe(":"+strconv.Itoa(port), server.Handler())
+}
+
+// Handler call creates a handler serving the API of the server, so a few servers
+// could run in a single process
+func (server *GedisServer) Handler() http.Handler {
 	server.startTime = time.Now()
 
 	// used gorilla mux router because it reduces boilerplate code of http methods & paths matching
@@ -40,12 +69,55 @@ func (server *GedisServer) StartSerever(port int) error {
 	router.HandleFunc("/entries/{key}", server.putItem).Methods(http.MethodPut)
 	router.HandleFunc("/entries/{key}", server.appendItem).Methods(http.MethodPost)
 	router.HandleFunc("/entries/{key}", server.deleteItem).Methods(http.MethodDelete)
-	router.HandleFunc("/entries/{key}/elements/{ind}", server.getByNestedIndex).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/incr", server.increment).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/elements", server.getListRange).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/elements", server.pushToList).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/elements", server.popFromList).Methods(http.MethodDelete)
+	router.HandleFunc("/entries/{key}/elements/length", server.getListLength).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/elements/trim", server.trimList).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/elements/{index:-?[0-9]+}", server.getByNestedIndex).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/elements/{index:-?[0-9]+}", server.setListElement).Methods(http.MethodPut)
+	router.HandleFunc("/entries/{key}/entries", server.getDictEntries).Methods(http.MethodGet)
 	router.HandleFunc("/entries/{key}/entries/{subKey}", server.getByNestedKey).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/entries/{subKey}", server.checkDictEntryPresence).Methods(http.MethodHead)
+	router.HandleFunc("/entries/{key}/entries/{subKey}", server.setDictEntry).Methods(http.MethodPut)
+	router.HandleFunc("/entries/{key}/entries/{subKey}", server.deleteDictEntry).Methods(http.MethodDelete)
+	router.HandleFunc("/entries/{key}/members", server.getSetMembers).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/members", server.addToSet).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/members", server.removeFromSet).Methods(http.MethodDelete)
+	router.HandleFunc("/entries/{key}/members/pop", server.popFromSet).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/members/{subKey}", server.checkSetMembership).Methods(http.MethodHead)
+	router.HandleFunc("/entries/{key}/scores", server.getSortedSetRange).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/scores", server.addToSortedSet).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/scores", server.removeFromSortedSet).Methods(http.MethodDelete)
+	router.HandleFunc("/entries/{key}/scores/pop", server.popFromSortedSet).Methods(http.MethodPost)
+	router.HandleFunc("/entries/{key}/scores/{subKey}", server.getSortedSetMember).Methods(http.MethodGet)
+	router.HandleFunc("/entries/{key}/scores/{subKey}/increment", server.incrementSortedSetScore).Methods(http.MethodPost)
+	router.HandleFunc("/sets/{operation}", server.combineSets).Methods(http.MethodGet)
+	router.HandleFunc("/sets/{operation}", server.storeCombinedSets).Methods(http.MethodPost)
+	router.HandleFunc("/batch/get", server.batchGet).Methods(http.MethodPost)
+	router.HandleFunc("/batch/set", server.batchSet).Methods(http.MethodPost)
+	router.HandleFunc("/batch/delete", server.batchDelete).Methods(http.MethodPost)
+	router.HandleFunc("/tx", server.execTransaction).Methods(http.MethodPost)
+	router.HandleFunc("/channels/{channel}", server.publish).Methods(http.MethodPost)
+	router.HandleFunc("/channels/{channel}", server.subscribe).Methods(http.MethodGet)
+	router.HandleFunc("/notifications", server.streamNotifications).Methods(http.MethodGet)
+	router.HandleFunc("/admin/snapshot", server.snapshotInfo).Methods(http.MethodGet)
+	router.HandleFunc("/admin/snapshot", server.takeSnapshot).Methods(http.MethodPost)
+	router.HandleFunc("/admin/replication", server.replicationInfo).Methods(http.MethodGet)
+	router.HandleFunc("/replication", server.replicationStream).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/entries/{key}", server.getClusterItem).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/entries/{key}", server.putClusterItem).Methods(http.MethodPut)
+	router.HandleFunc("/cluster/entries/{key}", server.deleteClusterItem).Methods(http.MethodDelete)
+	router.HandleFunc("/admin/cluster", server.clusterInfo).Methods(http.MethodGet)
+	router.HandleFunc("/admin/cluster/members/{id}", server.changeClusterMembers).Methods(http.MethodPut, http.MethodDelete)
+	router.HandleFunc("/raft/{rpc}", server.raftRPC).Methods(http.MethodPost)
+	router.HandleFunc("/slots", server.slotMap).Methods(http.MethodGet)
+	router.HandleFunc("/admin/slots", server.setSlotMap).Methods(http.MethodPut)
+	router.HandleFunc("/admin/slots/{slot}/migrate", server.migrateSlot).Methods(http.MethodPost)
+	router.HandleFunc("/admin/slots/{slot}/import", server.importSlot).Methods(http.MethodPost)
 
-	http.Handle("/", router)
-	log.Println("Starting server @ port " + strconv.Itoa(port))
-	return http.ListenAndServe(":"+strconv.Itoa(port), nil)
+	return server.routeSlots(server.redirectWrites(router))
 }
 
 func (server *GedisServer) heartbeat(w http.ResponseWriter, r *http.Request) {
@@ -53,19 +125,44 @@ func (server *GedisServer) heartbeat(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w)
 }
 
+// keys handler responds with all the keys at once unless any of the scan params is passed, see scanKeys
 func (server *GedisServer) keys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if _, scan := query["cursor"]; scan || query.Get("count") != "" || query.Get("match") != "" || query.Get("type") != "" {
+		server.scanKeys(w, r)
+		return
+	}
 	keys := server.storage.GetAllKeys()
 	json.NewEncoder(w).Encode(keys)
 	respondWithJSON(w)
 }
 
+// putItem handler updates existing entry unless If-Match or If-None-Match header is passed.
+// Conditional PUT creates the entry if the precondition allows and responds with its new ETag
 func (server *GedisServer) putItem(w http.ResponseWriter, r *http.Request) {
 	key, _, _ := getPathVars(r)
-	if newValue, err := parseJSONFormRequestBody(r); err == nil {
-		if operationForbidden := server.storage.UpdateValueByKey(key, newValue); operationForbidden == nil {
+	ttl, err := getTTL(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	precondition, err := getPrecondition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if newValue, err := parseEntryRequestBody(r); err == nil && precondition != nil {
+		if entry, err := server.storage.SetValueByKeyIf(key, newValue, ttl, precondition); err == nil {
+			respondWithVersion(w, entry)
 			w.WriteHeader(http.StatusNoContent)
 		} else {
-			http.Error(w, operationForbidden.Error(), http.StatusBadRequest)
+			respondWithWriteError(w, entry, err)
+		}
+	} else if err == nil {
+		if operationForbidden := server.storage.UpdateValueByKeyWithTTL(key, newValue, ttl); operationForbidden == nil {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			http.Error(w, operationForbidden.Error(), errorStatus(operationForbidden))
 		}
 	} else {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -74,12 +171,17 @@ func (server *GedisServer) putItem(w http.ResponseWriter, r *http.Request) {
 
 func (server *GedisServer) appendItem(w http.ResponseWriter, r *http.Request) {
 	key, _, _ := getPathVars(r)
-	if newValue, err := parseJSONFormRequestBody(r); err == nil {
-		if operationForbidden := server.storage.AppendNewValue(key, newValue); operationForbidden == nil {
+	ttl, err := getTTL(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if newValue, err := parseEntryRequestBody(r); err == nil {
+		if operationForbidden := server.storage.AppendNewValueWithTTL(key, newValue, ttl); operationForbidden == nil {
 			w.WriteHeader(http.StatusCreated)
 			// TODO add Location header & make response compliant to rfc2616
 		} else {
-			http.Error(w, operationForbidden.Error(), http.StatusBadRequest)
+			http.Error(w, operationForbidden.Error(), errorStatus(operationForbidden))
 		}
 	} else {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -88,33 +190,66 @@ func (server *GedisServer) appendItem(w http.ResponseWriter, r *http.Request) {
 
 func (server *GedisServer) deleteItem(w http.ResponseWriter, r *http.Request) {
 	key, _, _ := getPathVars(r)
-	server.storage.DeleteValueByKey(key) // TODO handle deleted flag
+	precondition, err := getPrecondition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if precondition != nil {
+		if current, err := server.storage.DeleteValueByKeyIf(key, precondition); err != nil {
+			respondWithWriteError(w, current, err)
+			return
+		}
+	} else {
+		server.storage.DeleteValueByKey(key) // TODO handle deleted flag
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (server *GedisServer) chechItemPresense(w http.ResponseWriter, r *http.Request) {
 	key, _, _ := getPathVars(r)
-	if _, ok := server.storage.GetValueByKey(key); ok {
+	if val, ok := server.storage.GetValueByKey(key); ok {
+		respondWithExpireIn(w, val)
+		respondWithEntryType(w, val)
+		respondWithVersion(w, val)
 		return
 	}
 	http.NotFound(w, r)
 }
 
+// getItem handler waits for a change of the entry if `watch=true` query param is passed, see watchItem
 func (server *GedisServer) getItem(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("watch") == "true" {
+		server.watchItem(w, r)
+		return
+	}
 	key, _, _ := getPathVars(r)
 	if val, ok := server.storage.GetValueByKey(key); ok {
-		json.NewEncoder(w).Encode(val.Entity)
-		respondWithJSON(w)
+		respondWithVersion(w, val)
+		if notModified(r, val) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		respondWithEntry(w, val)
 	} else {
 		http.NotFound(w, r)
 	}
 }
 
+// respondWithEntry call writes the entry along with its meta headers except the version
+func respondWithEntry(w http.ResponseWriter, val *storage.StorableWithMeta) {
+	respondWithJSON(w)
+	respondWithExpireIn(w, val)
+	respondWithEntryType(w, val)
+	json.NewEncoder(w).Encode(val.Entity)
+}
+
 func (server *GedisServer) getByNestedKey(w http.ResponseWriter, r *http.Request) {
 	key, subKey, _ := getPathVars(r)
 	if val, exists, error := server.storage.GetNestedValueByKeyAndSubkey(key, subKey); error == nil && exists {
-		json.NewEncoder(w).Encode(val.Entity)
 		respondWithJSON(w)
+		respondWithExpireIn(w, val)
+		json.NewEncoder(w).Encode(val.Entity)
 	} else if !exists && error == nil {
 		http.NotFound(w, r)
 	} else { // TODO identify bad op error
@@ -126,8 +261,9 @@ func (server *GedisServer) getByNestedIndex(w http.ResponseWriter, r *http.Reque
 	key, _, index := getPathVars(r)
 	// TODO handle errors
 	if val, exists, error := server.storage.GetNestedValueByKeyAndIndex(key, index); error == nil && exists {
-		json.NewEncoder(w).Encode(val.Entity)
 		respondWithJSON(w)
+		respondWithExpireIn(w, val)
+		json.NewEncoder(w).Encode(val.Entity)
 	} else if !exists && error == nil {
 		http.NotFound(w, r)
 	} else { // TODO identify bad op error
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// parseEntryRequestBody call parses the whole entry of the type passed as `type` query param
+func parseEntryRequestBody(r *http.Request) (storage.Storable, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Body.Close(); err != nil {
+		return nil, err
+	}
+	return parseEntry(body, r.URL.Query().Get("type"))
+}
+
+// parseEntry call parses the whole entry, arrays are stored as sets if the type is `set`.
+// Sorted sets (`zset`) are passed as arrays of scored members
+func parseEntry(body []byte, entryType string) (storage.Storable, error) {
+	if entryType == "zset" {
+		sortedSet := new(storage.SortedSet)
+		if err := json.Unmarshal(body, sortedSet); err != nil {
+			return nil, errors.New("Sorted set should be an array of scored members")
+		}
+		return sortedSet, nil
+	}
+
+	value, err := parseStorable(body)
+	if err != nil {
+		return nil, err
+	}
+	switch entryType {
+	case "":
+		return value, nil
+	case "set":
+		if members, isArray := value.([]string); isArray {
+			return storage.NewSet(members...), nil
+		}
+		return nil, errors.New("Set should be an array of strings")
+	}
+	return nil, errors.New("Type should be either omitted, set or zset")
+}
+
+// getSetMembers handler returns either sorted members of the set,
+// a number of random ones (random=N) or cardinality of the set (view=length)
+func (server *GedisServer) getSetMembers(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	random, err := getIntQuery(r, "random", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result interface{}
+	switch view := r.URL.Query().Get("view"); {
+	case view == "length":
+		result, err = server.storage.GetSetCardinality(key)
+	case view != "":
+		http.Error(w, "View should be either omitted or length", http.StatusBadRequest)
+		return
+	case random > 0:
+		result, err = server.storage.GetRandomSetMembers(key, random)
+	default:
+		result, err = server.storage.GetSetMembers(key)
+	}
+
+	if err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(result)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// addToSet handler responds with the number of members which were not in the set before
+func (server *GedisServer) addToSet(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	members, err := parseListElements(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if added, err := server.storage.AddToSet(key, members...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(added)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// removeFromSet handler responds with the number of members which were in the set
+func (server *GedisServer) removeFromSet(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	members, err := parseListElements(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if removed, err := server.storage.RemoveFromSet(key, members...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(removed)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+func (server *GedisServer) checkSetMembership(w http.ResponseWriter, r *http.Request) {
+	key, member, _ := getPathVars(r)
+	if isMember, err := server.storage.IsSetMember(key, member); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+	} else if !isMember {
+		http.NotFound(w, r)
+	}
+}
+
+func (server *GedisServer) popFromSet(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	count, err := getIntQuery(r, "count", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if popped, err := server.storage.PopRandomSetMembers(key, count); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(popped)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// combineSets handler responds with sorted members of the combination of sets passed as `key` query params
+func (server *GedisServer) combineSets(w http.ResponseWriter, r *http.Request) {
+	operation := storage.SetOperation(mux.Vars(r)["operation"])
+	if members, err := server.storage.CombineSets(operation, r.URL.Query()["key"]...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(members)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// storeCombinedSets handler stores the combination into `destination` key and responds with its cardinality
+func (server *GedisServer) storeCombinedSets(w http.ResponseWriter, r *http.Request) {
+	operation := storage.SetOperation(mux.Vars(r)["operation"])
+	destination := r.URL.Query().Get("destination")
+	if destination == "" {
+		http.Error(w, "Destination should be specified", http.StatusBadRequest)
+		return
+	}
+
+	if cardinality, err := server.storage.StoreCombinedSets(operation, destination, r.URL.Query()["key"]...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(cardinality)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
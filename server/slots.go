@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/izhamoidsin/gedis/slots"
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+const (
+	// slotStripes is the number of locks slots are striped over
+	slotStripes = 256
+	// migrationTimeout limits the time the entries of a slot are sent to the new owner
+	migrationTimeout = time.Minute
+)
+
+// migrationClient sends the entries of migrating slots, unlike http.DefaultClient it does not wait forever
+var migrationClient = &http.Client{Timeout: migrationTimeout}
+
+// slotMigration is reported by the migration endpoint
+type slotMigration struct {
+	Slot int    `json:"slot"`
+	Node string `json:"node"`
+	Keys int    `json:"keys"`
+}
+
+// slotState holds the slot map of the server along with the locks keeping entries from being changed
+// while their slot migrates. Requests to a slot share its lock, a migration holds it exclusively only
+// to mark the slot as migrating and to hand it over: writes to a migrating slot are rejected, so its
+// entries are neither changed while they are collected nor while they are sent to the new owner
+type slotState struct {
+	self   string
+	owners *slots.Map
+	locks  [slotStripes]sync.RWMutex
+	// migrating slots reject writes, every flag is guarded by the lock of its slot
+	migrating [slots.Count]bool
+}
+
+// SetSlots call enables slot mode: the server serves only the keys of the slots the map assigns to self
+// (the address of the server as other nodes and clients reach it, e.g. `host:8081`) and redirects requests
+// to other keys to their owners. Multi-key requests are rejected as keys could belong to different nodes
+func (server *GedisServer) SetSlots(self string, owners *slots.Map) {
+	server.slots = &slotState{self: self, owners: owners}
+}
+
+func (state *slotState) lock(slot int) *sync.RWMutex {
+	return &state.locks[slot%slotStripes]
+}
+
+// keyOfPath call extracts the key of the /entries/{key} paths
+func keyOfPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/entries/") {
+		return "", false
+	}
+	key := strings.SplitN(strings.TrimPrefix(path, "/entries/"), "/", 2)[0]
+	return key, key != ""
+}
+
+// isMultiKey call tells if the request touches a few keys at once
+func isMultiKey(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/batch/") || strings.HasPrefix(r.URL.Path, "/sets/") || r.URL.Path == "/tx"
+}
+
+// routeSlots call wraps the handler, so in slot mode requests to keys of other nodes are responded with
+// 307 Temporary Redirect to the same path of the owner. The slot is reported in the Gedis-Slot header,
+// so clients could learn the new owner. Writes to a migrating slot are responded with 503 Service Unavailable.
+// Watching requests are checked but do not lock the slot as they last long and a migration would wait for them
+func (server *GedisServer) routeSlots(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server.slots == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if isMultiKey(r) {
+			http.Error(w, "Multi-key requests are not supported in slot mode", http.StatusNotImplemented)
+			return
+		}
+		key, ok := keyOfPath(r.URL.Path)
+		if !ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		slot := slots.KeySlot(key)
+		if r.URL.Query().Get("watch") != "true" {
+			lock := server.slots.lock(slot)
+			lock.RLock()
+			defer lock.RUnlock()
+		}
+		switch owner := server.slots.owners.Owner(slot); {
+		case owner == server.slots.self && isWrite(r) && server.slots.migrating[slot]:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Slot "+strconv.Itoa(slot)+" is migrating", http.StatusServiceUnavailable)
+		case owner == server.slots.self:
+			handler.ServeHTTP(w, r)
+		case owner == "":
+			http.Error(w, "Slot "+strconv.Itoa(slot)+" is not assigned", http.StatusServiceUnavailable)
+		default:
+			w.Header().Set(slots.Header, strconv.Itoa(slot))
+			http.Redirect(w, r, requestScheme(r)+"://"+owner+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		}
+	})
+}
+
+// requestScheme call returns the scheme the request is served with, nodes of the slot map are expected to share it
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func (server *GedisServer) slotsEnabled(w http.ResponseWriter) bool {
+	if server.slots == nil {
+		http.Error(w, "Slot mode is disabled", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+func getSlot(r *http.Request) (int, error) {
+	slot, err := strconv.Atoi(mux.Vars(r)["slot"])
+	if err != nil || slot < 0 || slot >= slots.Count {
+		return 0, errors.New("Slot should be an integer from 0 to " + strconv.Itoa(slots.Count-1))
+	}
+	return slot, nil
+}
+
+// slotMap handler responds with the slot ranges and their owners
+func (server *GedisServer) slotMap(w http.ResponseWriter, r *http.Request) {
+	if !server.slotsEnabled(w) {
+		return
+	}
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(server.slots.owners.Ranges())
+}
+
+// setSlotMap handler replaces the slot map of the node, e.g. to let it know about migrations between other nodes
+func (server *GedisServer) setSlotMap(w http.ResponseWriter, r *http.Request) {
+	if !server.slotsEnabled(w) {
+		return
+	}
+	var ranges []slots.Range
+	if err := decodeJSONRequestBody(r, &ranges); err != nil {
+		http.Error(w, "Slot map should be an array of ranges", http.StatusBadRequest)
+		return
+	}
+	if err := server.slots.owners.SetRanges(ranges); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// migrateSlot handler moves the entries of the slot to the node passed in `to` param and makes it the owner.
+// Writes to the slot are rejected during the migration, so no entry is lost, reads are served by the node
+// until the new owner has the entries. The node keeps redirecting requests to the new owner, other nodes
+// learn about it with PUT /admin/slots
+func (server *GedisServer) migrateSlot(w http.ResponseWriter, r *http.Request) {
+	if !server.slotsEnabled(w) {
+		return
+	}
+	persistable, ok := server.storage.(storage.PersistableStorage)
+	if !ok {
+		http.Error(w, "Migration is not supported by the storage", http.StatusNotImplemented)
+		return
+	}
+	slot, err := getSlot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target := r.URL.Query().Get("to")
+	if target == "" {
+		http.Error(w, "Target node should be passed in `to` param", http.StatusBadRequest)
+		return
+	}
+
+	keys, data, status, err := server.beginMigration(persistable, slot)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), migrationTimeout)
+	defer cancel()
+	err = importTo(ctx, requestScheme(r)+"://"+target, slot, data)
+
+	lock := server.slots.lock(slot)
+	lock.Lock()
+	server.slots.migrating[slot] = false
+	if err == nil {
+		server.slots.owners.Assign(slot, slot, target)
+		for _, key := range keys {
+			server.storage.DeleteValueByKey(key)
+		}
+	}
+	lock.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	respondWithJSON(w)
+	json.NewEncoder(w).Encode(slotMigration{slot, target, len(keys)})
+}
+
+// beginMigration call marks the slot as migrating and dumps its entries. The lock of the slot is held only
+// to mark it, so requests changing the entries are done before and the following ones are rejected.
+// The status of the response is returned along with the error
+func (server *GedisServer) beginMigration(persistable storage.PersistableStorage, slot int) ([]string, *bytes.Buffer, int, error) {
+	lock := server.slots.lock(slot)
+	lock.Lock()
+	if owner := server.slots.owners.Owner(slot); owner != server.slots.self {
+		lock.Unlock()
+		return nil, nil, http.StatusConflict, errors.New("Slot " + strconv.Itoa(slot) + " is owned by " + owner)
+	}
+	if server.slots.migrating[slot] {
+		lock.Unlock()
+		return nil, nil, http.StatusConflict, errors.New("Slot " + strconv.Itoa(slot) + " is migrating already")
+	}
+	server.slots.migrating[slot] = true
+	lock.Unlock()
+
+	var keys []string
+	var data bytes.Buffer
+	err := storage.WriteSnapshotOf(persistable, &data, func(key string) bool {
+		if slots.KeySlot(key) != slot {
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		lock.Lock()
+		server.slots.migrating[slot] = false
+		lock.Unlock()
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	return keys, &data, http.StatusOK, nil
+}
+
+// importTo call sends the entries of the slot to the node at the base URL
+func importTo(ctx context.Context, targetURL string, slot int, data io.Reader) error {
+	importURL := targetURL + "/admin/slots/" + strconv.Itoa(slot) + "/import"
+	request, err := http.NewRequest(http.MethodPost, importURL, data)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	response, err := migrationClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return errors.New("Import to " + targetURL + " failed with " + strconv.Itoa(response.StatusCode) + ": " + string(message))
+	}
+	return nil
+}
+
+// importSlot handler stores the entries of the migrating slot sent by its owner and makes the node the new one.
+// A failed import leaves the entries stored so far, the owner keeps the slot and could retry
+func (server *GedisServer) importSlot(w http.ResponseWriter, r *http.Request) {
+	if !server.slotsEnabled(w) {
+		return
+	}
+	slot, err := getSlot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := storage.ImportSnapshot(server.storage, r.Body); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	server.slots.owners.Assign(slot, slot, server.slots.self)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// memberInfo is a response of the sorted set member lookup
+type memberInfo struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+	Rank   int     `json:"rank"`
+}
+
+// isDescOrder call checks `order` query param, ascending order is the default one
+func isDescOrder(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("order") {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, errors.New("Order should be either asc or desc")
+}
+
+// parseScoreBound call parses Redis-like score bound: a number, -inf or +inf,
+// prefixed with `(` if the bound is exclusive
+func parseScoreBound(bound string, defaultValue float64) (float64, bool, error) {
+	if bound == "" {
+		return defaultValue, false, nil
+	}
+	exclusive := strings.HasPrefix(bound, "(")
+	score, err := strconv.ParseFloat(strings.TrimPrefix(bound, "("), 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false, errors.New("Score bound should be a number optionally prefixed with (")
+	}
+	return score, exclusive, nil
+}
+
+// getScoreRange call extracts `min` and `max` query params, false is returned if neither is passed
+func getScoreRange(r *http.Request) (storage.ScoreRange, bool, error) {
+	var scoreRange storage.ScoreRange
+	var err error
+	query := r.URL.Query()
+	if scoreRange.Min, scoreRange.MinExclusive, err = parseScoreBound(query.Get("min"), math.Inf(-1)); err != nil {
+		return scoreRange, false, err
+	}
+	if scoreRange.Max, scoreRange.MaxExclusive, err = parseScoreBound(query.Get("max"), math.Inf(1)); err != nil {
+		return scoreRange, false, err
+	}
+	return scoreRange, query.Get("min") != "" || query.Get("max") != "", nil
+}
+
+// getSortedSetRange handler returns members of the sorted set either by rank (`start` & `stop`)
+// or by score (`min` & `max` with optional `offset` & `count`) in the `order`.
+// Cardinality of the sorted set is returned for view=length
+func (server *GedisServer) getSortedSetRange(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	switch view := r.URL.Query().Get("view"); view {
+	case "length":
+		if cardinality, err := server.storage.GetSortedSetCardinality(key); err == nil {
+			respondWithJSON(w)
+			json.NewEncoder(w).Encode(cardinality)
+		} else {
+			http.Error(w, err.Error(), errorStatus(err))
+		}
+		return
+	case "":
+	default:
+		http.Error(w, "View should be either omitted or length", http.StatusBadRequest)
+		return
+	}
+
+	reverse, err := isDescOrder(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	scoreRange, byScore, err := getScoreRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// either offset & count or start & stop
+	var from, to int
+	if byScore {
+		if from, err = getIntQuery(r, "offset", 0); err == nil {
+			to, err = getIntQuery(r, "count", -1)
+		}
+	} else if from, err = getIntQuery(r, "start", 0); err == nil {
+		to, err = getIntQuery(r, "stop", -1)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var members []storage.ScoredMember
+	if byScore {
+		members, err = server.storage.GetSortedSetRangeByScore(key, scoreRange, reverse, from, to)
+	} else {
+		members, err = server.storage.GetSortedSetRangeByRank(key, from, to, reverse)
+	}
+	if err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(members)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// addToSortedSet handler accepts a dictionary of members to scores and responds with the number of new members
+func (server *GedisServer) addToSortedSet(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	var scores map[string]float64
+	if err := decodeJSONRequestBody(r, &scores); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	members := make([]storage.ScoredMember, 0, len(scores))
+	for member, score := range scores {
+		members = append(members, storage.ScoredMember{Member: member, Score: score})
+	}
+
+	if added, err := server.storage.AddToSortedSet(key, members...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(added)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// removeFromSortedSet handler responds with the number of members which were in the sorted set
+func (server *GedisServer) removeFromSortedSet(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	members, err := parseListElements(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if removed, err := server.storage.RemoveFromSortedSet(key, members...); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(removed)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// getSortedSetMember handler responds with the score and the rank (in the `order`) of the member
+func (server *GedisServer) getSortedSetMember(w http.ResponseWriter, r *http.Request) {
+	key, member, _ := getPathVars(r)
+	reverse, err := isDescOrder(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// score and rank are read separately, so they are reported only if the member is still there
+	score, exists, err := server.storage.GetSortedSetScore(key, member)
+	var rank int
+	if err == nil && exists {
+		rank, exists, err = server.storage.GetSortedSetRank(key, member, reverse)
+	}
+	if err == nil && exists {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(memberInfo{member, score, rank})
+	} else if err == nil {
+		http.NotFound(w, r)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// incrementSortedSetScore handler accepts a number to add to the score and responds with the new score
+func (server *GedisServer) incrementSortedSetScore(w http.ResponseWriter, r *http.Request) {
+	key, member, _ := getPathVars(r)
+	var delta float64
+	if err := decodeJSONRequestBody(r, &delta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if score, err := server.storage.IncrementSortedSetScore(key, member, delta); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(score)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
+
+// popFromSortedSet handler removes `count` members from the `side` (min or max) of the sorted set
+func (server *GedisServer) popFromSortedSet(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	var max bool
+	switch r.URL.Query().Get("side") {
+	case "", "min":
+	case "max":
+		max = true
+	default:
+		http.Error(w, "Side should be either min or max", http.StatusBadRequest)
+		return
+	}
+	count, err := getIntQuery(r, "count", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if popped, err := server.storage.PopFromSortedSet(key, max, count); err == nil {
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(popped)
+	} else {
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
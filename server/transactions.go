@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+// txRequest is a body of the transaction request. Watch maps keys to their expected
+// versions (as returned in ETag), zero version means the key should be absent
+type txRequest struct {
+	Watch    map[string]uint64 `json:"watch"`
+	Commands []txCommand       `json:"commands"`
+}
+
+// txCommand is a single command of the transaction. Meaning of value depends on the command,
+// field is a sub-key of a dictionary or a member of a sorted set
+type txCommand struct {
+	Command string          `json:"command"`
+	Key     string          `json:"key"`
+	Field   string          `json:"field,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Type    string          `json:"type,omitempty"`
+	Side    string          `json:"side,omitempty"`
+	TTL     string          `json:"ttl,omitempty"`
+}
+
+// strings call accepts either a single string or an array of them as the value
+func (command txCommand) strings() ([]string, error) {
+	value, err := parseStorable(command.Value)
+	switch values := value.(type) {
+	case string:
+		return []string{values}, nil
+	case []string:
+		return values, nil
+	}
+	if err == nil {
+		err = errors.New("Value should be a string or an array of strings")
+	}
+	return nil, err
+}
+
+// exec call performs the command within the transaction and returns its result
+func (command txCommand) exec(tx *storage.Tx) (interface{}, error) {
+	switch command.Command {
+	case "get":
+		if val, exists := tx.GetValueByKey(command.Key); exists {
+			return val.Entity, nil
+		}
+		return nil, nil
+	case "set":
+		value, err := parseEntry(command.Value, command.Type)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := parseTTL(command.TTL)
+		if err != nil {
+			return nil, err
+		}
+		tx.SetValueByKey(command.Key, value, ttl)
+		return nil, nil
+	case "delete":
+		return tx.DeleteValueByKey(command.Key), nil
+	case "incr":
+		delta := json.Number("1")
+		if len(command.Value) > 0 {
+			if err := json.Unmarshal(command.Value, &delta); err != nil {
+				return nil, errors.New("Increment should be a number")
+			}
+		}
+		return incrementBy(tx, command.Key, delta)
+	case "push":
+		toHead, err := parseSide(command.Side)
+		if err != nil {
+			return nil, err
+		}
+		elements, err := command.strings()
+		if err != nil {
+			return nil, err
+		}
+		return tx.PushToList(command.Key, toHead, elements...)
+	case "pop":
+		fromHead, err := parseSide(command.Side)
+		if err != nil {
+			return nil, err
+		}
+		if element, exists, err := tx.PopFromList(command.Key, fromHead); exists || err != nil {
+			return element, err
+		}
+		return nil, nil
+	case "hset":
+		var value string
+		if err := json.Unmarshal(command.Value, &value); err != nil {
+			return nil, errors.New("Dictionary value should be a string")
+		}
+		return tx.SetDictEntry(command.Key, command.Field, value)
+	case "hdel":
+		return tx.DeleteDictEntry(command.Key, command.Field)
+	case "sadd":
+		members, err := command.strings()
+		if err != nil {
+			return nil, err
+		}
+		return tx.AddToSet(command.Key, members...)
+	case "srem":
+		members, err := command.strings()
+		if err != nil {
+			return nil, err
+		}
+		return tx.RemoveFromSet(command.Key, members...)
+	case "zadd":
+		var scores map[string]float64
+		if err := json.Unmarshal(command.Value, &scores); err != nil {
+			return nil, errors.New("Value should be a dictionary of members to scores")
+		}
+		members := make([]storage.ScoredMember, 0, len(scores))
+		for member, score := range scores {
+			members = append(members, storage.ScoredMember{Member: member, Score: score})
+		}
+		return tx.AddToSortedSet(command.Key, members...)
+	case "zincrby":
+		var delta float64
+		if err := json.Unmarshal(command.Value, &delta); err != nil {
+			return nil, errors.New("Increment should be a number")
+		}
+		return tx.IncrementSortedSetScore(command.Key, command.Field, delta)
+	}
+	return nil, errors.New("Unknown command " + command.Command)
+}
+
+// execTransaction handler runs all the commands atomically and responds with their results.
+// Nothing is changed if any of the commands fails (400) or any of the watched keys is changed (409)
+func (server *GedisServer) execTransaction(w http.ResponseWriter, r *http.Request) {
+	var request txRequest
+	if err := decodeJSONRequestBody(r, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(request.Commands) == 0 {
+		http.Error(w, "There are no commands to execute", http.StatusBadRequest)
+		return
+	}
+
+	var results []interface{}
+	err := server.storage.ExecTransaction(request.Watch, func(tx *storage.Tx) error {
+		results = make([]interface{}, len(request.Commands))
+		for i, command := range request.Commands {
+			result, err := command.exec(tx)
+			if err != nil {
+				return errors.New("Command " + strconv.Itoa(i) + " (" + command.Command + ") failed: " + err.Error())
+			}
+			results[i] = result
+		}
+		return nil
+	})
+
+	switch err {
+	case nil:
+		respondWithJSON(w)
+		json.NewEncoder(w).Encode(results)
+	case storage.ErrTransactionAborted:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), errorStatus(err))
+	}
+}
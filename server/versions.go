@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+func formatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// respondWithVersion call sets ETag header, it should be done before the body is written
+func respondWithVersion(w http.ResponseWriter, val *storage.StorableWithMeta) {
+	w.Header().Set("ETag", formatETag(val.Version))
+}
+
+// parseETags call parses comma separated entity tags of If-Match or If-None-Match header.
+// True is returned for `*`, weak tags are considered as strong ones
+func parseETags(header string) ([]uint64, bool, error) {
+	if strings.TrimSpace(header) == "*" {
+		return nil, true, nil
+	}
+	var versions []uint64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		version, err := strconv.ParseUint(strings.Trim(tag, `"`), 10, 64)
+		if err != nil {
+			return nil, false, errors.New("Entity tag should be a quoted version of the entry")
+		}
+		versions = append(versions, version)
+	}
+	return versions, false, nil
+}
+
+// getPrecondition call builds the precondition of the write from If-Match and If-None-Match headers.
+// Nil is returned if there are no such headers
+func getPrecondition(r *http.Request) (storage.Precondition, error) {
+	var preconditions []storage.Precondition
+	if header := r.Header.Get("If-Match"); header != "" {
+		versions, any, err := parseETags(header)
+		if err != nil {
+			return nil, err
+		}
+		if any {
+			preconditions = append(preconditions, storage.IfExists)
+		} else {
+			preconditions = append(preconditions, storage.IfVersion(versions...))
+		}
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		versions, any, err := parseETags(header)
+		if err != nil {
+			return nil, err
+		}
+		if any {
+			preconditions = append(preconditions, storage.IfAbsent)
+		} else {
+			preconditions = append(preconditions, storage.IfNotVersion(versions...))
+		}
+	}
+
+	switch len(preconditions) {
+	case 0:
+		return nil, nil
+	case 1:
+		return preconditions[0], nil
+	}
+	return func(current *storage.StorableWithMeta) bool {
+		return preconditions[0](current) && preconditions[1](current)
+	}, nil
+}
+
+// notModified call checks If-None-Match header of a read request
+func notModified(r *http.Request, val *storage.StorableWithMeta) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	versions, any, err := parseETags(header)
+	return err == nil && (any || storage.IfVersion(versions...)(val))
+}
+
+// respondWithWriteError call reports failed precondition with the version of the current entry (if any)
+func respondWithWriteError(w http.ResponseWriter, current *storage.StorableWithMeta, err error) {
+	if err == storage.ErrVersionConflict {
+		if current != nil {
+			respondWithVersion(w, current)
+		}
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	http.Error(w, err.Error(), errorStatus(err))
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/izhamoidsin/gedis/storage"
+)
+
+const (
+	defaultWatchTimeout = 30 * time.Second
+	maxWatchTimeout     = 5 * time.Minute
+)
+
+// getWatchTimeout call accepts either number of seconds or duration string, the timeout is limited by maxWatchTimeout
+func getWatchTimeout(r *http.Request) (time.Duration, error) {
+	timeoutStr := r.URL.Query().Get("timeout")
+	if timeoutStr == "" {
+		return defaultWatchTimeout, nil
+	}
+	timeout, err := parseTTL(timeoutStr)
+	if err != nil {
+		return 0, errors.New("Timeout should be a positive number of seconds or a duration")
+	}
+	if timeout > maxWatchTimeout {
+		timeout = maxWatchTimeout
+	}
+	return timeout, nil
+}
+
+// watchItem handler is a long poll: it responds with the entry (or 404) as soon as its version differs from
+// `version` query param (zero for absent entry, the current version if not passed). Otherwise it waits for the next
+// write, removal or expiration of the entry up to `timeout` and responds with 304 Not Modified if nothing happens
+func (server *GedisServer) watchItem(w http.ResponseWriter, r *http.Request) {
+	key, _, _ := getPathVars(r)
+	timeout, err := getWatchTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var watchedVersion uint64
+	versionStr := r.URL.Query().Get("version")
+	if versionStr != "" {
+		if watchedVersion, err = strconv.ParseUint(versionStr, 10, 64); err != nil {
+			http.Error(w, "Version should be a non-negative number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// subscription is done before the entry is read, so changes made in between are not missed
+	changes := server.broker.Subscribe(keyspaceChannelPrefix + key)
+	defer changes.Close()
+
+	val, exists, version := server.readVersion(key)
+	if versionStr == "" {
+		watchedVersion = version
+	}
+
+	if version == watchedVersion {
+		// changes reported before the read (e.g. expiration done by the read itself) are already seen. The ones
+		// reported after the read are drained as well, so the entry is read again not to miss them
+		for drained := false; !drained; {
+			select {
+			case _, open := <-changes.Messages:
+				drained = !open
+			default:
+				drained = true
+			}
+		}
+		val, exists, version = server.readVersion(key)
+	}
+
+	if version == watchedVersion {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+			if exists {
+				respondWithVersion(w, val)
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-changes.Messages:
+		}
+		val, exists = server.storage.GetValueByKey(key)
+	}
+
+	if exists {
+		respondWithVersion(w, val)
+		respondWithEntry(w, val)
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+// readVersion call reads the entry along with its version, zero for absent entry
+func (server *GedisServer) readVersion(key string) (*storage.StorableWithMeta, bool, uint64) {
+	val, exists := server.storage.GetValueByKey(key)
+	if !exists {
+		return nil, false, 0
+	}
+	return val, true, val.Version
+}
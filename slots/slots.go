@@ -0,0 +1,152 @@
+package slots
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Count is the number of hash slots the keyspace is split into, the same as Redis Cluster has
+const Count = 16384
+
+// Header holds the slot of the key in responses redirecting clients to the owner of the slot
+const Header = "Gedis-Slot"
+
+// KeySlot call returns the slot of the key: CRC16 of its hash tag modulo Count
+func KeySlot(key string) int {
+	return int(crc16(HashTag(key)) % Count)
+}
+
+// HashTag call returns the part of the key which is hashed. Like Redis does, it is a non-empty part
+// between the first `{` and the following `}` if there is one, the whole key otherwise.
+// So related keys (e.g. `{user1}:name` and `{user1}:email`) could be put into the same slot
+func HashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// crc16 call implements CRC-16/XMODEM used by Redis Cluster
+func crc16(data string) uint16 {
+	var crc uint16
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Range is a range of slots from Start to End inclusive owned by the node (its `host:port` address)
+type Range struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Node  string `json:"node"`
+}
+
+// Map assigns slots to nodes, it is safe for concurrent use
+type Map struct {
+	lock   sync.RWMutex
+	owners [Count]string
+}
+
+// NewMap ...
+func NewMap() *Map {
+	return new(Map)
+}
+
+// SplitEvenly call creates a map spreading the slots over the nodes in contiguous ranges
+func SplitEvenly(nodes ...string) *Map {
+	slotMap := NewMap()
+	for i, node := range nodes {
+		slotMap.Assign(i*Count/len(nodes), (i+1)*Count/len(nodes)-1, node)
+	}
+	return slotMap
+}
+
+func validRange(start int, end int) error {
+	if start < 0 || end >= Count || start > end {
+		return errors.New("Malformed slot range " + strconv.Itoa(start) + "-" + strconv.Itoa(end))
+	}
+	return nil
+}
+
+// Owner call returns the node owning the slot, empty if the slot is not assigned
+func (slotMap *Map) Owner(slot int) string {
+	if slot < 0 || slot >= Count {
+		return ""
+	}
+	slotMap.lock.RLock()
+	defer slotMap.lock.RUnlock()
+	return slotMap.owners[slot]
+}
+
+// Assign call makes the node own the slots from start to end inclusive, empty node unassigns them
+func (slotMap *Map) Assign(start int, end int, node string) error {
+	if err := validRange(start, end); err != nil {
+		return err
+	}
+	slotMap.lock.Lock()
+	defer slotMap.lock.Unlock()
+	for slot := start; slot <= end; slot++ {
+		slotMap.owners[slot] = node
+	}
+	return nil
+}
+
+// Ranges call returns the assigned slots as contiguous ranges in the slot order
+func (slotMap *Map) Ranges() []Range {
+	slotMap.lock.RLock()
+	defer slotMap.lock.RUnlock()
+	ranges := []Range{}
+	for slot := 0; slot < Count; slot++ {
+		node := slotMap.owners[slot]
+		switch {
+		case node == "":
+		case len(ranges) > 0 && ranges[len(ranges)-1].End == slot-1 && ranges[len(ranges)-1].Node == node:
+			ranges[len(ranges)-1].End = slot
+		default:
+			ranges = append(ranges, Range{slot, slot, node})
+		}
+	}
+	return ranges
+}
+
+// SetRanges call replaces the whole map with the ranges, slots out of them are unassigned
+func (slotMap *Map) SetRanges(ranges []Range) error {
+	var owners [Count]string
+	for _, slotRange := range ranges {
+		if err := validRange(slotRange.Start, slotRange.End); err != nil {
+			return err
+		}
+		for slot := slotRange.Start; slot <= slotRange.End; slot++ {
+			owners[slot] = slotRange.Node
+		}
+	}
+	slotMap.lock.Lock()
+	defer slotMap.lock.Unlock()
+	slotMap.owners = owners
+	return nil
+}
+
+// Nodes call returns the nodes owning any slot in the slot order
+func (slotMap *Map) Nodes() []string {
+	var nodes []string
+	seen := make(map[string]bool)
+	for _, slotRange := range slotMap.Ranges() {
+		if !seen[slotRange.Node] {
+			seen[slotRange.Node] = true
+			nodes = append(nodes, slotRange.Node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,52 @@
+package slots
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeySlot(t *testing.T) {
+	if checksum := crc16("123456789"); checksum != 0x31C3 {
+		t.Errorf("Unexpected CRC16 checksum %x", checksum)
+	}
+	expectations := map[string]int{"foo": 12182, "bar": 5061, "": 0}
+	for key, slot := range expectations {
+		if actual := KeySlot(key); actual != slot {
+			t.Errorf("Slot of %q is %d instead of %d", key, actual, slot)
+		}
+	}
+	if KeySlot("{user1000}.following") != KeySlot("{user1000}.followers") || KeySlot("{user1000}.following") != KeySlot("user1000") {
+		t.Error("Keys with the same hash tag are put into different slots")
+	}
+	if KeySlot("foo{}{bar}") == KeySlot("bar") || KeySlot("foo{{bar}}zap") != KeySlot("{bar") {
+		t.Error("Empty or nested hash tags are not handled as Redis does")
+	}
+}
+
+func TestMap(t *testing.T) {
+	slotMap := SplitEvenly("node1", "node2", "node3")
+	expected := []Range{{0, 5460, "node1"}, {5461, 10921, "node2"}, {10922, Count - 1, "node3"}}
+	if ranges := slotMap.Ranges(); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("Unexpected ranges: %v", ranges)
+	}
+
+	slotMap.Assign(100, 100, "node3")
+	slotMap.Assign(Count-1, Count-1, "")
+	if slotMap.Owner(100) != "node3" || slotMap.Owner(99) != "node1" || slotMap.Owner(Count-1) != "" || slotMap.Owner(Count) != "" {
+		t.Error("Slots are not reassigned")
+	}
+	if nodes := slotMap.Nodes(); !reflect.DeepEqual(nodes, []string{"node1", "node3", "node2"}) {
+		t.Errorf("Unexpected nodes: %v", nodes)
+	}
+	if err := slotMap.Assign(10, 5, "node1"); err == nil {
+		t.Error("Malformed range is assigned")
+	}
+
+	copied := NewMap()
+	if err := copied.SetRanges(slotMap.Ranges()); err != nil || !reflect.DeepEqual(copied.Ranges(), slotMap.Ranges()) {
+		t.Error("Map is not restored from its ranges")
+	}
+	if err := copied.SetRanges([]Range{{0, Count, "node1"}}); err == nil || copied.Owner(100) != "node3" {
+		t.Error("Malformed ranges are applied")
+	}
+}
@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy defines how often the append-only file is flushed to the disk
+type FsyncPolicy int
+
+// fsync policies, the same as Redis has
+const (
+	FsyncAlways FsyncPolicy = iota
+	FsyncEverySecond
+	FsyncNever
+)
+
+// ParseFsyncPolicy call converts Redis-like policy name (always, everysec, no) to FsyncPolicy
+func ParseFsyncPolicy(name string) (FsyncPolicy, error) {
+	switch name {
+	case "always":
+		return FsyncAlways, nil
+	case "everysec":
+		return FsyncEverySecond, nil
+	case "no":
+		return FsyncNever, nil
+	}
+	return FsyncNever, errors.New("Unknown fsync policy: " + name)
+}
+
+// aofRecord is a single line of the append-only file. Appends and updates carry
+// the whole new state of the entry, so replaying a record twice gives the same result
+type aofRecord struct {
+	Type  MutationType  `json:"type"`
+	Key   string        `json:"key"`
+	Entry *encodedEntry `json:"entry,omitempty"`
+}
+
+// AppendOnlyFile logs every mutation of the storage to the file and replays it on startup
+type AppendOnlyFile struct {
+	path             string
+	policy           FsyncPolicy
+	rewriteThreshold int64
+	storage          PersistableStorage
+
+	lock       sync.Mutex
+	file       *os.File
+	size       int64
+	baseSize   int64 // size right after the last rewrite
+	dirty      bool  // there are writes not synced yet
+	rewriting  bool
+	closed     bool
+	stopSyncer chan struct{}
+	syncerDone chan struct{}
+}
+
+// OpenAppendOnlyFile call replays the file (if exists) into the storage and starts logging
+// its mutations. The file is rewritten once it grows past rewriteThreshold and doubles
+// its size since the last rewrite. Non-positive rewriteThreshold disables rewriting
+func OpenAppendOnlyFile(storage PersistableStorage, path string, policy FsyncPolicy, rewriteThreshold int64) (*AppendOnlyFile, error) {
+	aof := new(AppendOnlyFile)
+	aof.path = path
+	aof.policy = policy
+	aof.rewriteThreshold = rewriteThreshold
+	aof.storage = storage
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	size, err := aof.replay(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// the file could be truncated by replay, so it is positioned explicitly
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	aof.file = file
+	aof.size = size
+	aof.baseSize = size
+
+	if policy == FsyncEverySecond {
+		aof.stopSyncer = make(chan struct{})
+		aof.syncerDone = make(chan struct{})
+		go aof.runSyncer()
+	}
+	storage.AddMutationListener(aof.log)
+	return aof, nil
+}
+
+// OpenAppendOnlyFileWithSnapshot call opens the append-only file of a storage which is also snapshotted.
+// The file has every change, so the snapshot could only bring back entries deleted after it was taken:
+// it is loaded only if there is no file yet, and the new file is rewritten to start from its content
+func OpenAppendOnlyFileWithSnapshot(storage PersistableStorage, snapshotter *Snapshotter, path string, policy FsyncPolicy, rewriteThreshold int64) (*AppendOnlyFile, error) {
+	_, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	fromSnapshot := os.IsNotExist(err)
+	if fromSnapshot {
+		if err := snapshotter.Load(); err != nil {
+			return nil, err
+		}
+	}
+	aof, err := OpenAppendOnlyFile(storage, path, policy, rewriteThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if fromSnapshot {
+		if err := aof.Rewrite(); err != nil {
+			aof.Close()
+			return nil, err
+		}
+	}
+	return aof, nil
+}
+
+// replay call applies all the records of the file to the storage and returns the size
+// of the valid part of the file. Incomplete last record (e.g. written partially before
+// a crash) is truncated
+func (aof *AppendOnlyFile) replay(file *os.File) (int64, error) {
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Println("Truncating incomplete record at the end of " + aof.path)
+				return offset, file.Truncate(offset)
+			}
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		var record aofRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return offset, errors.New("Corrupted record in " + aof.path + ": " + err.Error())
+		}
+		if err := applyRecord(aof.storage, &record); err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+	}
+}
+
+// applyRecord call is used to load both append-only files and snapshots
+func applyRecord(storage PersistableStorage, record *aofRecord) error {
+	switch record.Type {
+	case MutationAppend, MutationUpdate:
+		if record.Entry == nil {
+			return errors.New("Record of " + record.Key + " has no entry")
+		}
+		entry, err := decodeEntry(record.Entry)
+		if err != nil {
+			return err
+		}
+		if notExpired(entry) {
+			storage.RestoreEntry(record.Key, entry)
+		} else {
+			storage.DeleteValueByKey(record.Key)
+		}
+	case MutationDelete, MutationExpire, MutationEvict:
+		storage.DeleteValueByKey(record.Key)
+	default:
+		return errors.New("Unknown record type: " + string(record.Type))
+	}
+	return nil
+}
+
+func (aof *AppendOnlyFile) log(mutation Mutation) {
+	record := aofRecord{Type: mutation.Type, Key: mutation.Key}
+	if mutation.Entry != nil {
+		encoded, err := encodeEntry(mutation.Entry)
+		if err != nil {
+			log.Println("Can not log mutation of " + mutation.Key + ": " + err.Error())
+			return
+		}
+		record.Entry = encoded
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Println("Can not log mutation of " + mutation.Key + ": " + err.Error())
+		return
+	}
+
+	aof.lock.Lock()
+	defer aof.lock.Unlock()
+	if aof.closed {
+		return
+	}
+	if err := aof.write(append(line, '\n')); err != nil {
+		log.Println("Can not write to " + aof.path + ": " + err.Error())
+		return
+	}
+	if aof.needsRewrite() {
+		aof.rewriting = true
+		go func() {
+			if err := aof.Rewrite(); err != nil {
+				log.Println("Can not rewrite " + aof.path + ": " + err.Error())
+			}
+		}()
+	}
+}
+
+// write call should be done under the lock
+func (aof *AppendOnlyFile) write(line []byte) error {
+	n, err := aof.file.Write(line)
+	aof.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if aof.policy == FsyncAlways {
+		return aof.file.Sync()
+	}
+	aof.dirty = true
+	return nil
+}
+
+func (aof *AppendOnlyFile) needsRewrite() bool {
+	return aof.rewriteThreshold > 0 && !aof.rewriting &&
+		aof.size > aof.rewriteThreshold && aof.size > aof.baseSize*2
+}
+
+func (aof *AppendOnlyFile) runSyncer() {
+	defer close(aof.syncerDone)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-aof.stopSyncer:
+			return
+		case <-ticker.C:
+			aof.lock.Lock()
+			if aof.dirty && !aof.closed {
+				if err := aof.file.Sync(); err != nil {
+					log.Println("Can not sync " + aof.path + ": " + err.Error())
+				}
+				aof.dirty = false
+			}
+			aof.lock.Unlock()
+		}
+	}
+}
+
+// Rewrite call compacts the file replacing its content with the current state of the storage.
+// Mutations are blocked while the file is being rewritten
+func (aof *AppendOnlyFile) Rewrite() error {
+	aof.lock.Lock()
+	defer aof.lock.Unlock()
+	defer func() { aof.rewriting = false }()
+	if aof.closed {
+		return errors.New("Append-only file is closed")
+	}
+
+	tmpPath := aof.path + ".rewrite"
+	tmpFile, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	size, err := dumpEntries(aof.storage.RangeEntries, tmpFile, nil)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, aof.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	file, err := os.OpenFile(aof.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	aof.file.Close()
+	aof.file = file
+	aof.size = size
+	aof.baseSize = size
+	aof.dirty = false
+	// the rename is durable only once the directory is synced
+	return syncDir(filepath.Dir(aof.path))
+}
+
+// dumpEntries call writes the ranged entries matching the filter (all of them if it is nil) as append records.
+// It is used for both append-only file rewriting and snapshots
+func dumpEntries(rangeEntries func(f func(key string, entry *StorableWithMeta) bool), w io.Writer, match func(key string) bool) (int64, error) {
+	writer := bufio.NewWriter(w)
+	var size int64
+	var err error
+	rangeEntries(func(key string, entry *StorableWithMeta) bool {
+		if match != nil && !match(key) {
+			return true
+		}
+		var encoded *encodedEntry
+		if encoded, err = encodeEntry(entry); err != nil {
+			return false
+		}
+		var line []byte
+		if line, err = json.Marshal(aofRecord{Type: MutationAppend, Key: key, Entry: encoded}); err != nil {
+			return false
+		}
+		var n int
+		n, err = writer.Write(append(line, '\n'))
+		size += int64(n)
+		return err == nil
+	})
+	if err != nil {
+		return size, err
+	}
+	return size, writer.Flush()
+}
+
+// Close call flushes and closes the file. Mutations are not logged after that
+func (aof *AppendOnlyFile) Close() error {
+	aof.lock.Lock()
+	if aof.closed {
+		aof.lock.Unlock()
+		return nil
+	}
+	aof.closed = true
+	aof.lock.Unlock()
+
+	if aof.stopSyncer != nil {
+		close(aof.stopSyncer)
+		<-aof.syncerDone
+	}
+
+	aof.lock.Lock()
+	defer aof.lock.Unlock()
+	if err := aof.file.Sync(); err != nil {
+		aof.file.Close()
+		return err
+	}
+	return aof.file.Close()
+}
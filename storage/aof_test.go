@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func openTestAOF(t *testing.T, path string) (*SyncMapStorage, *AppendOnlyFile) {
+	testStorage := InitSyncMapStorage(time.Minute)
+	aof, err := OpenAppendOnlyFile(testStorage, path, FsyncAlways, 0)
+	if err != nil {
+		t.Fatal("Can not open append-only file. " + err.Error())
+	}
+	return testStorage, aof
+}
+
+func TestAOFReplay(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.aof")
+
+	testStorage, aof := openTestAOF(t, path)
+	testStorage.AppendNewValue("str", "Lorem ipsum")
+	testStorage.AppendNewValueWithTTL("arr", []string{"Alpha", "Bravo"}, time.Hour)
+	testStorage.AppendNewValue("dic", map[string]string{"1": "One"})
+	testStorage.UpdateValueByKey("str", "Dolor sit amet")
+	testStorage.DeleteValueByKey("dic")
+	testStorage.SetFlaggedValueByKeyIf("flagged", "Consectetur", 42, 0, Always)
+	aof.Close()
+
+	restored, aof := openTestAOF(t, path)
+	defer aof.Close()
+	if val, ok := restored.GetValueByKey("str"); !ok || val.Entity != "Dolor sit amet" {
+		t.Error("Updated value is not restored from append-only file")
+	}
+	if val, ok := restored.GetValueByKey("arr"); !ok || !reflect.DeepEqual(val.Entity, []string{"Alpha", "Bravo"}) || val.TTL != time.Hour {
+		t.Error("Array value is not restored with its TTL from append-only file")
+	}
+	if _, ok := restored.GetValueByKey("dic"); ok {
+		t.Error("Deleted value is restored from append-only file")
+	}
+	if val, ok := restored.GetValueByKey("flagged"); !ok || val.Flags != 42 {
+		t.Error("Flags of the value are not restored from append-only file")
+	}
+}
+
+func TestAOFReplaySkipsExpired(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.aof")
+
+	testStorage, aof := openTestAOF(t, path)
+	testStorage.AppendNewValueWithTTL("short", "Soon expired", time.Millisecond*10)
+	aof.Close()
+
+	time.Sleep(time.Millisecond * 20)
+	restored, aof := openTestAOF(t, path)
+	defer aof.Close()
+	if keys := restored.GetAllKeys(); len(keys) != 0 {
+		t.Error("Expired value is restored from append-only file")
+	}
+}
+
+func TestAOFTruncatedRecord(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.aof")
+
+	testStorage, aof := openTestAOF(t, path)
+	testStorage.AppendNewValue("str", "Lorem ipsum")
+	aof.Close()
+
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString(`{"type":"append","key":"broken"`)
+	file.Close()
+
+	restored, aof := openTestAOF(t, path)
+	restored.AppendNewValue("next", "After truncation")
+	aof.Close()
+
+	restored, aof = openTestAOF(t, path)
+	defer aof.Close()
+	if _, ok := restored.GetValueByKey("str"); !ok {
+		t.Error("Value written before incomplete record is not restored")
+	}
+	if _, ok := restored.GetValueByKey("next"); !ok {
+		t.Error("Value written after truncation is not restored")
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.aof")
+
+	testStorage, aof := openTestAOF(t, path)
+	for i := 0; i < 100; i++ {
+		testStorage.AppendNewValue("key", "Value #"+strconv.Itoa(i))
+		testStorage.DeleteValueByKey("key")
+	}
+	testStorage.AppendNewValue("key", "The last one")
+	before, _ := os.Stat(path)
+
+	if err := aof.Rewrite(); err != nil {
+		t.Error("Can not rewrite append-only file. " + err.Error())
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Error("Append-only file is not compacted by rewrite")
+	}
+	testStorage.UpdateValueByKey("key", "Updated after rewrite")
+	aof.Close()
+
+	restored, aof := openTestAOF(t, path)
+	defer aof.Close()
+	if val, ok := restored.GetValueByKey("key"); !ok || val.Entity != "Updated after rewrite" {
+		t.Error("Value is not restored from rewritten append-only file")
+	}
+}
+
+func TestAOFWithSnapshot(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.aof")
+	snapshotPath := filepath.Join(dir, "test.snapshot")
+
+	testStorage := InitSyncMapStorage(time.Minute)
+	aof, err := OpenAppendOnlyFileWithSnapshot(testStorage, NewSnapshotter(testStorage, snapshotPath), path, FsyncAlways, 0)
+	if err != nil {
+		t.Fatal("Can not open append-only file. " + err.Error())
+	}
+	testStorage.AppendNewValue("deleted", "Lorem ipsum")
+	testStorage.AppendNewValue("kept", "Dolor sit amet")
+	if _, err := NewSnapshotter(testStorage, snapshotPath).Save(); err != nil {
+		t.Fatal("Can not save snapshot. " + err.Error())
+	}
+	testStorage.DeleteValueByKey("deleted")
+	if err := aof.Rewrite(); err != nil {
+		t.Error("Can not rewrite append-only file. " + err.Error())
+	}
+	aof.Close()
+
+	restored := InitSyncMapStorage(time.Minute)
+	aof, err = OpenAppendOnlyFileWithSnapshot(restored, NewSnapshotter(restored, snapshotPath), path, FsyncAlways, 0)
+	if err != nil {
+		t.Fatal("Can not reopen append-only file. " + err.Error())
+	}
+	aof.Close()
+	if _, ok := restored.GetValueByKey("deleted"); ok {
+		t.Error("Value deleted after the snapshot is restored")
+	}
+	if _, ok := restored.GetValueByKey("kept"); !ok {
+		t.Error("Value is not restored from append-only file")
+	}
+
+	// without the append-only file the snapshot is loaded and becomes the content of the new file
+	os.Remove(path)
+	fromSnapshot := InitSyncMapStorage(time.Minute)
+	aof, err = OpenAppendOnlyFileWithSnapshot(fromSnapshot, NewSnapshotter(fromSnapshot, snapshotPath), path, FsyncAlways, 0)
+	if err != nil {
+		t.Fatal("Can not open append-only file. " + err.Error())
+	}
+	aof.Close()
+	if _, ok := fromSnapshot.GetValueByKey("deleted"); !ok {
+		t.Error("Snapshot is not loaded when there is no append-only file")
+	}
+	restored, aof = openTestAOF(t, path)
+	defer aof.Close()
+	if _, ok := restored.GetValueByKey("deleted"); !ok {
+		t.Error("Append-only file does not start from the loaded snapshot")
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// names of storable kinds used in persisted entries
+const (
+	stringKind    = "string"
+	listKind      = "list"
+	dictKind      = "dict"
+	setKind       = "set"
+	sortedSetKind = "zset"
+)
+
+// encodedEntry is a serializable form of StorableWithMeta. Kind is kept explicitly
+// because JSON representation of the entity is not enough to restore its go type
+type encodedEntry struct {
+	Kind          string          `json:"kind"`
+	Entity        json.RawMessage `json:"entity"`
+	LastWriteTime time.Time       `json:"lastWriteTime"`
+	TTL           time.Duration   `json:"ttl"`
+	Version       uint64          `json:"version,omitempty"`
+	Flags         uint32          `json:"flags,omitempty"`
+}
+
+func encodeEntry(entry *StorableWithMeta) (*encodedEntry, error) {
+	kind := TypeOf(entry.Entity)
+	if kind == "" {
+		return nil, errors.New("Unsupported type of stored value")
+	}
+
+	entity, err := json.Marshal(entry.Entity)
+	if err != nil {
+		return nil, err
+	}
+	return &encodedEntry{kind, entity, entry.LastWriteTime, entry.TTL, entry.Version, entry.Flags}, nil
+}
+
+func decodeEntry(encoded *encodedEntry) (*StorableWithMeta, error) {
+	var entity Storable
+	var err error
+	switch encoded.Kind {
+	case stringKind:
+		var str string
+		err = json.Unmarshal(encoded.Entity, &str)
+		entity = str
+	case listKind:
+		var list []string
+		err = json.Unmarshal(encoded.Entity, &list)
+		entity = list
+	case dictKind:
+		var dict map[string]string
+		err = json.Unmarshal(encoded.Entity, &dict)
+		entity = dict
+	case setKind:
+		var set Set
+		err = json.Unmarshal(encoded.Entity, &set)
+		entity = set
+	case sortedSetKind:
+		sortedSet := new(SortedSet)
+		err = json.Unmarshal(encoded.Entity, sortedSet)
+		entity = sortedSet
+	default:
+		err = errors.New("Unsupported kind of stored value: " + encoded.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := new(StorableWithMeta)
+	entry.Entity = entity
+	entry.LastWriteTime = encoded.LastWriteTime
+	entry.TTL = encoded.TTL
+	entry.Flags = encoded.Flags
+	// entries persisted before versioning are considered as new ones
+	entry.Version = encoded.Version
+	if entry.Version == 0 {
+		entry.Version = nextVersion()
+	} else {
+		observeVersion(entry.Version)
+	}
+	entry.touch()
+	return entry, nil
+}
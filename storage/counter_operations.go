@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// CounterStorage contains atomic increments of string values which parse as numbers.
+// Counters are created at zero if there is no such key
+type CounterStorage interface {
+	// IncrementBy adds delta (negative one to decrement) to the integer value and returns the new value
+	IncrementBy(key string, delta int64) (int64, error)
+
+	// IncrementByFloat adds delta to the numeric value and returns the new value
+	IncrementByFloat(key string, delta float64) (float64, error)
+}
+
+// counterOf call extracts string representation of the counter, nil entry is considered as zero
+func counterOf(entry *StorableWithMeta) (string, error) {
+	if entry == nil {
+		return "0", nil
+	}
+	str, yes := entry.Entity.(string)
+	if !yes {
+		return "", errors.New("Stored value is not a string")
+	}
+	return str, nil
+}
+
+// IncrementBy ...
+func (ops operations) IncrementBy(key string, delta int64) (int64, error) {
+	var value int64
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		str, err := counterOf(current)
+		if err != nil {
+			return nil, err
+		}
+		counter, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, errors.New("Stored value is not an integer or out of range")
+		}
+		if (delta > 0 && counter > math.MaxInt64-delta) || (delta < 0 && counter < math.MinInt64-delta) {
+			return nil, errors.New("Increment or decrement would overflow")
+		}
+		value = counter + delta
+		return ops.rewrap(current, strconv.FormatInt(value, 10)), nil
+	})
+	return value, err
+}
+
+// IncrementByFloat ...
+func (ops operations) IncrementByFloat(key string, delta float64) (float64, error) {
+	var value float64
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		str, err := counterOf(current)
+		if err != nil {
+			return nil, err
+		}
+		counter, err := strconv.ParseFloat(str, 64)
+		if err != nil || math.IsNaN(counter) || math.IsInf(counter, 0) {
+			return nil, errors.New("Stored value is not a valid float")
+		}
+		value = counter + delta
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, errors.New("Increment would produce NaN or Infinity")
+		}
+		// no exponent is used, so integral results could be incremented by integers later
+		return ops.rewrap(current, strconv.FormatFloat(value, 'f', -1, 64)), nil
+	})
+	return value, err
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentIncrements(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					testStorage.IncrementBy("counter", 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if value, err := testStorage.IncrementBy("counter", -4000); err != nil || value != 0 {
+			t.Error(name + ": Some of concurrent increments are lost")
+		}
+		if stored, ok := testStorage.GetValueByKey("counter"); !ok || stored.Entity != "0" {
+			t.Error(name + ": Counter is not stored as a string")
+		}
+	}
+}
+
+func TestIncrementErrors(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("max", "9223372036854775807")
+		if _, err := testStorage.IncrementBy("max", 1); err == nil {
+			t.Error(name + ": Overflow does not lead to error")
+		}
+		testStorage.AppendNewValue("text", "ten")
+		if _, err := testStorage.IncrementBy("text", 1); err == nil {
+			t.Error(name + ": Incrementing non-numeric value does not lead to error")
+		}
+		if _, err := testStorage.IncrementByFloat("text", 1); err == nil {
+			t.Error(name + ": Incrementing non-numeric value by float does not lead to error")
+		}
+		testStorage.AppendNewValue("list", []string{"1"})
+		if _, err := testStorage.IncrementBy("list", 1); err == nil {
+			t.Error(name + ": Incrementing list does not lead to error")
+		}
+		if _, err := testStorage.IncrementByFloat("float", math.Inf(1)); err == nil {
+			t.Error(name + ": Incrementing by Infinity does not lead to error")
+		}
+	}
+}
+
+func TestIncrementByFloat(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		if value, err := testStorage.IncrementByFloat("float", 10.5); err != nil || value != 10.5 {
+			t.Error(name + ": Can not create float counter")
+		}
+		if value, err := testStorage.IncrementByFloat("float", -0.5); err != nil || value != 10 {
+			t.Error(name + ": Can not decrement float counter")
+		}
+		// integral float is stored without fraction, so it is still an integer
+		if value, err := testStorage.IncrementBy("float", 5); err != nil || value != 15 {
+			t.Error(name + ": Integral float counter could not be incremented by integer")
+		}
+	}
+}
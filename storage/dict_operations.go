@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+)
+
+// DictStorage contains atomic operations over dictionary entries.
+// Dictionaries are created on set and removed once they get empty
+type DictStorage interface {
+	// SetDictEntry returns true if the sub-key is a new one
+	SetDictEntry(key string, subKey string, value string) (bool, error)
+
+	// DeleteDictEntry returns true if the sub-key existed
+	DeleteDictEntry(key string, subKey string) (bool, error)
+
+	GetDictEntries(key string) (map[string]string, error)
+
+	DictEntryExists(key string, subKey string) (bool, error)
+
+	GetDictLength(key string) (int, error)
+
+	// GetDictKeys returns sub-keys sorted
+	GetDictKeys(key string) ([]string, error)
+}
+
+// dictOf call extracts the dictionary from the entry, nil entry is considered as an empty dictionary
+func dictOf(entry *StorableWithMeta) (map[string]string, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	dict, yes := entry.Entity.(map[string]string)
+	if !yes {
+		return nil, errors.New("Stored value is not a dictionary")
+	}
+	return dict, nil
+}
+
+// copyDict call is used to modify dictionaries, as stored ones are shared with readers
+func copyDict(dict map[string]string, extraCapacity int) map[string]string {
+	copied := make(map[string]string, len(dict)+extraCapacity)
+	for subKey, value := range dict {
+		copied[subKey] = value
+	}
+	return copied
+}
+
+// SetDictEntry ...
+func (ops operations) SetDictEntry(key string, subKey string, value string) (bool, error) {
+	var created bool
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		dict, err := dictOf(current)
+		if err != nil {
+			return nil, err
+		}
+		previous, exists := dict[subKey]
+		created = !exists
+		if exists && previous == value {
+			return current, nil
+		}
+		modified := copyDict(dict, 1)
+		modified[subKey] = value
+		return ops.rewrap(current, modified), nil
+	})
+	return created, err
+}
+
+// DeleteDictEntry ...
+func (ops operations) DeleteDictEntry(key string, subKey string) (bool, error) {
+	var deleted bool
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		dict, err := dictOf(current)
+		if err != nil {
+			return nil, err
+		}
+		if _, deleted = dict[subKey]; !deleted {
+			return current, nil
+		}
+		if len(dict) == 1 {
+			return nil, nil
+		}
+		modified := copyDict(dict, 0)
+		delete(modified, subKey)
+		return ops.rewrap(current, modified), nil
+	})
+	return deleted, err
+}
+
+// GetDictEntries ...
+func (ops operations) GetDictEntries(key string) (map[string]string, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	dict, err := dictOf(entry)
+	if err != nil {
+		return nil, err
+	}
+	return copyDict(dict, 0), nil
+}
+
+// DictEntryExists ...
+func (ops operations) DictEntryExists(key string, subKey string) (bool, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	dict, err := dictOf(entry)
+	_, exists := dict[subKey]
+	return exists, err
+}
+
+// GetDictLength ...
+func (ops operations) GetDictLength(key string) (int, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	dict, err := dictOf(entry)
+	return len(dict), err
+}
+
+// GetDictKeys ...
+func (ops operations) GetDictKeys(key string) ([]string, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	dict, err := dictOf(entry)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(dict))
+	for subKey := range dict {
+		keys = append(keys, subKey)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestDictSetAndDelete(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key := "dict"
+		if created, err := testStorage.SetDictEntry(key, "1", "One"); err != nil || !created {
+			t.Error(name + ": Can not set entry of a new dictionary")
+		}
+		if created, err := testStorage.SetDictEntry(key, "1", "Uno"); err != nil || created {
+			t.Error(name + ": Update of the existing entry is reported as creation")
+		}
+		testStorage.SetDictEntry(key, "2", "Two")
+
+		if entries, err := testStorage.GetDictEntries(key); err != nil || !reflect.DeepEqual(entries, map[string]string{"1": "Uno", "2": "Two"}) {
+			t.Error(name + ": Entries of the dictionary do not match")
+		}
+		if keys, err := testStorage.GetDictKeys(key); err != nil || !reflect.DeepEqual(keys, []string{"1", "2"}) {
+			t.Error(name + ": Keys of the dictionary do not match")
+		}
+		if exists, err := testStorage.DictEntryExists(key, "2"); err != nil || !exists {
+			t.Error(name + ": Existing entry is not found")
+		}
+		if length, err := testStorage.GetDictLength(key); err != nil || length != 2 {
+			t.Error(name + ": Length of the dictionary does not match")
+		}
+
+		if deleted, err := testStorage.DeleteDictEntry(key, "1"); err != nil || !deleted {
+			t.Error(name + ": Can not delete entry of the dictionary")
+		}
+		if deleted, err := testStorage.DeleteDictEntry(key, "1"); err != nil || deleted {
+			t.Error(name + ": Deletion of missing entry is reported as successful")
+		}
+		testStorage.DeleteDictEntry(key, "2")
+		if _, ok := testStorage.GetValueByKey(key); ok {
+			t.Error(name + ": Empty dictionary is not removed")
+		}
+	}
+}
+
+func TestDictCopyOnWrite(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		original := map[string]string{"1": "One"}
+		testStorage.AppendNewValue("dict", original)
+		testStorage.SetDictEntry("dict", "2", "Two")
+		entries, _ := testStorage.GetDictEntries("dict")
+		entries["3"] = "Three"
+
+		if len(original) != 1 {
+			t.Error(name + ": Stored dictionary is modified in place")
+		}
+		if length, _ := testStorage.GetDictLength("dict"); length != 2 {
+			t.Error(name + ": Returned entries are shared with the stored dictionary")
+		}
+	}
+}
+
+func TestDictTypeErrors(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("list", []string{"Alpha"})
+		if _, err := testStorage.SetDictEntry("list", "1", "One"); err == nil {
+			t.Error(name + ": Setting entry of a list value does not lead to error")
+		}
+		if _, err := testStorage.GetDictKeys("list"); err == nil {
+			t.Error(name + ": Getting keys of a list value does not lead to error")
+		}
+	}
+}
+
+func TestDictConcurrentSets(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					testStorage.SetDictEntry("dict", strconv.Itoa(i)+"_"+strconv.Itoa(j), "value")
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if length, _ := testStorage.GetDictLength("dict"); length != 400 {
+			t.Error(name + ": Concurrent sets are lost, dictionary length is " + strconv.Itoa(length))
+		}
+	}
+}
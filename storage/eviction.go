@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrOutOfMemory is returned by writes which would exceed the memory limit of the storage
+var ErrOutOfMemory = errors.New("Memory limit is reached")
+
+// EvictionPolicy defines which entries are evicted when the memory limit is reached
+type EvictionPolicy int32
+
+// eviction policies, named after Redis ones
+const (
+	// NoEviction rejects writes with ErrOutOfMemory
+	NoEviction EvictionPolicy = iota
+	// AllKeysLRU evicts the least recently used entries
+	AllKeysLRU
+	// AllKeysLFU evicts the least frequently used entries
+	AllKeysLFU
+	// VolatileTTL evicts the entries closest to expiration
+	VolatileTTL
+)
+
+const (
+	// number of entries the victim is chosen from, the same as Redis uses by default
+	evictionSampleSize = 5
+	// approximate overhead of a stored entry: map cell, meta and interface headers
+	entryOverhead = 64
+	// approximate overhead of an element of list or dictionary
+	elementOverhead = 16
+)
+
+// ParseEvictionPolicy call converts Redis-like policy name
+// (noeviction, allkeys-lru, allkeys-lfu, volatile-ttl) to EvictionPolicy
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch name {
+	case "noeviction":
+		return NoEviction, nil
+	case "allkeys-lru":
+		return AllKeysLRU, nil
+	case "allkeys-lfu":
+		return AllKeysLFU, nil
+	case "volatile-ttl":
+		return VolatileTTL, nil
+	}
+	return NoEviction, errors.New("Unknown eviction policy: " + name)
+}
+
+// MemoryStats contains counters of the memory used by a storage
+type MemoryStats struct {
+	UsedMemory  int64
+	MaxMemory   int64
+	Entries     int64
+	EvictedKeys uint64
+}
+
+// sampledStorage is implemented by storages which entries could be removed
+// by background expiry or eviction
+type sampledStorage interface {
+	// sampleEntries returns up to n entries picked randomly
+	sampleEntries(n int) map[string]*StorableWithMeta
+	// removeEntry removes the entry if it is still the same one, returns true if removed
+	removeEntry(key string, entry *StorableWithMeta, mutationType MutationType) bool
+}
+
+// memoryLimiter keeps track of approximate memory used by entries of a storage
+// and evicts some of them when the limit is reached. Approximated LRU/LFU are used,
+// like Redis does: the victim is the worst one of a few randomly sampled entries
+type memoryLimiter struct {
+	target sampledStorage
+
+	maxMemory   int64
+	policy      int32
+	usedMemory  int64
+	entries     int64
+	evictedKeys uint64
+}
+
+func newMemoryLimiter(target sampledStorage) *memoryLimiter {
+	ml := new(memoryLimiter)
+	ml.target = target
+	return ml
+}
+
+// SetMaxMemory call sets the memory limit in bytes. Non-positive maxMemory means no limit
+func (ml *memoryLimiter) SetMaxMemory(maxMemory int64, policy EvictionPolicy) {
+	atomic.StoreInt32(&ml.policy, int32(policy))
+	atomic.StoreInt64(&ml.maxMemory, maxMemory)
+}
+
+// MemoryStats ...
+func (ml *memoryLimiter) MemoryStats() MemoryStats {
+	return MemoryStats{
+		UsedMemory:  atomic.LoadInt64(&ml.usedMemory),
+		MaxMemory:   atomic.LoadInt64(&ml.maxMemory),
+		Entries:     atomic.LoadInt64(&ml.entries),
+		EvictedKeys: atomic.LoadUint64(&ml.evictedKeys),
+	}
+}
+
+func (ml *memoryLimiter) entriesCount() int64 {
+	return atomic.LoadInt64(&ml.entries)
+}
+
+// accountEntry call should be done on every change of the storage. Either of previous
+// and current entries could be nil meaning that the entry was added or removed
+func (ml *memoryLimiter) accountEntry(key string, previous *StorableWithMeta, current *StorableWithMeta) {
+	var delta int64
+	if previous != nil {
+		delta -= entrySize(key, previous)
+		atomic.AddInt64(&ml.entries, -1)
+	}
+	if current != nil {
+		delta += entrySize(key, current)
+		atomic.AddInt64(&ml.entries, 1)
+	}
+	atomic.AddInt64(&ml.usedMemory, delta)
+}
+
+// freeMemory call makes room for needed bytes evicting entries according to the policy.
+// ErrOutOfMemory is returned when it is impossible
+func (ml *memoryLimiter) freeMemory(needed int64) error {
+	maxMemory := atomic.LoadInt64(&ml.maxMemory)
+	if maxMemory <= 0 {
+		return nil
+	}
+	policy := EvictionPolicy(atomic.LoadInt32(&ml.policy))
+
+	for atomic.LoadInt64(&ml.usedMemory)+needed > maxMemory {
+		if policy == NoEviction {
+			return ErrOutOfMemory
+		}
+		key, victim := ml.pickVictim(policy)
+		if victim == nil {
+			return ErrOutOfMemory
+		}
+		if ml.target.removeEntry(key, victim, MutationEvict) {
+			atomic.AddUint64(&ml.evictedKeys, 1)
+		}
+	}
+	return nil
+}
+
+func (ml *memoryLimiter) pickVictim(policy EvictionPolicy) (string, *StorableWithMeta) {
+	var victimKey string
+	var victim *StorableWithMeta
+	for key, entry := range ml.target.sampleEntries(evictionSampleSize) {
+		if victim == nil || evictsBefore(policy, entry, victim) {
+			victimKey, victim = key, entry
+		}
+	}
+	return victimKey, victim
+}
+
+func evictsBefore(policy EvictionPolicy, entry *StorableWithMeta, other *StorableWithMeta) bool {
+	switch policy {
+	case AllKeysLRU:
+		return entry.LastAccessTime().Before(other.LastAccessTime())
+	case AllKeysLFU:
+		return entry.AccessFrequency() < other.AccessFrequency()
+	case VolatileTTL:
+		return entry.ExpireAt().Before(other.ExpireAt())
+	}
+	return false
+}
+
+// entrySize call approximates the memory taken by the entry
+func entrySize(key string, entry *StorableWithMeta) int64 {
+	if entry == nil {
+		return 0
+	}
+	size := int64(len(key) + entryOverhead)
+	switch entity := entry.Entity.(type) {
+	case string:
+		size += int64(len(entity))
+	case []string:
+		for _, element := range entity {
+			size += int64(len(element) + elementOverhead)
+		}
+	case map[string]string:
+		for subKey, value := range entity {
+			size += int64(len(subKey) + len(value) + elementOverhead)
+		}
+	case Set:
+		for member := range entity {
+			size += int64(len(member) + elementOverhead)
+		}
+	case *SortedSet:
+		// member is kept in both trees, its name is shared by their nodes
+		size += entity.memberBytes + int64(entity.Len()*2*elementOverhead)
+	}
+	return size
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// size of an entry with one letter key and 7 letters string value
+const testEntrySize = 1 + 7 + entryOverhead
+
+func fillLimitedStorage(testStorage Storage, limited MemoryLimitedStorage, policy EvictionPolicy) {
+	limited.SetMaxMemory(testEntrySize*3, policy)
+	testStorage.AppendNewValueWithTTL("a", "value_a", time.Minute*3)
+	time.Sleep(time.Millisecond)
+	testStorage.AppendNewValueWithTTL("b", "value_b", time.Minute)
+	time.Sleep(time.Millisecond)
+	testStorage.AppendNewValueWithTTL("c", "value_c", time.Minute*2)
+	time.Sleep(time.Millisecond)
+}
+
+func TestNoEviction(t *testing.T) {
+	testStorage := InitSyncMapStorage(time.Minute)
+	fillLimitedStorage(testStorage, testStorage, NoEviction)
+
+	if err := testStorage.AppendNewValue("d", "value_d"); err != ErrOutOfMemory {
+		t.Error("Write exceeding memory limit is not rejected")
+	}
+	if err := testStorage.UpdateValueByKey("a", "value_A"); err != nil {
+		t.Error("Update of the same size is rejected")
+	}
+	if stats := testStorage.MemoryStats(); stats.UsedMemory != testEntrySize*3 || stats.Entries != 3 {
+		t.Error("Memory used by entries is not accounted properly")
+	}
+	testStorage.DeleteValueByKey("a")
+	if err := testStorage.AppendNewValue("d", "value_d"); err != nil {
+		t.Error("Write is rejected after memory is freed")
+	}
+}
+
+func TestShardedNoEviction(t *testing.T) {
+	testStorage := InitShardedStorage(time.Minute, 2)
+	fillLimitedStorage(testStorage, testStorage, NoEviction)
+
+	if err := testStorage.AppendNewValue("d", "value_d"); err != ErrOutOfMemory {
+		t.Error("Write exceeding memory limit is not rejected")
+	}
+	if err := testStorage.UpdateValueByKey("a", "value_A"); err != nil {
+		t.Error("Update of the same size is rejected")
+	}
+	if err := testStorage.UpdateValueByKey("a", "value_A_grown"); err != ErrOutOfMemory {
+		t.Error("Update growing past memory limit is not rejected")
+	}
+	if stats := testStorage.MemoryStats(); stats.UsedMemory != testEntrySize*3 || stats.Entries != 3 {
+		t.Error("Memory used by entries is not accounted properly")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	testStorage := InitShardedStorage(time.Minute, 2)
+	fillLimitedStorage(testStorage, testStorage, AllKeysLRU)
+
+	testStorage.GetValueByKey("a")
+	if err := testStorage.AppendNewValue("d", "value_d"); err != nil {
+		t.Error("Write is rejected instead of eviction")
+	}
+	if _, ok := testStorage.GetValueByKey("b"); ok {
+		t.Error("Least recently used entry is not evicted")
+	}
+	if stats := testStorage.MemoryStats(); stats.EvictedKeys != 1 || stats.Entries != 3 {
+		t.Error("Eviction is not counted")
+	}
+}
+
+func TestLFUEviction(t *testing.T) {
+	testStorage := InitSyncMapStorage(time.Minute)
+	fillLimitedStorage(testStorage, testStorage, AllKeysLFU)
+
+	for i := 0; i < 3; i++ {
+		testStorage.GetValueByKey("b")
+		testStorage.GetValueByKey("c")
+	}
+	testStorage.AppendNewValue("d", "value_d")
+	if _, ok := testStorage.GetValueByKey("a"); ok {
+		t.Error("Least frequently used entry is not evicted")
+	}
+}
+
+func TestVolatileTTLEviction(t *testing.T) {
+	testStorage := InitSyncMapStorage(time.Minute)
+	fillLimitedStorage(testStorage, testStorage, VolatileTTL)
+
+	testStorage.AppendNewValue("d", "value_d")
+	if _, ok := testStorage.GetValueByKey("b"); ok {
+		t.Error("Entry closest to expiration is not evicted")
+	}
+}
+
+func TestSamplingCoversKeyspace(t *testing.T) {
+	storages := map[string]sampledStorage{
+		"syncmap": InitSyncMapStorage(time.Minute),
+		"sharded": InitShardedStorage(time.Minute, 4),
+	}
+	for name, sampled := range storages {
+		testStorage := sampled.(Storage)
+		for i := 0; i < 1000; i++ {
+			testStorage.AppendNewValue("key"+strconv.Itoa(i), "value")
+		}
+		sampledKeys := make(map[string]bool)
+		for round := 0; round < 200; round++ {
+			for key := range sampled.sampleEntries(evictionSampleSize) {
+				sampledKeys[key] = true
+			}
+		}
+		if len(sampledKeys) < 300 {
+			t.Errorf("%s: only %d distinct keys are sampled in 200 rounds", name, len(sampledKeys))
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package storage
+
+import "errors"
+
+// ListStorage contains atomic operations over list entries. Indexes follow Redis
+// semantics: negative ones are counted from the end of the list, ranges are inclusive.
+// Lists are created on push and removed once they get empty
+type ListStorage interface {
+	// PushToList returns the length of the list after the push
+	PushToList(key string, toHead bool, values ...string) (int, error)
+
+	PopFromList(key string, fromHead bool) (string, bool, error)
+
+	GetListRange(key string, start int, stop int) ([]string, error)
+
+	SetListElement(key string, index int, value string) error
+
+	TrimList(key string, start int, stop int) error
+
+	GetListLength(key string) (int, error)
+}
+
+// listOf call extracts the list from the entry, nil entry is considered as an empty list
+func listOf(entry *StorableWithMeta) ([]string, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	list, yes := entry.Entity.([]string)
+	if !yes {
+		return nil, errors.New("Stored value is not an array")
+	}
+	return list, nil
+}
+
+// listRange call converts Redis-like range to slice bounds, from > to means an empty range
+func listRange(start int, stop int, length int) (int, int) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	return start, stop + 1
+}
+
+// withList call replaces the list of the entry removing the entry if the list is empty
+func (ops operations) withList(current *StorableWithMeta, list []string) *StorableWithMeta {
+	if len(list) == 0 {
+		return nil
+	}
+	return ops.rewrap(current, list)
+}
+
+// PushToList ...
+func (ops operations) PushToList(key string, toHead bool, values ...string) (int, error) {
+	if len(values) == 0 {
+		return 0, errors.New("There are no values to push")
+	}
+
+	length := 0
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		list, err := listOf(current)
+		if err != nil {
+			return nil, err
+		}
+		// stored lists are shared with readers, so a new one is always allocated
+		pushed := make([]string, 0, len(list)+len(values))
+		if toHead {
+			// like Redis LPUSH does, values are pushed one by one, so they end up reversed
+			for i := len(values) - 1; i >= 0; i-- {
+				pushed = append(pushed, values[i])
+			}
+			pushed = append(pushed, list...)
+		} else {
+			pushed = append(append(pushed, list...), values...)
+		}
+		length = len(pushed)
+		return ops.withList(current, pushed), nil
+	})
+	return length, err
+}
+
+// PopFromList ...
+func (ops operations) PopFromList(key string, fromHead bool) (string, bool, error) {
+	var popped string
+	var found bool
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		list, err := listOf(current)
+		if err != nil || len(list) == 0 {
+			found = false
+			return current, err
+		}
+		found = true
+		if fromHead {
+			popped, list = list[0], list[1:]
+		} else {
+			popped, list = list[len(list)-1], list[:len(list)-1]
+		}
+		return ops.withList(current, list), nil
+	})
+	return popped, found, err
+}
+
+// GetListRange ...
+func (ops operations) GetListRange(key string, start int, stop int) ([]string, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	list, err := listOf(entry)
+	if err != nil {
+		return nil, err
+	}
+	from, to := listRange(start, stop, len(list))
+	if from >= to {
+		return []string{}, nil
+	}
+	return append([]string{}, list[from:to]...), nil
+}
+
+// SetListElement ...
+func (ops operations) SetListElement(key string, index int, value string) error {
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		if current == nil {
+			return nil, errors.New("There is no entry with such key")
+		}
+		list, err := listOf(current)
+		if err != nil {
+			return nil, err
+		}
+		// the update could be retried, so the index itself is kept intact
+		i := index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil, errors.New("Index out of range")
+		}
+		modified := append([]string{}, list...)
+		modified[i] = value
+		return ops.withList(current, modified), nil
+	})
+	return err
+}
+
+// TrimList ...
+func (ops operations) TrimList(key string, start int, stop int) error {
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		list, err := listOf(current)
+		if err != nil || current == nil {
+			return current, err
+		}
+		from, to := listRange(start, stop, len(list))
+		if from >= to {
+			return nil, nil
+		}
+		if from == 0 && to == len(list) {
+			return current, nil
+		}
+		return ops.withList(current, list[from:to]), nil
+	})
+	return err
+}
+
+// GetListLength ...
+func (ops operations) GetListLength(key string) (int, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	list, err := listOf(entry)
+	return len(list), err
+}
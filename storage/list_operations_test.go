@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStorages returns fresh instances of all the storage implementations
+func testStorages() map[string]Storage {
+	return map[string]Storage{
+		"syncmap": InitSyncMapStorage(time.Minute),
+		"sharded": InitShardedStorage(time.Minute, 4),
+	}
+}
+
+func TestListPushPopAndRange(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key := "list"
+		if length, err := testStorage.PushToList(key, false, "b", "c"); err != nil || length != 2 {
+			t.Error(name + ": Can not push to the tail of a new list")
+		}
+		if length, err := testStorage.PushToList(key, true, "a", "z"); err != nil || length != 4 {
+			t.Error(name + ": Can not push to the head of the list")
+		}
+		if list, err := testStorage.GetListRange(key, 0, -1); err != nil || !reflect.DeepEqual(list, []string{"z", "a", "b", "c"}) {
+			t.Error(name + ": Whole range of the list does not match pushed values")
+		}
+		if list, _ := testStorage.GetListRange(key, -3, 1); !reflect.DeepEqual(list, []string{"a"}) {
+			t.Error(name + ": Negative start of the range is not counted from the end")
+		}
+		if list, _ := testStorage.GetListRange(key, 3, 1); len(list) != 0 {
+			t.Error(name + ": Inverted range is not empty")
+		}
+
+		if val, ok, err := testStorage.PopFromList(key, true); err != nil || !ok || val != "z" {
+			t.Error(name + ": Can not pop from the head of the list")
+		}
+		if val, ok, err := testStorage.PopFromList(key, false); err != nil || !ok || val != "c" {
+			t.Error(name + ": Can not pop from the tail of the list")
+		}
+		if length, _ := testStorage.GetListLength(key); length != 2 {
+			t.Error(name + ": Length of the list does not match")
+		}
+
+		testStorage.PopFromList(key, false)
+		testStorage.PopFromList(key, false)
+		if _, ok, err := testStorage.PopFromList(key, false); err != nil || ok {
+			t.Error(name + ": Pop from an empty list does not report absence of the element")
+		}
+		if _, ok := testStorage.GetValueByKey(key); ok {
+			t.Error(name + ": Empty list is not removed")
+		}
+	}
+}
+
+// retryingKeyspace runs every update once more after the entry is changed concurrently,
+// as storages do when they fail to swap the entry
+type retryingKeyspace struct {
+	*SyncMapStorage
+	concurrentChange func()
+}
+
+func (rk retryingKeyspace) updateEntry(key string, update entryUpdate) (*StorableWithMeta, error) {
+	current, _ := rk.loadNotExpired(key)
+	if _, err := update(current); err != nil {
+		return nil, err
+	}
+	rk.concurrentChange()
+	return rk.SyncMapStorage.updateEntry(key, update)
+}
+
+func TestListSetRetried(t *testing.T) {
+	testStorage := InitSyncMapStorage(time.Minute)
+	testStorage.AppendNewValue("list", []string{"Alpha", "Bravo", "Charlie"})
+	retrying := operations{retryingKeyspace{testStorage, func() { testStorage.PushToList("list", false, "Delta") }}}
+	if err := retrying.SetListElement("list", -1, "Echo"); err != nil {
+		t.Fatal("Can not set element by negative index. " + err.Error())
+	}
+	if list, _ := testStorage.GetListRange("list", 0, -1); !reflect.DeepEqual(list, []string{"Alpha", "Bravo", "Charlie", "Echo"}) {
+		t.Errorf("Retried update sets another element: %v", list)
+	}
+}
+
+func TestListSetAndTrim(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key, original := "list", []string{"Alpha", "Bravo", "Charlie", "Delta"}
+		testStorage.AppendNewValue(key, original)
+
+		if err := testStorage.SetListElement(key, -1, "Echo"); err != nil {
+			t.Error(name + ": Can not set element by negative index")
+		}
+		if err := testStorage.SetListElement(key, 4, "Foxtrot"); err == nil {
+			t.Error(name + ": Outbounding index does not lead to error")
+		}
+		if err := testStorage.SetListElement("missing", 0, "Foxtrot"); err == nil {
+			t.Error(name + ": Setting element of missing list does not lead to error")
+		}
+		if original[3] != "Delta" {
+			t.Error(name + ": Stored list is modified in place")
+		}
+
+		if err := testStorage.TrimList(key, 1, -2); err != nil {
+			t.Error(name + ": Can not trim the list")
+		}
+		if list, _ := testStorage.GetListRange(key, 0, -1); !reflect.DeepEqual(list, []string{"Bravo", "Charlie"}) {
+			t.Error(name + ": Trimmed list does not match")
+		}
+		testStorage.TrimList(key, 5, 10)
+		if _, ok := testStorage.GetValueByKey(key); ok {
+			t.Error(name + ": List trimmed to empty is not removed")
+		}
+	}
+}
+
+func TestListTypeErrors(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("str", "Not a list")
+		if _, err := testStorage.PushToList("str", false, "value"); err == nil {
+			t.Error(name + ": Push to a string value does not lead to error")
+		}
+		if _, _, err := testStorage.PopFromList("str", false); err == nil {
+			t.Error(name + ": Pop from a string value does not lead to error")
+		}
+		if _, err := testStorage.GetListLength("str"); err == nil {
+			t.Error(name + ": Length of a string value does not lead to error")
+		}
+	}
+}
+
+func TestListConcurrentPushes(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					testStorage.PushToList("list", i%2 == 0, strconv.Itoa(j))
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if length, _ := testStorage.GetListLength("list"); length != 800 {
+			t.Error(name + ": Concurrent pushes are lost, list length is " + strconv.Itoa(length))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// orderStripes is the number of locks ordering changes of the keys, see mutationHub
+const orderStripes = 256
+
+// MutationType ...
+type MutationType string
+
+// kinds of changes a storage reports to its listeners
+const (
+	MutationAppend MutationType = "append"
+	MutationUpdate MutationType = "update"
+	MutationDelete MutationType = "delete"
+	MutationExpire MutationType = "expire"
+	MutationEvict  MutationType = "evict"
+)
+
+// Mutation describes a change of a single entry. Entry holds the new state
+// of the entry and is nil for deletions, expirations and evictions
+type Mutation struct {
+	Type  MutationType
+	Key   string
+	Entry *StorableWithMeta
+}
+
+// MutationListener is called synchronously after every change of the storage,
+// so it should not block for long
+type MutationListener func(mutation Mutation)
+
+// ObservableStorage is a storage which reports its changes, including expirations and evictions
+type ObservableStorage interface {
+	AddMutationListener(listener MutationListener)
+}
+
+// mutationHub keeps listeners of a storage and notifies them. A key is changed and its change is reported
+// under the lock of its stripe, so listeners (e.g. the append-only file) get changes of the same key in the
+// order they are applied. Storage locks are not held while listeners are notified
+type mutationHub struct {
+	lock      sync.RWMutex
+	listeners []MutationListener
+	order     [orderStripes]sync.Mutex
+}
+
+// AddMutationListener ...
+func (hub *mutationHub) AddMutationListener(listener MutationListener) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	hub.listeners = append(hub.listeners, listener)
+}
+
+func (hub *mutationHub) emit(mutationType MutationType, key string, entry *StorableWithMeta) {
+	hub.lock.RLock()
+	defer hub.lock.RUnlock()
+	for _, listener := range hub.listeners {
+		listener(Mutation{Type: mutationType, Key: key, Entry: entry})
+	}
+}
+
+func orderStripe(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % orderStripes)
+}
+
+// lockKey call should be done before the key is changed, the returned call releases the lock once the change is reported
+func (hub *mutationHub) lockKey(key string) func() {
+	stripe := &hub.order[orderStripe(key)]
+	stripe.Lock()
+	return stripe.Unlock
+}
+
+// lockKeys call locks stripes of all the keys in the same order to avoid deadlocks
+func (hub *mutationHub) lockKeys(keys map[string]*StorableWithMeta) func() {
+	involved := make(map[int]struct{}, len(keys))
+	for key := range keys {
+		involved[orderStripe(key)] = struct{}{}
+	}
+	stripes := make([]int, 0, len(involved))
+	for stripe := range involved {
+		stripes = append(stripes, stripe)
+	}
+	sort.Ints(stripes)
+	for _, stripe := range stripes {
+		hub.order[stripe].Lock()
+	}
+	return func() {
+		for _, stripe := range stripes {
+			hub.order[stripe].Unlock()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mutationRecorder struct {
+	lock      sync.Mutex
+	mutations []Mutation
+}
+
+func (recorder *mutationRecorder) record(mutation Mutation) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.mutations = append(recorder.mutations, mutation)
+}
+
+func (recorder *mutationRecorder) types(key string) []MutationType {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	var types []MutationType
+	for _, mutation := range recorder.mutations {
+		if mutation.Key == key {
+			types = append(types, mutation.Type)
+		}
+	}
+	return types
+}
+
+func TestMutationsAreReported(t *testing.T) {
+	storages := map[string]PersistableStorage{
+		"syncmap": InitSyncMapStorage(time.Minute),
+		"sharded": InitShardedStorage(time.Minute, 4),
+	}
+	for name, testStorage := range storages {
+		recorder := new(mutationRecorder)
+		testStorage.AddMutationListener(recorder.record)
+
+		testStorage.AppendNewValue("key", "1")
+		testStorage.UpdateValueByKey("key", "2")
+		testStorage.IncrementBy("key", 1)
+		testStorage.DeleteValueByKey("key")
+		testStorage.DeleteValueByKey("key")
+		testStorage.AppendNewValueWithTTL("lazy", "1", 10*time.Millisecond)
+		testStorage.AppendNewValueWithTTL("active", "1", 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		testStorage.GetValueByKey("lazy")
+
+		activeExpire := testStorage.(ActiveExpireStorage)
+		activeExpire.StartVacuum(10 * time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		activeExpire.StopVacuum()
+
+		expected := []MutationType{MutationAppend, MutationUpdate, MutationUpdate, MutationDelete}
+		if types := recorder.types("key"); !equalMutationTypes(types, expected) {
+			t.Error(name + ": Changes of the entry are not reported properly")
+		}
+		if types := recorder.types("lazy"); !equalMutationTypes(types, []MutationType{MutationAppend, MutationExpire}) {
+			t.Error(name + ": Lazy expiration is not reported")
+		}
+		if types := recorder.types("active"); !equalMutationTypes(types, []MutationType{MutationAppend, MutationExpire}) {
+			t.Error(name + ": Active expiration is not reported")
+		}
+	}
+}
+
+func equalMutationTypes(actual []MutationType, expected []MutationType) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMutationsAreReportedInOrder(t *testing.T) {
+	storages := map[string]PersistableStorage{
+		"syncmap": InitSyncMapStorage(time.Minute),
+		"sharded": InitShardedStorage(time.Minute, 4),
+	}
+	for name, testStorage := range storages {
+		recorder := new(mutationRecorder)
+		// a slow listener gives concurrent writers a chance to overtake each other
+		testStorage.AddMutationListener(func(mutation Mutation) { runtime.Gosched() })
+		testStorage.AddMutationListener(recorder.record)
+
+		var wait sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				for j := 0; j < 500; j++ {
+					testStorage.IncrementBy("counter", 1)
+				}
+			}()
+		}
+		wait.Wait()
+
+		// increments are applied one after another, so their values should be reported in the same order
+		for i, mutation := range recorder.mutations {
+			if mutation.Entry.Entity != strconv.Itoa(i+1) {
+				t.Error(name + ": Changes of the same key are reported out of order")
+				break
+			}
+		}
+	}
+}
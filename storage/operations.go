@@ -0,0 +1,62 @@
+package storage
+
+// entryUpdate computes the new state of the entry from the current one. Current entry
+// is nil if there is no such key (or it is expired); returning nil removes the key and
+// returning current entry itself keeps the key untouched.
+// The function could be called more than once, so it should have no side effects
+// other than capturing results, and must never modify the current entry in place
+type entryUpdate func(current *StorableWithMeta) (*StorableWithMeta, error)
+
+// keyspace is a set of primitives each storage implementation provides,
+// so operations over particular data types could be shared between implementations
+type keyspace interface {
+	LazyExpireStorage
+
+	loadNotExpired(key string) (*StorableWithMeta, bool)
+
+	// updateEntry atomically applies the update to the entry and returns its new state
+	updateEntry(key string, update entryUpdate) (*StorableWithMeta, error)
+}
+
+// operations implements operations over particular data types on top of keyspace
+// primitives. It is embedded into every storage implementation
+type operations struct {
+	keyspace keyspace
+}
+
+// rewrap call creates the new state of the modified entry. Modification is a write,
+// so lifetime of the entry is prolonged keeping its TTL
+func (ops operations) rewrap(current *StorableWithMeta, entity Storable) *StorableWithMeta {
+	ttl := ops.keyspace.getTtl()
+	if current != nil {
+		ttl = current.TTL
+	}
+	return newStorableWithMeta(entity, ttl)
+}
+
+// mutationOf call chooses the type of mutation to report for the update
+func mutationOf(current *StorableWithMeta, updated *StorableWithMeta) MutationType {
+	if current == nil {
+		return MutationAppend
+	}
+	if updated == nil {
+		return MutationDelete
+	}
+	return MutationUpdate
+}
+
+// updateStored call is a part of updateEntry implementations: it returns the current
+// (not expired) entry and its new state. Nothing should be changed if they are the same
+func updateStored(stored *StorableWithMeta, update entryUpdate) (*StorableWithMeta, *StorableWithMeta, error) {
+	var current *StorableWithMeta
+	if stored != nil && notExpired(stored) {
+		current = stored
+	}
+	updated, err := update(current)
+	return current, updated, err
+}
+
+// sizeDelta call computes how much memory is needed to replace the entry
+func sizeDelta(key string, current *StorableWithMeta, updated *StorableWithMeta) int64 {
+	return entrySize(key, updated) - entrySize(key, current)
+}
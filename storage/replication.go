@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// replicationBacklog is the number of records a follower could lag behind before it is disconnected
+// and has to start over with a full sync, like Redis does with slow replicas
+const replicationBacklog = 10000
+
+// types of replication records which are not mutations. A full sync is sent between
+// replicationSync and replicationSynced records, replicationPing carries the offset of the leader
+const (
+	replicationSync   MutationType = "sync"
+	replicationSynced MutationType = "synced"
+	replicationPing   MutationType = "ping"
+)
+
+// ReplicationRecord is a mutation of the leader storage numbered by its offset. Like records
+// of the append-only file appends and updates carry the whole new state of the entry,
+// so applying a record twice gives the same result
+type ReplicationRecord struct {
+	Offset uint64        `json:"offset"`
+	Type   MutationType  `json:"type"`
+	Key    string        `json:"key,omitempty"`
+	Entry  *encodedEntry `json:"entry,omitempty"`
+}
+
+// ReplicationLog numbers mutations of the leader storage and streams them to the followers.
+// Records are not stored, every follower starts with a full sync of the storage
+type ReplicationLog struct {
+	storage PersistableStorage
+
+	lock      sync.Mutex
+	offset    uint64
+	followers map[*ReplicationStream]struct{}
+}
+
+// NewReplicationLog ...
+func NewReplicationLog(storage PersistableStorage) *ReplicationLog {
+	replication := new(ReplicationLog)
+	replication.storage = storage
+	replication.followers = make(map[*ReplicationStream]struct{})
+	storage.AddMutationListener(replication.append)
+	return replication
+}
+
+// Offset call returns the number of mutations made since the log is created
+func (replication *ReplicationLog) Offset() uint64 {
+	replication.lock.Lock()
+	defer replication.lock.Unlock()
+	return replication.offset
+}
+
+// Followers call returns the number of connected followers
+func (replication *ReplicationLog) Followers() int {
+	replication.lock.Lock()
+	defer replication.lock.Unlock()
+	return len(replication.followers)
+}
+
+// append call never blocks: followers which could not keep up are disconnected
+func (replication *ReplicationLog) append(mutation Mutation) {
+	record := ReplicationRecord{Type: mutation.Type, Key: mutation.Key}
+	if mutation.Entry != nil {
+		encoded, err := encodeEntry(mutation.Entry)
+		if err != nil {
+			log.Println("Can not replicate mutation of " + mutation.Key + ": " + err.Error())
+			return
+		}
+		record.Entry = encoded
+	}
+
+	replication.lock.Lock()
+	defer replication.lock.Unlock()
+	replication.offset++
+	record.Offset = replication.offset
+	for stream := range replication.followers {
+		select {
+		case stream.records <- record:
+		default:
+			replication.unfollow(stream)
+		}
+	}
+}
+
+// unfollow call should be done under the lock
+func (replication *ReplicationLog) unfollow(stream *ReplicationStream) {
+	if _, following := replication.followers[stream]; following {
+		delete(replication.followers, stream)
+		close(stream.records)
+	}
+}
+
+// ReplicationStream passes records of the leader to a single follower. Records channel is closed
+// once the stream is closed or the follower is too slow
+type ReplicationStream struct {
+	Records <-chan ReplicationRecord
+
+	replication *ReplicationLog
+	offset      uint64
+	records     chan ReplicationRecord
+}
+
+// Follow call registers a new follower. Mutations are buffered from now on,
+// so Sync should be called before the records are read
+func (replication *ReplicationLog) Follow() *ReplicationStream {
+	records := make(chan ReplicationRecord, replicationBacklog)
+	stream := &ReplicationStream{Records: records, replication: replication, records: records}
+
+	replication.lock.Lock()
+	defer replication.lock.Unlock()
+	stream.offset = replication.offset
+	replication.followers[stream] = struct{}{}
+	return stream
+}
+
+// Sync call passes all the entries of the storage to send as append records framed by sync and synced ones.
+// Entries changed during the call could get into the sync in either state, the following records fix them.
+// False is returned if send failed
+func (stream *ReplicationStream) Sync(send func(record ReplicationRecord) bool) bool {
+	if !send(ReplicationRecord{Offset: stream.offset, Type: replicationSync}) {
+		return false
+	}
+	sent := true
+	stream.replication.storage.RangeEntries(func(key string, entry *StorableWithMeta) bool {
+		encoded, err := encodeEntry(entry)
+		if err != nil {
+			log.Println("Can not replicate " + key + ": " + err.Error())
+			return true
+		}
+		sent = send(ReplicationRecord{Offset: stream.offset, Type: MutationAppend, Key: key, Entry: encoded})
+		return sent
+	})
+	return sent && send(ReplicationRecord{Offset: stream.offset, Type: replicationSynced})
+}
+
+// Ping call makes a record telling the follower the current offset of the leader
+func (stream *ReplicationStream) Ping() ReplicationRecord {
+	return ReplicationRecord{Offset: stream.replication.Offset(), Type: replicationPing}
+}
+
+// Close call stops the stream
+func (stream *ReplicationStream) Close() {
+	stream.replication.lock.Lock()
+	defer stream.replication.lock.Unlock()
+	stream.replication.unfollow(stream)
+}
+
+// ReplicaStatus describes how far the follower is behind the leader
+type ReplicaStatus struct {
+	Synced       bool
+	Offset       uint64
+	LeaderOffset uint64
+	LastContact  time.Time
+}
+
+// Replica applies records of the leader to the follower storage. Entries are restored as is,
+// keeping their versions, so listeners of the follower storage are notified about deletions only
+type Replica struct {
+	storage PersistableStorage
+
+	lock         sync.Mutex
+	syncing      map[string]struct{} // keys received during the full sync in progress
+	synced       bool
+	offset       uint64
+	leaderOffset uint64
+	lastContact  time.Time
+}
+
+// NewReplica ...
+func NewReplica(storage PersistableStorage) *Replica {
+	replica := new(Replica)
+	replica.storage = storage
+	return replica
+}
+
+// Apply call applies the record. Every full sync replaces the whole content of the storage
+func (replica *Replica) Apply(record ReplicationRecord) error {
+	replica.lock.Lock()
+	defer replica.lock.Unlock()
+	replica.lastContact = time.Now()
+
+	switch record.Type {
+	case replicationSync:
+		replica.syncing = make(map[string]struct{})
+		replica.synced = false
+		replica.offset = record.Offset
+		replica.leaderOffset = record.Offset
+	case replicationSynced:
+		if replica.syncing == nil {
+			return errors.New("Full sync is not started")
+		}
+		replica.removeStaleEntries()
+		replica.syncing = nil
+		replica.synced = true
+	case replicationPing:
+		replica.leaderOffset = record.Offset
+	default:
+		if replica.syncing != nil {
+			replica.syncing[record.Key] = struct{}{}
+		} else if replica.synced {
+			replica.offset = record.Offset
+		} else {
+			return errors.New("Record is received before full sync")
+		}
+		if err := applyRecord(replica.storage, &aofRecord{Type: record.Type, Key: record.Key, Entry: record.Entry}); err != nil {
+			return err
+		}
+	}
+	if replica.leaderOffset < replica.offset {
+		replica.leaderOffset = replica.offset
+	}
+	return nil
+}
+
+// removeStaleEntries call removes the entries which are not received during the full sync
+func (replica *Replica) removeStaleEntries() {
+	var stale []string
+	replica.storage.RangeEntries(func(key string, entry *StorableWithMeta) bool {
+		if _, received := replica.syncing[key]; !received {
+			stale = append(stale, key)
+		}
+		return true
+	})
+	for _, key := range stale {
+		replica.storage.DeleteValueByKey(key)
+	}
+}
+
+// Status ...
+func (replica *Replica) Status() ReplicaStatus {
+	replica.lock.Lock()
+	defer replica.lock.Unlock()
+	return ReplicaStatus{replica.synced, replica.offset, replica.leaderOffset, replica.lastContact}
+}
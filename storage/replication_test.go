@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// drain call applies all the buffered records of the stream to the replica
+func drain(t *testing.T, stream *ReplicationStream, replica *Replica) {
+	for {
+		select {
+		case record := <-stream.Records:
+			if err := replica.Apply(record); err != nil {
+				t.Fatal("Can not apply record. " + err.Error())
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestReplication(t *testing.T) {
+	leader := InitSyncMapStorage(time.Minute)
+	follower := InitShardedStorage(time.Minute, 4)
+	replication := NewReplicationLog(leader)
+	replica := NewReplica(follower)
+
+	leader.AppendNewValue("str", "Lorem ipsum")
+	leader.AppendNewValueWithTTL("arr", []string{"Alpha", "Bravo"}, time.Hour)
+	follower.AppendNewValue("stale", "Not on the leader")
+
+	stream := replication.Follow()
+	defer stream.Close()
+	leader.UpdateValueByKey("str", "Dolor sit amet")
+	if !stream.Sync(func(record ReplicationRecord) bool { return replica.Apply(record) == nil }) {
+		t.Fatal("Full sync failed")
+	}
+	if status := replica.Status(); !status.Synced || status.Offset != 2 {
+		t.Errorf("Unexpected status after full sync: %+v", status)
+	}
+	if _, exists := follower.GetValueByKey("stale"); exists {
+		t.Error("Entry absent on the leader is kept by full sync")
+	}
+
+	leader.AppendNewValue("dic", map[string]string{"1": "One"})
+	leader.DeleteValueByKey("arr")
+	drain(t, stream, replica)
+
+	leaderKeys, followerKeys := leader.GetAllKeys(), follower.GetAllKeys()
+	sort.Strings(leaderKeys)
+	sort.Strings(followerKeys)
+	if !reflect.DeepEqual(leaderKeys, followerKeys) {
+		t.Errorf("Keys differ: %v and %v", leaderKeys, followerKeys)
+	}
+	original, _ := leader.GetValueByKey("str")
+	replicated, _ := follower.GetValueByKey("str")
+	if replicated.Entity != "Dolor sit amet" || replicated.Version != original.Version {
+		t.Error("Entry is not replicated with its version")
+	}
+	if status := replica.Status(); status.Offset != replication.Offset() || status.LeaderOffset != status.Offset {
+		t.Errorf("Follower is behind: %+v, leader offset %d", status, replication.Offset())
+	}
+
+	leader.AppendNewValue("new", "value")
+	replica.Apply(stream.Ping())
+	if status := replica.Status(); status.LeaderOffset != status.Offset+1 {
+		t.Errorf("Lag is not reported: %+v", status)
+	}
+}
+
+func TestSlowFollowerIsDisconnected(t *testing.T) {
+	leader := InitSyncMapStorage(time.Minute)
+	replication := NewReplicationLog(leader)
+	leader.AppendNewValue("key", "value")
+	stream := replication.Follow()
+	for i := 0; i <= replicationBacklog; i++ {
+		leader.UpdateValueByKey("key", "value")
+	}
+	if replication.Followers() != 0 {
+		t.Error("Slow follower is not disconnected")
+	}
+	received := 0
+	for range stream.Records {
+		received++
+	}
+	if received != replicationBacklog {
+		t.Errorf("%d records received before disconnection", received)
+	}
+	stream.Close()
+}
+
+func TestRecordBeforeSyncIsRejected(t *testing.T) {
+	replica := NewReplica(InitSyncMapStorage(time.Minute))
+	if err := replica.Apply(ReplicationRecord{Offset: 1, Type: MutationDelete, Key: "key"}); err == nil {
+		t.Error("Record is applied before full sync")
+	}
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"container/heap"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/izhamoidsin/gedis/glob"
+)
+
+// ScanFilter limits keys returned by Scan. Empty Match and Type mean any key and any type
+type ScanFilter struct {
+	// Match is a glob pattern (`*`, `?`, `[...]` and `\` escapes) the keys should match, `*` matches `/` as well
+	Match string
+	// Type is a name of the value type as returned by TypeOf
+	Type string
+}
+
+// ScanStorage iterates over the keys incrementally, page by page
+type ScanStorage interface {
+	// Scan visits count keys starting at the cursor (zero for the first page) and returns the ones passing
+	// the filter along with the cursor of the next page, which is zero if there are no more keys. So pages
+	// of filtered scans could have less keys or none at all. Like Redis SCAN does, it guarantees that a key
+	// present during the whole iteration is returned, and returned only once, while keys added or removed
+	// meanwhile could be returned or not
+	Scan(cursor uint64, count int, filter ScanFilter) ([]string, uint64, error)
+}
+
+// ScanHash call returns the hash keys are ordered by, so the cursor is the least hash of the keys of the page
+// and the order does not depend on keys added or removed between the calls
+func ScanHash(key string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return hash.Sum64()
+}
+
+// scanIndex keeps the keys ordered by ScanHash in a persistent treap, so a page of Scan starts at the cursor
+// in logarithmic time, and pages are read from a version of the index without blocking writers
+type scanIndex struct {
+	lock sync.Mutex
+	root *treapNode
+}
+
+func scanBefore(hash uint64, key string) treapLeft {
+	return func(node *treapNode) bool {
+		nodeHash := ScanHash(node.member)
+		return nodeHash < hash || (nodeHash == hash && node.member < key)
+	}
+}
+
+func scanNotAfter(hash uint64, key string) treapLeft {
+	return func(node *treapNode) bool {
+		nodeHash := ScanHash(node.member)
+		return nodeHash < hash || (nodeHash == hash && node.member <= key)
+	}
+}
+
+// track call should be done along with accountEntry on every change of the storage,
+// changes of the same key should not be reordered
+func (index *scanIndex) track(key string, previous *StorableWithMeta, current *StorableWithMeta) {
+	if (previous == nil) == (current == nil) {
+		return
+	}
+	hash := ScanHash(key)
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	if current != nil {
+		index.root = treapInsert(index.root, newTreapNode(key, 0), scanBefore(hash, key))
+	} else {
+		index.root = treapDelete(index.root, scanBefore(hash, key), scanNotAfter(hash, key))
+	}
+}
+
+func (index *scanIndex) tree() *treapNode {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	return index.root
+}
+
+type scannedKey struct {
+	hash uint64
+	key  string
+}
+
+// scanPage is a max-heap of the keys with the least hashes seen so far
+type scanPage []scannedKey
+
+func (page scanPage) Len() int            { return len(page) }
+func (page scanPage) Less(i, j int) bool  { return page[i].hash > page[j].hash }
+func (page scanPage) Swap(i, j int)       { page[i], page[j] = page[j], page[i] }
+func (page *scanPage) Push(x interface{}) { *page = append(*page, x.(scannedKey)) }
+func (page *scanPage) Pop() interface{} {
+	old := *page
+	last := old[len(old)-1]
+	*page = old[:len(old)-1]
+	return last
+}
+
+// matches call checks the filter against the entry
+func (filter ScanFilter) matches(key string, entry *StorableWithMeta) bool {
+	if filter.Type != "" && TypeOf(entry.Entity) != filter.Type {
+		return false
+	}
+	if filter.Match != "" {
+		matched, _ := glob.Match(filter.Match, key)
+		return matched
+	}
+	return true
+}
+
+func (filter ScanFilter) validate() error {
+	if err := glob.Validate(filter.Match); err != nil {
+		return errors.New("Malformed pattern " + filter.Match)
+	}
+	switch filter.Type {
+	case "", stringKind, listKind, dictKind, setKind, sortedSetKind:
+		return nil
+	}
+	return errors.New("Unknown type " + filter.Type)
+}
+
+// scanKeys call is shared by storage implementations, which keep one or a few indexes (e.g. one per shard).
+// Every index is walked from the cursor until count keys are visited, the page holds the keys of all
+// the indexes with hashes less than the first one not visited yet, up to count of them
+func scanKeys(indexes []*treapNode, load func(key string) *StorableWithMeta, cursor uint64, count int, filter ScanFilter) ([]string, uint64, error) {
+	if count < 1 {
+		return nil, 0, errors.New("Count should be positive")
+	}
+	if err := filter.validate(); err != nil {
+		return nil, 0, err
+	}
+
+	page := make(scanPage, 0, count+1)
+	more := false
+	var leastSkipped uint64
+	skip := func(hash uint64) {
+		if !more || hash < leastSkipped {
+			leastSkipped = hash
+		}
+		more = true
+	}
+	for _, root := range indexes {
+		visited := 0
+		var lastHash uint64
+		treapAscend(root, treapCount(root, scanBefore(cursor, "")), func(node *treapNode) bool {
+			hash := ScanHash(node.member)
+			// keys sharing the hash are visited at once, so none of them is lost
+			if visited >= count && hash != lastHash {
+				skip(hash)
+				return false
+			}
+			visited++
+			lastHash = hash
+			if entry := load(node.member); entry != nil && notExpired(entry) && filter.matches(node.member, entry) {
+				heap.Push(&page, scannedKey{hash, node.member})
+				if len(page) > count {
+					skip(heap.Pop(&page).(scannedKey).hash)
+				}
+			}
+			return true
+		})
+	}
+
+	sort.Slice(page, func(i, j int) bool { return page[i].hash < page[j].hash })
+	next := uint64(0)
+	if more {
+		// keys sharing the hash with a skipped one are left for the next page, so none of them is lost
+		next = leastSkipped
+		end := sort.Search(len(page), func(i int) bool { return page[i].hash >= leastSkipped })
+		if end == 0 && leastSkipped == cursor {
+			// the whole page shares the hash of the cursor (which is unlikely for 64 bits), skipped keys are lost then
+			end, next = len(page), leastSkipped+1
+		}
+		page = page[:end]
+	}
+
+	keys := make([]string, len(page))
+	for i, scanned := range page {
+		keys[i] = scanned.key
+	}
+	return keys, next, nil
+}
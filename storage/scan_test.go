@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"strconv"
+	"testing"
+)
+
+func scanAll(testStorage Storage, count int, filter ScanFilter, betweenPages func()) (map[string]int, error) {
+	seen := make(map[string]int)
+	cursor := uint64(0)
+	for {
+		keys, next, err := testStorage.Scan(cursor, count, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			seen[key]++
+		}
+		if next == 0 {
+			return seen, nil
+		}
+		cursor = next
+		betweenPages()
+	}
+}
+
+func TestScanReturnsEveryKeyOnce(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		for i := 0; i < 100; i++ {
+			testStorage.AppendNewValue("user:"+strconv.Itoa(i), "value")
+			testStorage.PushToList("list:"+strconv.Itoa(i), false, "value")
+		}
+
+		seen, err := scanAll(testStorage, 7, ScanFilter{}, func() {})
+		if err != nil || len(seen) != 200 {
+			t.Error(name + ": Not all the keys are scanned")
+		}
+		for key, times := range seen {
+			if times != 1 {
+				t.Error(name + ": Key " + key + " is scanned " + strconv.Itoa(times) + " times")
+			}
+		}
+
+		if seen, _ := scanAll(testStorage, 10, ScanFilter{Match: "user:1*"}, func() {}); len(seen) != 11 {
+			t.Error(name + ": Keys are not filtered by the pattern")
+		}
+		if seen, _ := scanAll(testStorage, 10, ScanFilter{Match: "*:1*", Type: listKind}, func() {}); len(seen) != 11 || seen["list:1"] != 1 {
+			t.Error(name + ": Keys are not filtered by the type")
+		}
+		if _, _, err := testStorage.Scan(0, 10, ScanFilter{Type: "unknown"}); err == nil {
+			t.Error(name + ": Unknown type is accepted")
+		}
+		for _, key := range []string{"users/1/name", "users/1/age", "users/2/name", "users"} {
+			testStorage.AppendNewValue(key, "value")
+		}
+		if seen, _ := scanAll(testStorage, 10, ScanFilter{Match: "users/*"}, func() {}); len(seen) != 3 || seen["users/1/name"] != 1 {
+			t.Error(name + ": Star does not match slashes of the keys")
+		}
+		if seen, _ := scanAll(testStorage, 10, ScanFilter{Match: "*/name"}, func() {}); len(seen) != 2 || seen["users/2/name"] != 1 {
+			t.Error(name + ": Keys with slashes are not filtered by the pattern")
+		}
+		if _, _, err := testStorage.Scan(0, 10, ScanFilter{Match: "[a-"}); err == nil {
+			t.Error(name + ": Malformed pattern is accepted")
+		}
+	}
+}
+
+func TestScanUnderConcurrentModification(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		for i := 0; i < 100; i++ {
+			testStorage.AppendNewValue("stable:"+strconv.Itoa(i), "value")
+			testStorage.AppendNewValue("volatile:"+strconv.Itoa(i), "value")
+		}
+
+		added := 0
+		seen, _ := scanAll(testStorage, 5, ScanFilter{}, func() {
+			testStorage.DeleteValueByKey("volatile:" + strconv.Itoa(added))
+			testStorage.AppendNewValue("added:"+strconv.Itoa(added), "value")
+			added++
+		})
+		for i := 0; i < 100; i++ {
+			if seen["stable:"+strconv.Itoa(i)] != 1 {
+				t.Error(name + ": Key present during the whole scan is not returned exactly once")
+			}
+		}
+	}
+}
+
+func TestScanPageIsBounded(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		for i := 0; i < 1000; i++ {
+			testStorage.AppendNewValue("key:"+strconv.Itoa(i), "value")
+		}
+		// a page visits count keys only, even if none of them passes the filter
+		if keys, next, err := testStorage.Scan(0, 10, ScanFilter{Match: "missing:*"}); err != nil || len(keys) != 0 || next == 0 {
+			t.Error(name + ": Filtered page is not limited by count")
+		}
+		if seen, _ := scanAll(testStorage, 10, ScanFilter{Match: "key:99*"}, func() {}); len(seen) != 11 {
+			t.Error(name + ": Keys are lost by pages without matching keys")
+		}
+	}
+}
+
+func TestScanIndexFollowsChanges(t *testing.T) {
+	indexed := func(testStorage Storage) int {
+		switch typed := testStorage.(type) {
+		case *SyncMapStorage:
+			return typed.index.tree().count()
+		case *ShardedStorage:
+			count := 0
+			for _, shard := range typed.shards {
+				count += shard.index.tree().count()
+			}
+			return count
+		}
+		return -1
+	}
+	for name, testStorage := range testStorages() {
+		for i := 0; i < 100; i++ {
+			testStorage.AppendNewValue("key:"+strconv.Itoa(i), "value")
+			testStorage.UpdateValueByKey("key:"+strconv.Itoa(i), "updated")
+		}
+		for i := 0; i < 50; i++ {
+			testStorage.DeleteValueByKey("key:" + strconv.Itoa(i))
+		}
+		testStorage.ExecTransaction(nil, func(tx *Tx) error {
+			tx.DeleteValueByKey("key:50")
+			tx.SetValueByKey("tx:key", "value", 0)
+			return nil
+		})
+		if count := indexed(testStorage); count != 50 {
+			t.Errorf("%s: Index has %d keys instead of 50", name, count)
+		}
+	}
+}
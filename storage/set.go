@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Set is an unordered collection of unique strings. Its JSON representation is a sorted array,
+// so it could not be distinguished from a list by JSON itself
+type Set map[string]struct{}
+
+// NewSet ...
+func NewSet(members ...string) Set {
+	set := make(Set, len(members))
+	for _, member := range members {
+		set[member] = struct{}{}
+	}
+	return set
+}
+
+// Contains ...
+func (set Set) Contains(member string) bool {
+	_, contains := set[member]
+	return contains
+}
+
+// Members call returns members of the set sorted
+func (set Set) Members() []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// MarshalJSON ...
+func (set Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set.Members())
+}
+
+// UnmarshalJSON ...
+func (set *Set) UnmarshalJSON(data []byte) error {
+	var members []string
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	*set = NewSet(members...)
+	return nil
+}
+
+// TypeOf call returns the name of the stored value type: string, list, dict, set or zset
+func TypeOf(entity Storable) string {
+	switch entity.(type) {
+	case string:
+		return stringKind
+	case []string:
+		return listKind
+	case map[string]string:
+		return dictKind
+	case Set:
+		return setKind
+	case *SortedSet:
+		return sortedSetKind
+	}
+	return ""
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+)
+
+// SetOperation is a kind of set algebra operation
+type SetOperation string
+
+// supported set algebra operations
+const (
+	SetUnion        SetOperation = "union"
+	SetIntersection SetOperation = "intersection"
+	SetDifference   SetOperation = "difference"
+)
+
+// SetStorage contains atomic operations over set entries.
+// Sets are created on add and removed once they get empty
+type SetStorage interface {
+	// AddToSet returns the number of members which were not in the set before
+	AddToSet(key string, members ...string) (int, error)
+
+	// RemoveFromSet returns the number of members which were in the set
+	RemoveFromSet(key string, members ...string) (int, error)
+
+	IsSetMember(key string, member string) (bool, error)
+
+	GetSetCardinality(key string) (int, error)
+
+	// GetSetMembers returns members sorted
+	GetSetMembers(key string) ([]string, error)
+
+	// GetRandomSetMembers returns up to count distinct members
+	GetRandomSetMembers(key string, count int) ([]string, error)
+
+	// PopRandomSetMembers removes and returns up to count distinct members
+	PopRandomSetMembers(key string, count int) ([]string, error)
+
+	// CombineSets returns sorted members of the union, intersection or difference of the sets.
+	// Missing keys are considered as empty sets
+	CombineSets(operation SetOperation, keys ...string) ([]string, error)
+
+	// StoreCombinedSets works as CombineSets but stores the result into destination key
+	// and returns its cardinality. Sources are read one by one, so the result could be
+	// inconsistent if they are modified concurrently
+	StoreCombinedSets(operation SetOperation, destination string, keys ...string) (int, error)
+}
+
+// setOf call extracts the set from the entry, nil entry is considered as an empty set
+func setOf(entry *StorableWithMeta) (Set, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	set, yes := entry.Entity.(Set)
+	if !yes {
+		return nil, errors.New("Stored value is not a set")
+	}
+	return set, nil
+}
+
+// copySet call is used to modify sets, as stored ones are shared with readers
+func copySet(set Set, extraCapacity int) Set {
+	copied := make(Set, len(set)+extraCapacity)
+	for member := range set {
+		copied[member] = struct{}{}
+	}
+	return copied
+}
+
+// withSet call replaces the set of the entry removing the entry if the set is empty
+func (ops operations) withSet(current *StorableWithMeta, set Set) *StorableWithMeta {
+	if len(set) == 0 {
+		return nil
+	}
+	return ops.rewrap(current, set)
+}
+
+// randomMembers call picks up to count distinct members of the set
+func randomMembers(set Set, count int) []string {
+	members := set.Members()
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	if count < len(members) {
+		members = members[:count]
+	}
+	return members
+}
+
+// AddToSet ...
+func (ops operations) AddToSet(key string, members ...string) (int, error) {
+	if len(members) == 0 {
+		return 0, errors.New("There are no members to add")
+	}
+
+	var added int
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		set, err := setOf(current)
+		if err != nil {
+			return nil, err
+		}
+		modified := copySet(set, len(members))
+		for _, member := range members {
+			modified[member] = struct{}{}
+		}
+		added = len(modified) - len(set)
+		if added == 0 {
+			return current, nil
+		}
+		return ops.withSet(current, modified), nil
+	})
+	return added, err
+}
+
+// RemoveFromSet ...
+func (ops operations) RemoveFromSet(key string, members ...string) (int, error) {
+	var removed int
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		set, err := setOf(current)
+		if err != nil {
+			return nil, err
+		}
+		modified := copySet(set, 0)
+		for _, member := range members {
+			delete(modified, member)
+		}
+		removed = len(set) - len(modified)
+		if removed == 0 {
+			return current, nil
+		}
+		return ops.withSet(current, modified), nil
+	})
+	return removed, err
+}
+
+// IsSetMember ...
+func (ops operations) IsSetMember(key string, member string) (bool, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	set, err := setOf(entry)
+	return set.Contains(member), err
+}
+
+// GetSetCardinality ...
+func (ops operations) GetSetCardinality(key string) (int, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	set, err := setOf(entry)
+	return len(set), err
+}
+
+// GetSetMembers ...
+func (ops operations) GetSetMembers(key string) ([]string, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	set, err := setOf(entry)
+	if err != nil {
+		return nil, err
+	}
+	return set.Members(), nil
+}
+
+// GetRandomSetMembers ...
+func (ops operations) GetRandomSetMembers(key string, count int) ([]string, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	set, err := setOf(entry)
+	if err != nil {
+		return nil, err
+	}
+	return randomMembers(set, count), nil
+}
+
+// PopRandomSetMembers ...
+func (ops operations) PopRandomSetMembers(key string, count int) ([]string, error) {
+	var popped []string
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		set, err := setOf(current)
+		if err != nil {
+			return nil, err
+		}
+		popped = randomMembers(set, count)
+		if len(popped) == 0 {
+			return current, nil
+		}
+		modified := copySet(set, 0)
+		for _, member := range popped {
+			delete(modified, member)
+		}
+		return ops.withSet(current, modified), nil
+	})
+	return popped, err
+}
+
+// combineSets call reads the sets one by one and combines them
+func (ops operations) combineSets(operation SetOperation, keys []string) (Set, error) {
+	if operation != SetUnion && operation != SetIntersection && operation != SetDifference {
+		return nil, errors.New("Unknown set operation: " + string(operation))
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("There are no sets to combine")
+	}
+
+	sets := make([]Set, len(keys))
+	for i, key := range keys {
+		entry, _ := ops.keyspace.loadNotExpired(key)
+		set, err := setOf(entry)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	combined := copySet(sets[0], 0)
+	for _, set := range sets[1:] {
+		switch operation {
+		case SetUnion:
+			for member := range set {
+				combined[member] = struct{}{}
+			}
+		case SetIntersection:
+			for member := range combined {
+				if !set.Contains(member) {
+					delete(combined, member)
+				}
+			}
+		case SetDifference:
+			for member := range set {
+				delete(combined, member)
+			}
+		}
+	}
+	return combined, nil
+}
+
+// CombineSets ...
+func (ops operations) CombineSets(operation SetOperation, keys ...string) ([]string, error) {
+	combined, err := ops.combineSets(operation, keys)
+	if err != nil {
+		return nil, err
+	}
+	return combined.Members(), nil
+}
+
+// StoreCombinedSets ...
+func (ops operations) StoreCombinedSets(operation SetOperation, destination string, keys ...string) (int, error) {
+	combined, err := ops.combineSets(operation, keys)
+	if err != nil {
+		return 0, err
+	}
+	// like Redis does, destination is overridden regardless of its type and TTL
+	// and removed if the result is empty
+	_, err = ops.keyspace.updateEntry(destination, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		return ops.withSet(nil, combined), nil
+	})
+	return len(combined), err
+}
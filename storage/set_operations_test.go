@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSetAddRemoveAndMembership(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key := "set"
+		if added, err := testStorage.AddToSet(key, "Alpha", "Bravo", "Alpha"); err != nil || added != 2 {
+			t.Error(name + ": Can not add members to a new set")
+		}
+		if added, _ := testStorage.AddToSet(key, "Bravo", "Charlie"); added != 1 {
+			t.Error(name + ": Existing members are counted as added")
+		}
+		if members, err := testStorage.GetSetMembers(key); err != nil || !reflect.DeepEqual(members, []string{"Alpha", "Bravo", "Charlie"}) {
+			t.Error(name + ": Members of the set do not match")
+		}
+		if isMember, err := testStorage.IsSetMember(key, "Bravo"); err != nil || !isMember {
+			t.Error(name + ": Member of the set is not found")
+		}
+		if cardinality, _ := testStorage.GetSetCardinality(key); cardinality != 3 {
+			t.Error(name + ": Cardinality of the set does not match")
+		}
+		if removed, err := testStorage.RemoveFromSet(key, "Bravo", "Delta"); err != nil || removed != 1 {
+			t.Error(name + ": Can not remove members of the set")
+		}
+		if stored, ok := testStorage.GetValueByKey(key); !ok || !reflect.DeepEqual(stored.Entity, NewSet("Alpha", "Charlie")) {
+			t.Error(name + ": Stored value is not a set")
+		}
+
+		testStorage.RemoveFromSet(key, "Alpha", "Charlie")
+		if _, ok := testStorage.GetValueByKey(key); ok {
+			t.Error(name + ": Empty set is not removed")
+		}
+	}
+}
+
+func TestSetRandomMembers(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key := "set"
+		testStorage.AddToSet(key, "Alpha", "Bravo", "Charlie")
+
+		if members, err := testStorage.GetRandomSetMembers(key, 2); err != nil || len(members) != 2 || members[0] == members[1] {
+			t.Error(name + ": Random members are not distinct")
+		}
+		if members, _ := testStorage.GetRandomSetMembers(key, 10); len(members) != 3 {
+			t.Error(name + ": Not all the members are returned when count exceeds cardinality")
+		}
+
+		popped, err := testStorage.PopRandomSetMembers(key, 2)
+		if err != nil || len(popped) != 2 {
+			t.Error(name + ": Can not pop random members")
+		}
+		remaining, _ := testStorage.GetSetMembers(key)
+		all := append(remaining, popped...)
+		sort.Strings(all)
+		if !reflect.DeepEqual(all, []string{"Alpha", "Bravo", "Charlie"}) {
+			t.Error(name + ": Popped members are not removed from the set")
+		}
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AddToSet("a", "1", "2", "3")
+		testStorage.AddToSet("b", "2", "3", "4")
+		testStorage.AddToSet("c", "3", "5")
+		testStorage.AppendNewValue("str", "Not a set")
+
+		if union, err := testStorage.CombineSets(SetUnion, "a", "b", "missing"); err != nil || !reflect.DeepEqual(union, []string{"1", "2", "3", "4"}) {
+			t.Error(name + ": Union of the sets does not match")
+		}
+		if intersection, _ := testStorage.CombineSets(SetIntersection, "a", "b", "c"); !reflect.DeepEqual(intersection, []string{"3"}) {
+			t.Error(name + ": Intersection of the sets does not match")
+		}
+		if difference, _ := testStorage.CombineSets(SetDifference, "a", "b"); !reflect.DeepEqual(difference, []string{"1"}) {
+			t.Error(name + ": Difference of the sets does not match")
+		}
+		if _, err := testStorage.CombineSets(SetUnion, "a", "str"); err == nil {
+			t.Error(name + ": Combining a set with a string value does not lead to error")
+		}
+
+		if cardinality, err := testStorage.StoreCombinedSets(SetUnion, "str", "a", "c"); err != nil || cardinality != 4 {
+			t.Error(name + ": Can not store union of the sets")
+		}
+		if members, _ := testStorage.GetSetMembers("str"); !reflect.DeepEqual(members, []string{"1", "2", "3", "5"}) {
+			t.Error(name + ": Stored union does not match")
+		}
+		testStorage.StoreCombinedSets(SetDifference, "str", "c", "a")
+		testStorage.StoreCombinedSets(SetDifference, "str", "c", "c")
+		if _, ok := testStorage.GetValueByKey("str"); ok {
+			t.Error(name + ": Empty result is stored")
+		}
+	}
+}
+
+func TestSetEncoding(t *testing.T) {
+	entry := newStorableWithMeta(NewSet("Alpha", "Bravo"), time.Minute)
+	encoded, err := encodeEntry(entry)
+	if err != nil {
+		t.Fatal("Can not encode the set: " + err.Error())
+	}
+	decoded, err := decodeEntry(encoded)
+	if err != nil || !reflect.DeepEqual(decoded.Entity, entry.Entity) {
+		t.Error("Decoded set does not match the encoded one")
+	}
+}
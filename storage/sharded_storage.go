@@ -0,0 +1,411 @@
+package storage
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ShardedStorage is a Redis-like storage model which spreads keys over a number of maps
+// each guarded by its own RWMutex. Unlike SyncMapStorage it does not suffer from
+// write-heavy workloads as writers of different shards do not block each other
+type ShardedStorage struct {
+	ttl    time.Duration
+	shards []*storageShard
+	*vacuum
+	*memoryLimiter
+	mutationHub
+	operations
+}
+
+type storageShard struct {
+	lock    sync.RWMutex
+	entries map[string]*StorableWithMeta
+	// the index is changed under the write lock of the shard
+	index scanIndex
+}
+
+// InitShardedStorage ...
+func InitShardedStorage(ttl time.Duration, shardsCount int) *ShardedStorage {
+	if shardsCount < 1 {
+		shardsCount = 1
+	}
+	newStorage := new(ShardedStorage)
+	newStorage.ttl = ttl
+	newStorage.shards = make([]*storageShard, shardsCount)
+	for i := range newStorage.shards {
+		newStorage.shards[i] = &storageShard{entries: make(map[string]*StorableWithMeta)}
+	}
+	newStorage.vacuum = newVacuum(newStorage)
+	newStorage.memoryLimiter = newMemoryLimiter(newStorage)
+	newStorage.operations = operations{newStorage}
+
+	return newStorage
+}
+
+func (ss *ShardedStorage) getTtl() time.Duration {
+	return ss.ttl
+}
+
+func (ss *ShardedStorage) shardIndex(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(ss.shards)))
+}
+
+func (ss *ShardedStorage) shardFor(key string) *storageShard {
+	return ss.shards[ss.shardIndex(key)]
+}
+
+// samples are taken from shards starting with a random one, iteration order of the maps is random as well
+func (ss *ShardedStorage) sampleEntries(n int) map[string]*StorableWithMeta {
+	sample := make(map[string]*StorableWithMeta, n)
+	first := rand.Intn(len(ss.shards))
+	for i := 0; i < len(ss.shards) && len(sample) < n; i++ {
+		shard := ss.shards[(first+i)%len(ss.shards)]
+		shard.lock.RLock()
+		for key, entry := range shard.entries {
+			if len(sample) >= n {
+				break
+			}
+			sample[key] = entry
+		}
+		shard.lock.RUnlock()
+	}
+	return sample
+}
+
+func (ss *ShardedStorage) removeEntry(key string, entry *StorableWithMeta, mutationType MutationType) bool {
+	defer ss.lockKey(key)()
+	shard := ss.shardFor(key)
+	shard.lock.Lock()
+	// the entry could be overridden since it was loaded, so it is removed only if it is still the same
+	removed := shard.entries[key] == entry
+	if removed {
+		delete(shard.entries, key)
+		ss.accountEntry(key, entry, nil)
+		shard.index.track(key, entry, nil)
+	}
+	shard.lock.Unlock()
+
+	// listeners are notified outside of the shard lock, so they are free to read the storage
+	if removed {
+		ss.emit(mutationType, key, nil)
+	}
+	return removed
+}
+
+// updateEntry call computes the update outside of the lock, so eviction is free to take
+// shard locks, and retries it if the entry is changed concurrently
+func (ss *ShardedStorage) updateEntry(key string, update entryUpdate) (*StorableWithMeta, error) {
+	shard := ss.shardFor(key)
+	for {
+		shard.lock.RLock()
+		stored := shard.entries[key]
+		shard.lock.RUnlock()
+
+		current, updated, err := updateStored(stored, update)
+		if err != nil {
+			return nil, err
+		}
+		if updated == current {
+			return current, nil
+		}
+		if err := ss.freeMemory(sizeDelta(key, current, updated)); err != nil {
+			return nil, err
+		}
+
+		unlockKey := ss.lockKey(key)
+		shard.lock.Lock()
+		if shard.entries[key] != stored {
+			shard.lock.Unlock()
+			unlockKey()
+			continue
+		}
+		if updated == nil {
+			delete(shard.entries, key)
+		} else {
+			shard.entries[key] = updated
+		}
+		ss.accountEntry(key, stored, updated)
+		shard.index.track(key, stored, updated)
+		shard.lock.Unlock()
+
+		ss.emit(mutationOf(current, updated), key, updated)
+		unlockKey()
+		return updated, nil
+	}
+}
+
+func (ss *ShardedStorage) loadStored(key string) *StorableWithMeta {
+	shard := ss.shardFor(key)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+	return shard.entries[key]
+}
+
+// commitEntries call locks all the shards of the transaction in the same order to avoid deadlocks
+func (ss *ShardedStorage) commitEntries(read map[string]*StorableWithMeta, written map[string]*StorableWithMeta) (bool, error) {
+	if err := ss.freeMemory(commitDelta(read, written)); err != nil {
+		return false, err
+	}
+
+	involved := make(map[int]struct{}, len(read))
+	for key := range read {
+		involved[ss.shardIndex(key)] = struct{}{}
+	}
+	indexes := make([]int, 0, len(involved))
+	for index := range involved {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	defer ss.lockKeys(read)()
+	for _, index := range indexes {
+		ss.shards[index].lock.Lock()
+	}
+	unlock := func() {
+		for _, index := range indexes {
+			ss.shards[index].lock.Unlock()
+		}
+	}
+
+	for key, stored := range read {
+		if ss.shardFor(key).entries[key] != stored {
+			unlock()
+			return false, nil
+		}
+	}
+	for key, updated := range written {
+		shard := ss.shardFor(key)
+		if updated == nil {
+			delete(shard.entries, key)
+		} else {
+			shard.entries[key] = updated
+		}
+		ss.accountEntry(key, read[key], updated)
+		shard.index.track(key, read[key], updated)
+	}
+	unlock()
+
+	ss.emitCommitted(read, written)
+	return true, nil
+}
+
+// ExecTransaction ...
+func (ss *ShardedStorage) ExecTransaction(watched map[string]uint64, transaction Transaction) error {
+	return execTransaction(ss, watched, transaction)
+}
+
+// loadNotExpired call loads the entry and removes it if it is already expired
+func (ss *ShardedStorage) loadNotExpired(key string) (*StorableWithMeta, bool) {
+	shard := ss.shardFor(key)
+	shard.lock.RLock()
+	entry, exists := shard.entries[key]
+	shard.lock.RUnlock()
+
+	if exists {
+		if notExpired(entry) {
+			entry.touch()
+			return entry, true
+		}
+		if !ss.keepsExpired() && ss.removeEntry(key, entry, MutationExpire) {
+			ss.lazyExpired()
+		}
+	}
+	return nil, false
+}
+
+// GetAllKeys ....
+func (ss *ShardedStorage) GetAllKeys() []string {
+	keys := make([]string, 0, 16)
+	ss.RangeEntries(func(key string, entry *StorableWithMeta) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Scan call walks the indexes of all the shards, so the page could hold keys of any of them
+func (ss *ShardedStorage) Scan(cursor uint64, count int, filter ScanFilter) ([]string, uint64, error) {
+	indexes := make([]*treapNode, len(ss.shards))
+	for i, shard := range ss.shards {
+		indexes[i] = shard.index.tree()
+	}
+	return scanKeys(indexes, ss.loadStored, cursor, count, filter)
+}
+
+// GetValueByKey ....
+func (ss *ShardedStorage) GetValueByKey(key string) (*StorableWithMeta, bool) {
+	return ss.loadNotExpired(key)
+}
+
+// DeleteValueByKey ...
+func (ss *ShardedStorage) DeleteValueByKey(key string) bool {
+	defer ss.lockKey(key)()
+	shard := ss.shardFor(key)
+	shard.lock.Lock()
+	previous, existed := shard.entries[key]
+	if existed {
+		delete(shard.entries, key)
+		ss.accountEntry(key, previous, nil)
+		shard.index.track(key, previous, nil)
+	}
+	shard.lock.Unlock()
+
+	if existed {
+		ss.emit(MutationDelete, key, nil)
+	}
+	return existed
+}
+
+// GetNestedValueByKeyAndIndex ....
+func (ss *ShardedStorage) GetNestedValueByKeyAndIndex(key string, index int) (*StorableWithMeta, bool, error) {
+	if swm, exists := ss.loadNotExpired(key); exists {
+		slice, yes := swm.Entity.([]string)
+		if yes {
+			if index >= 0 && index < len(slice) {
+				return enpackStorable(slice[index], swm), true, nil
+			}
+			return nil, false, errors.New("Index out of range")
+		}
+		return nil, false, errors.New("Stored value is not an array")
+	}
+	return nil, false, nil
+}
+
+// GetNestedValueByKeyAndSubkey ...
+func (ss *ShardedStorage) GetNestedValueByKeyAndSubkey(key string, subKey string) (*StorableWithMeta, bool, error) {
+	if swm, exists := ss.loadNotExpired(key); exists {
+		dict, yes := swm.Entity.(map[string]string)
+		if yes {
+			val, ok := dict[subKey]
+			return enpackStorable(val, swm), ok, nil
+		}
+		return nil, false, errors.New("Stored value is not a dictionary")
+	}
+	return nil, false, errors.New("Map not found")
+}
+
+// UpdateValueByKey ...
+func (ss *ShardedStorage) UpdateValueByKey(key string, newValue Storable) error {
+	return ss.UpdateValueByKeyWithTTL(key, newValue, 0)
+}
+
+// UpdateValueByKeyWithTTL ...
+func (ss *ShardedStorage) UpdateValueByKeyWithTTL(key string, newValue Storable, ttl time.Duration) error {
+	entry := newStorableWithMeta(newValue, effectiveTTL(ttl, ss))
+	// eviction takes shard locks, so memory is freed in advance. Only the growth of the entry needs it
+	delta := entrySize(key, entry)
+	if previous := ss.loadStored(key); previous != nil {
+		delta -= entrySize(key, previous)
+	}
+	if err := ss.freeMemory(delta); err != nil {
+		return err
+	}
+	defer ss.lockKey(key)()
+	shard := ss.shardFor(key)
+	shard.lock.Lock()
+	current, exists := shard.entries[key]
+	updated := exists && notExpired(current)
+	if updated {
+		shard.entries[key] = entry
+		ss.accountEntry(key, current, entry)
+		shard.index.track(key, current, entry)
+	}
+	shard.lock.Unlock()
+
+	if updated {
+		ss.emit(MutationUpdate, key, entry)
+		return nil
+	}
+	return errors.New("There is no entry with such key")
+}
+
+// AppendNewValue ...
+func (ss *ShardedStorage) AppendNewValue(key string, newValue Storable) error {
+	return ss.AppendNewValueWithTTL(key, newValue, 0)
+}
+
+// AppendNewValueWithTTL ...
+func (ss *ShardedStorage) AppendNewValueWithTTL(key string, newValue Storable, ttl time.Duration) error {
+	entry := newStorableWithMeta(newValue, effectiveTTL(ttl, ss))
+	// eviction takes shard locks, so memory is freed in advance
+	if err := ss.freeMemory(entrySize(key, entry)); err != nil {
+		return err
+	}
+	defer ss.lockKey(key)()
+	shard := ss.shardFor(key)
+	shard.lock.Lock()
+	current, exists := shard.entries[key]
+	// expired entry is silently replaced, there is no point to report its expiration separately
+	appended := !exists || !notExpired(current)
+	if appended {
+		shard.entries[key] = entry
+		ss.accountEntry(key, current, entry)
+		shard.index.track(key, current, entry)
+	}
+	shard.lock.Unlock()
+
+	if appended {
+		ss.emit(MutationAppend, key, entry)
+		return nil
+	}
+	return errors.New("Entry with such key already exists")
+}
+
+// RangeEntries ...
+func (ss *ShardedStorage) RangeEntries(f func(key string, entry *StorableWithMeta) bool) {
+	for _, shard := range ss.shards {
+		// entries are copied, so f is free to modify the storage
+		shard.lock.RLock()
+		keys := make([]string, 0, len(shard.entries))
+		entries := make([]*StorableWithMeta, 0, len(shard.entries))
+		for key, entry := range shard.entries {
+			if notExpired(entry) {
+				keys = append(keys, key)
+				entries = append(entries, entry)
+			}
+		}
+		shard.lock.RUnlock()
+
+		for i, key := range keys {
+			if !f(key, entries[i]) {
+				return
+			}
+		}
+	}
+}
+
+// SnapshotEntries call locks all the shards at once in the same order as transactions do
+func (ss *ShardedStorage) SnapshotEntries() map[string]*StorableWithMeta {
+	entries := make(map[string]*StorableWithMeta)
+	for _, shard := range ss.shards {
+		shard.lock.RLock()
+	}
+	for _, shard := range ss.shards {
+		for key, entry := range shard.entries {
+			if notExpired(entry) {
+				entries[key] = entry
+			}
+		}
+	}
+	for _, shard := range ss.shards {
+		shard.lock.RUnlock()
+	}
+	return entries
+}
+
+// RestoreEntry ...
+func (ss *ShardedStorage) RestoreEntry(key string, entry *StorableWithMeta) {
+	// the key is locked to keep the scan index in the order of the changes
+	defer ss.lockKey(key)()
+	shard := ss.shardFor(key)
+	shard.lock.Lock()
+	ss.accountEntry(key, shard.entries[key], entry)
+	shard.index.track(key, shard.entries[key], entry)
+	shard.entries[key] = entry
+	shard.lock.Unlock()
+}
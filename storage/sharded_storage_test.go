@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testShardedStorage Storage = InitShardedStorage(testTTL, 8)
+
+func TestShardedBasicCrud(t *testing.T) {
+	key, value := "zxcvf", "London is the capital of ..."
+	newValue := "Mordor is the land of..."
+
+	if err := testShardedStorage.AppendNewValue(key, value); err != nil {
+		t.Error("Can not append the test value")
+	}
+	if storedVal, ok := testShardedStorage.GetValueByKey(key); !ok || storedVal.Entity != value {
+		t.Error("Test storage does not contain the test value just appended")
+	}
+	if err := testShardedStorage.AppendNewValue(key, value); err == nil {
+		t.Error("Test storage allows to append the value twice")
+	}
+	testShardedStorage.UpdateValueByKey(key, newValue)
+	if storedVal, ok := testShardedStorage.GetValueByKey(key); !ok || storedVal.Entity != newValue {
+		t.Error("Test storage does not contain the new test value just updated")
+	}
+	if keys := testShardedStorage.GetAllKeys(); len(keys) != 1 || keys[0] != key {
+		t.Error("Test storage does not return the key of the test value")
+	}
+
+	if !testShardedStorage.DeleteValueByKey(key) {
+		t.Error("Deletion of the test value is not reported")
+	}
+	if _, ok := testShardedStorage.GetValueByKey(key); ok {
+		t.Error("Test storage contains the new value just deleted")
+	}
+	if err := testShardedStorage.UpdateValueByKey(key, newValue); err == nil {
+		t.Error("Test storage allows to update the value just deleted")
+	}
+}
+
+func TestShardedNestedOps(t *testing.T) {
+	aKey, aValue := "hjkl", []string{"Alpha", "Bravo", "Charlie"}
+	dKey, dValue := "qwegs", map[string]string{"the_first": "Nicolas"}
+	testShardedStorage.AppendNewValue(aKey, aValue)
+	testShardedStorage.AppendNewValue(dKey, dValue)
+	defer testShardedStorage.DeleteValueByKey(aKey)
+	defer testShardedStorage.DeleteValueByKey(dKey)
+
+	if resp, exists, err := testShardedStorage.GetNestedValueByKeyAndIndex(aKey, 1); !exists || err != nil || resp.Entity != "Bravo" {
+		t.Error("Can not get acces to the nested array item by its index")
+	}
+	if _, exists, err := testShardedStorage.GetNestedValueByKeyAndIndex(aKey, 3); exists || err == nil {
+		t.Error("Outbounding index does not lead to error")
+	}
+	if resp, exists, err := testShardedStorage.GetNestedValueByKeyAndSubkey(dKey, "the_first"); !exists || err != nil || resp.Entity != "Nicolas" {
+		t.Error("Can not get acces to the nested dictionary item by its subkey")
+	}
+	if _, ok, _ := testShardedStorage.GetNestedValueByKeyAndSubkey(aKey, "the_first"); ok {
+		t.Error("Sub-key based access is granted to a value with non-dictionary type")
+	}
+}
+
+func TestShardedExpiry(t *testing.T) {
+	var testVeryShortTTL = time.Millisecond * 10
+	testStorage := InitShardedStorage(testVeryShortTTL, 4)
+	for i := 0; i < 50; i++ {
+		testStorage.AppendNewValue("vacuum_"+strconv.Itoa(i), "Never read again")
+	}
+	testStorage.AppendNewValue("lazy", "Read after expiration")
+
+	time.Sleep(testVeryShortTTL * 2)
+	if _, ok := testStorage.GetValueByKey("lazy"); ok {
+		t.Error("Test storage still contains the test value that should be already expired")
+	}
+
+	testStorage.StartVacuum(time.Millisecond * 5)
+	time.Sleep(time.Millisecond * 100)
+	testStorage.StopVacuum()
+
+	if stats := testStorage.VacuumStats(); stats.ExpiredKeys != 50 || stats.LazyExpiredKeys != 1 {
+		t.Error("Expired entries are not reclaimed")
+	}
+}
+
+func TestShardedConcurrentAppends(t *testing.T) {
+	testStorage := InitShardedStorage(time.Minute, 4)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	appended := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if testStorage.AppendNewValue("key_"+strconv.Itoa(j), "value") == nil {
+					lock.Lock()
+					appended++
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if appended != 100 || len(testStorage.GetAllKeys()) != 100 {
+		t.Error("The same key is appended more than once by concurrent writers")
+	}
+}
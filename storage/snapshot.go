@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Snapshotter dumps the whole keyspace to a single file and loads it back.
+// The file has the same format as a compacted append-only file
+type Snapshotter struct {
+	path    string
+	storage PersistableStorage
+
+	lock             sync.Mutex
+	lastSnapshotTime time.Time
+	stop             chan struct{}
+	done             chan struct{}
+}
+
+// NewSnapshotter ...
+func NewSnapshotter(storage PersistableStorage, path string) *Snapshotter {
+	snapshotter := new(Snapshotter)
+	snapshotter.storage = storage
+	snapshotter.path = path
+	return snapshotter
+}
+
+// Save call writes the snapshot to a temporary file and renames it afterwards,
+// so the previous snapshot stays untouched if anything goes wrong.
+// The snapshot is point-in-time: writes are blocked only while references to the entries are collected
+func (snapshotter *Snapshotter) Save() (time.Time, error) {
+	snapshotter.lock.Lock()
+	defer snapshotter.lock.Unlock()
+
+	startTime := time.Now()
+	tmpPath := snapshotter.path + ".tmp"
+	tmpFile, err := os.Create(tmpPath)
+	if err != nil {
+		return snapshotter.lastSnapshotTime, err
+	}
+	_, err = dumpEntries(rangeSnapshot(snapshotter.storage), tmpFile, nil)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, snapshotter.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return snapshotter.lastSnapshotTime, err
+	}
+	// the rename is durable only once the directory is synced
+	if err := syncDir(filepath.Dir(snapshotter.path)); err != nil {
+		return snapshotter.lastSnapshotTime, err
+	}
+
+	snapshotter.lastSnapshotTime = startTime
+	return startTime, nil
+}
+
+// Load call restores all the not expired entries of the snapshot into the storage.
+// Missing snapshot file is not considered as an error
+func (snapshotter *Snapshotter) Load() error {
+	file, err := os.Open(snapshotter.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := ReadSnapshot(snapshotter.storage, file); err != nil {
+		return errors.New(err.Error() + " in " + snapshotter.path)
+	}
+
+	if info, err := file.Stat(); err == nil {
+		snapshotter.lock.Lock()
+		snapshotter.lastSnapshotTime = info.ModTime()
+		snapshotter.lock.Unlock()
+	}
+	return nil
+}
+
+// syncDir call flushes the directory entries, e.g. after a file is renamed
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// rangeSnapshot call takes the point-in-time snapshot of the entries and ranges over it
+func rangeSnapshot(storage PersistableStorage) func(f func(key string, entry *StorableWithMeta) bool) {
+	entries := storage.SnapshotEntries()
+	return func(f func(key string, entry *StorableWithMeta) bool) {
+		for key, entry := range entries {
+			if !f(key, entry) {
+				return
+			}
+		}
+	}
+}
+
+// WriteSnapshot call writes all the entries of the storage in the snapshot format as of a single point in time
+func WriteSnapshot(storage PersistableStorage, w io.Writer) error {
+	_, err := dumpEntries(rangeSnapshot(storage), w, nil)
+	return err
+}
+
+// WriteSnapshotOf call writes the entries of the storage with keys matching the filter in the snapshot format
+func WriteSnapshotOf(storage PersistableStorage, w io.Writer, match func(key string) bool) error {
+	_, err := dumpEntries(storage.RangeEntries, w, match)
+	return err
+}
+
+// ReadSnapshot call restores all the not expired entries written by WriteSnapshot into the storage.
+// Entries absent in the snapshot are kept
+func ReadSnapshot(storage PersistableStorage, r io.Reader) error {
+	return readRecords(r, func(record *aofRecord) error {
+		return applyRecord(storage, record)
+	})
+}
+
+// ImportSnapshot call stores the not expired entries written by WriteSnapshot as new writes keeping their expiration.
+// Unlike ReadSnapshot it reports them to mutation listeners, so they get into the append-only file and to followers
+func ImportSnapshot(storage Storage, r io.Reader) error {
+	return readRecords(r, func(record *aofRecord) error {
+		if record.Entry == nil {
+			return errors.New("Record of " + record.Key + " has no entry")
+		}
+		entry, err := decodeEntry(record.Entry)
+		if err != nil {
+			return err
+		}
+		if expireIn := entry.ExpireIn(); expireIn > 0 {
+			_, err = storage.SetValueByKeyIf(record.Key, entry.Entity, expireIn, Always)
+		}
+		return err
+	})
+}
+
+func readRecords(r io.Reader, apply func(record *aofRecord) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err != nil {
+			return errors.New("Incomplete record at the end of the snapshot")
+		}
+
+		var record aofRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return errors.New("Corrupted record: " + err.Error())
+		}
+		if err := apply(&record); err != nil {
+			return err
+		}
+	}
+}
+
+// LastSnapshotTime call returns the time of the last successful snapshot
+// or zero time if there were none
+func (snapshotter *Snapshotter) LastSnapshotTime() time.Time {
+	snapshotter.lock.Lock()
+	defer snapshotter.lock.Unlock()
+	return snapshotter.lastSnapshotTime
+}
+
+// StartPeriodicSnapshots call makes the snapshotter save a snapshot every interval
+func (snapshotter *Snapshotter) StartPeriodicSnapshots(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("Snapshot interval should be positive")
+	}
+
+	snapshotter.lock.Lock()
+	defer snapshotter.lock.Unlock()
+	if snapshotter.stop != nil {
+		return errors.New("Periodic snapshots are already started")
+	}
+	snapshotter.stop = make(chan struct{})
+	snapshotter.done = make(chan struct{})
+
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := snapshotter.Save(); err != nil {
+					log.Println("Can not save snapshot to " + snapshotter.path + ": " + err.Error())
+				}
+			}
+		}
+	}(snapshotter.stop, snapshotter.done)
+	return nil
+}
+
+// Close call stops periodic snapshots and saves the final one
+func (snapshotter *Snapshotter) Close() error {
+	snapshotter.lock.Lock()
+	stop, done := snapshotter.stop, snapshotter.done
+	snapshotter.stop, snapshotter.done = nil, nil
+	snapshotter.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	_, err := snapshotter.Save()
+	return err
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotSaveAndLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.snapshot")
+
+	testStorage := InitSyncMapStorage(time.Minute)
+	testStorage.AppendNewValue("str", "Lorem ipsum")
+	testStorage.AppendNewValueWithTTL("arr", []string{"Alpha", "Bravo"}, time.Hour)
+	testStorage.AppendNewValue("dic", map[string]string{"1": "One"})
+	testStorage.AppendNewValueWithTTL("short", "Soon expired", time.Millisecond*10)
+
+	snapshotter := NewSnapshotter(testStorage, path)
+	if !snapshotter.LastSnapshotTime().IsZero() {
+		t.Error("Last snapshot time is set before any snapshot")
+	}
+	snapshotTime, err := snapshotter.Save()
+	if err != nil {
+		t.Fatal("Can not save snapshot. " + err.Error())
+	}
+	if snapshotTime.IsZero() || !snapshotter.LastSnapshotTime().Equal(snapshotTime) {
+		t.Error("Last snapshot time is not updated")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Temporary snapshot file is left")
+	}
+
+	time.Sleep(time.Millisecond * 20)
+	restored := InitSyncMapStorage(time.Minute)
+	if err := NewSnapshotter(restored, path).Load(); err != nil {
+		t.Fatal("Can not load snapshot. " + err.Error())
+	}
+	original, _ := testStorage.GetValueByKey("str")
+	if val, ok := restored.GetValueByKey("str"); !ok || val.Entity != "Lorem ipsum" || val.Version != original.Version {
+		t.Error("String value is not restored with its version from snapshot")
+	}
+	if val, ok := restored.GetValueByKey("arr"); !ok || !reflect.DeepEqual(val.Entity, []string{"Alpha", "Bravo"}) || val.TTL != time.Hour {
+		t.Error("Array value is not restored with its TTL from snapshot")
+	}
+	if val, ok := restored.GetValueByKey("dic"); !ok || !reflect.DeepEqual(val.Entity, map[string]string{"1": "One"}) {
+		t.Error("Dictionary value is not restored from snapshot")
+	}
+	if _, ok := restored.GetValueByKey("short"); ok {
+		t.Error("Expired value is restored from snapshot")
+	}
+}
+
+func TestSnapshotMissingFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+
+	testStorage := InitSyncMapStorage(time.Minute)
+	if err := NewSnapshotter(testStorage, filepath.Join(dir, "missing.snapshot")).Load(); err != nil {
+		t.Error("Missing snapshot file leads to error")
+	}
+}
+
+func TestSnapshotIsPointInTime(t *testing.T) {
+	storages := map[string]PersistableStorage{
+		"syncmap": InitSyncMapStorage(time.Minute),
+		"sharded": InitShardedStorage(time.Minute, 4),
+	}
+	for name, testStorage := range storages {
+		// every transaction moves a unit between the counters, so their sum is always zero
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				testStorage.ExecTransaction(nil, func(tx *Tx) error {
+					tx.IncrementBy("from"+strconv.Itoa(i%1000), -1)
+					_, err := tx.IncrementBy("to"+strconv.Itoa(i%997), 1)
+					return err
+				})
+			}
+		}()
+
+		for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); {
+			var data bytes.Buffer
+			if err := WriteSnapshot(testStorage, &data); err != nil {
+				t.Fatal("Can not write snapshot. " + err.Error())
+			}
+			restored := InitSyncMapStorage(time.Minute)
+			if err := ReadSnapshot(restored, &data); err != nil {
+				t.Fatal("Can not read snapshot. " + err.Error())
+			}
+			sum := 0
+			restored.RangeEntries(func(key string, entry *StorableWithMeta) bool {
+				value, _ := strconv.Atoi(entry.Entity.(string))
+				sum += value
+				return true
+			})
+			if sum != 0 {
+				t.Error(name + ": Snapshot has a part of a transaction")
+				break
+			}
+		}
+		close(stop)
+		<-done
+	}
+}
+
+func TestSnapshotImport(t *testing.T) {
+	testStorage := InitSyncMapStorage(time.Minute)
+	testStorage.AppendNewValueWithTTL("moved:arr", []string{"Alpha", "Bravo"}, time.Hour)
+	testStorage.AppendNewValue("moved:str", "Lorem ipsum")
+	testStorage.AppendNewValue("kept", "Dolor")
+
+	var data bytes.Buffer
+	err := WriteSnapshotOf(testStorage, &data, func(key string) bool { return strings.HasPrefix(key, "moved:") })
+	if err != nil {
+		t.Fatal("Can not write snapshot. " + err.Error())
+	}
+	target := InitShardedStorage(time.Minute, 4)
+	var mutations []Mutation
+	target.AddMutationListener(func(mutation Mutation) { mutations = append(mutations, mutation) })
+	if err := ImportSnapshot(target, &data); err != nil {
+		t.Fatal("Can not import snapshot. " + err.Error())
+	}
+
+	if val, ok := target.GetValueByKey("moved:arr"); !ok || !reflect.DeepEqual(val.Entity, []string{"Alpha", "Bravo"}) || val.TTL > time.Hour || val.TTL < time.Minute {
+		t.Error("Array value is not imported with its expiration")
+	}
+	if val, ok := target.GetValueByKey("moved:str"); !ok || val.Entity != "Lorem ipsum" {
+		t.Error("String value is not imported")
+	}
+	if _, ok := target.GetValueByKey("kept"); ok {
+		t.Error("Filtered out value is imported")
+	}
+	if len(mutations) != 2 {
+		t.Errorf("Imported entries are not reported to listeners: %v", mutations)
+	}
+}
+
+func TestPeriodicSnapshots(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gedis")
+	defer os.RemoveAll(dir)
+
+	testStorage := InitSyncMapStorage(time.Minute)
+	snapshotter := NewSnapshotter(testStorage, filepath.Join(dir, "test.snapshot"))
+	if err := snapshotter.StartPeriodicSnapshots(time.Millisecond * 10); err != nil {
+		t.Fatal("Can not start periodic snapshots. " + err.Error())
+	}
+	time.Sleep(time.Millisecond * 50)
+	if snapshotter.LastSnapshotTime().IsZero() {
+		t.Error("Periodic snapshot is not taken")
+	}
+	if err := snapshotter.Close(); err != nil {
+		t.Error("Can not close snapshotter. " + err.Error())
+	}
+}
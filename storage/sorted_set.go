@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// ScoredMember is a member of the sorted set along with its score
+type ScoredMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// ScoreRange is an interval of scores, bounds are inclusive unless they are marked as exclusive
+type ScoreRange struct {
+	Min          float64
+	Max          float64
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+// AllScores is a range containing all the possible scores
+var AllScores = ScoreRange{Min: math.Inf(-1), Max: math.Inf(1)}
+
+func (scoreRange ScoreRange) aboveMin(score float64) bool {
+	if scoreRange.MinExclusive {
+		return score > scoreRange.Min
+	}
+	return score >= scoreRange.Min
+}
+
+func (scoreRange ScoreRange) belowMax(score float64) bool {
+	if scoreRange.MaxExclusive {
+		return score < scoreRange.Max
+	}
+	return score <= scoreRange.Max
+}
+
+// SortedSet is a collection of unique strings ordered by their scores, members with equal scores
+// are ordered lexicographically. Its JSON representation is an array of scored members in the order.
+// Sorted sets are immutable: changes return new sets sharing most of the structure with the original one,
+// as stored sets are shared with readers
+type SortedSet struct {
+	// members are ordered by names to look up their scores, ranks are ordered by scores to look up positions
+	members     *treapNode
+	ranks       *treapNode
+	memberBytes int64
+}
+
+// NewSortedSet call creates the sorted set, the last score wins for duplicated members
+func NewSortedSet(members ...ScoredMember) *SortedSet {
+	sortedSet := new(SortedSet)
+	for _, member := range members {
+		sortedSet, _ = sortedSet.with(member.Member, member.Score)
+	}
+	return sortedSet
+}
+
+// validScore call checks the score could be stored and encoded as JSON
+func validScore(score float64) error {
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return errors.New("Score should be a finite number")
+	}
+	return nil
+}
+
+func memberBefore(member string) treapLeft {
+	return func(node *treapNode) bool { return node.member < member }
+}
+
+func memberNotAfter(member string) treapLeft {
+	return func(node *treapNode) bool { return node.member <= member }
+}
+
+func rankBefore(score float64, member string) treapLeft {
+	return func(node *treapNode) bool { return node.before(score, member) }
+}
+
+func rankNotAfter(score float64, member string) treapLeft {
+	return func(node *treapNode) bool {
+		return node.before(score, member) || (node.score == score && node.member == member)
+	}
+}
+
+// with call returns the sorted set with the score of the member set and true if the member is a new one
+func (sortedSet *SortedSet) with(member string, score float64) (*SortedSet, bool) {
+	current, exists := sortedSet.Score(member)
+	if exists && current == score {
+		return sortedSet, false
+	}
+	modified := new(SortedSet)
+	if sortedSet != nil {
+		*modified = *sortedSet
+	}
+	if exists {
+		modified.members = treapDelete(modified.members, memberBefore(member), memberNotAfter(member))
+		modified.ranks = treapDelete(modified.ranks, rankBefore(current, member), rankNotAfter(current, member))
+	} else {
+		modified.memberBytes += int64(len(member))
+	}
+	modified.members = treapInsert(modified.members, newTreapNode(member, score), memberBefore(member))
+	modified.ranks = treapInsert(modified.ranks, newTreapNode(member, score), rankBefore(score, member))
+	return modified, !exists
+}
+
+// without call returns the sorted set without the member and true if there was such member
+func (sortedSet *SortedSet) without(member string) (*SortedSet, bool) {
+	score, exists := sortedSet.Score(member)
+	if !exists {
+		return sortedSet, false
+	}
+	return &SortedSet{
+		members:     treapDelete(sortedSet.members, memberBefore(member), memberNotAfter(member)),
+		ranks:       treapDelete(sortedSet.ranks, rankBefore(score, member), rankNotAfter(score, member)),
+		memberBytes: sortedSet.memberBytes - int64(len(member)),
+	}, true
+}
+
+// Len ...
+func (sortedSet *SortedSet) Len() int {
+	if sortedSet == nil {
+		return 0
+	}
+	return sortedSet.ranks.count()
+}
+
+// Score ...
+func (sortedSet *SortedSet) Score(member string) (float64, bool) {
+	if sortedSet == nil {
+		return 0, false
+	}
+	for node := sortedSet.members; node != nil; {
+		switch {
+		case member < node.member:
+			node = node.left
+		case member > node.member:
+			node = node.right
+		default:
+			return node.score, true
+		}
+	}
+	return 0, false
+}
+
+// Rank call returns 0-based position of the member in ascending or descending (reverse) order
+func (sortedSet *SortedSet) Rank(member string, reverse bool) (int, bool) {
+	score, exists := sortedSet.Score(member)
+	if !exists {
+		return 0, false
+	}
+	rank := treapCount(sortedSet.ranks, rankBefore(score, member))
+	if reverse {
+		rank = sortedSet.Len() - 1 - rank
+	}
+	return rank, true
+}
+
+// RangeByRank call returns members from start to stop inclusive. Negative indexes are counted
+// from the end, both indexes are applied to descending order if reverse is set
+func (sortedSet *SortedSet) RangeByRank(start int, stop int, reverse bool) []ScoredMember {
+	from, to := listRange(start, stop, sortedSet.Len())
+	if from >= to {
+		return []ScoredMember{}
+	}
+	members := make([]ScoredMember, 0, to-from)
+	collect := func(node *treapNode) bool {
+		members = append(members, ScoredMember{node.member, node.score})
+		return len(members) < to-from
+	}
+	if reverse {
+		treapDescend(sortedSet.ranks, sortedSet.Len()-1-from, collect)
+	} else {
+		treapAscend(sortedSet.ranks, from, collect)
+	}
+	return members
+}
+
+// RangeByScore call returns members within the range in ascending or descending (reverse) order,
+// skipping offset of them and returning up to count ones. Negative count means no limit
+func (sortedSet *SortedSet) RangeByScore(scoreRange ScoreRange, reverse bool, offset int, count int) []ScoredMember {
+	members := []ScoredMember{}
+	if sortedSet.Len() == 0 || offset < 0 || count == 0 {
+		return members
+	}
+
+	collect := func(node *treapNode) bool {
+		if reverse && !scoreRange.aboveMin(node.score) || !reverse && !scoreRange.belowMax(node.score) {
+			return false
+		}
+		members = append(members, ScoredMember{node.member, node.score})
+		return len(members) != count
+	}
+	// members within the range follow the ones below its min
+	if reverse {
+		last := treapCount(sortedSet.ranks, func(node *treapNode) bool { return scoreRange.belowMax(node.score) }) - 1
+		treapDescend(sortedSet.ranks, last-offset, collect)
+	} else {
+		first := treapCount(sortedSet.ranks, func(node *treapNode) bool { return !scoreRange.aboveMin(node.score) })
+		treapAscend(sortedSet.ranks, first+offset, collect)
+	}
+	return members
+}
+
+// Members call returns all the members in ascending order
+func (sortedSet *SortedSet) Members() []ScoredMember {
+	return sortedSet.RangeByRank(0, -1, false)
+}
+
+// MarshalJSON ...
+func (sortedSet *SortedSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sortedSet.Members())
+}
+
+// UnmarshalJSON ...
+func (sortedSet *SortedSet) UnmarshalJSON(data []byte) error {
+	var members []ScoredMember
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, member := range members {
+		if err := validScore(member.Score); err != nil {
+			return err
+		}
+	}
+	*sortedSet = *NewSortedSet(members...)
+	return nil
+}
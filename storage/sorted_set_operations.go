@@ -0,0 +1,206 @@
+package storage
+
+import "errors"
+
+// SortedSetStorage contains atomic operations over sorted set entries.
+// Sorted sets are created on add and removed once they get empty
+type SortedSetStorage interface {
+	// AddToSortedSet sets scores of the members and returns the number of new ones
+	AddToSortedSet(key string, members ...ScoredMember) (int, error)
+
+	// IncrementSortedSetScore adds delta to the score of the member (zero for a new one)
+	// and returns the new score
+	IncrementSortedSetScore(key string, member string, delta float64) (float64, error)
+
+	// RemoveFromSortedSet returns the number of members which were in the sorted set
+	RemoveFromSortedSet(key string, members ...string) (int, error)
+
+	GetSortedSetScore(key string, member string) (float64, bool, error)
+
+	// GetSortedSetRank returns 0-based rank of the member in ascending or descending (reverse) order
+	GetSortedSetRank(key string, member string, reverse bool) (int, bool, error)
+
+	GetSortedSetCardinality(key string) (int, error)
+
+	// GetSortedSetRangeByRank returns members from start to stop inclusive,
+	// negative indexes are counted from the end
+	GetSortedSetRangeByRank(key string, start int, stop int, reverse bool) ([]ScoredMember, error)
+
+	// GetSortedSetRangeByScore returns up to count members within the range skipping offset of them.
+	// Negative count means no limit
+	GetSortedSetRangeByScore(key string, scoreRange ScoreRange, reverse bool, offset int, count int) ([]ScoredMember, error)
+
+	// PopFromSortedSet removes and returns up to count members with the lowest or the highest (max) scores
+	PopFromSortedSet(key string, max bool, count int) ([]ScoredMember, error)
+}
+
+// sortedSetOf call extracts the sorted set from the entry, nil entry is considered as an empty sorted set
+func sortedSetOf(entry *StorableWithMeta) (*SortedSet, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	sortedSet, yes := entry.Entity.(*SortedSet)
+	if !yes {
+		return nil, errors.New("Stored value is not a sorted set")
+	}
+	return sortedSet, nil
+}
+
+// withSortedSet call replaces the sorted set of the entry removing the entry if the sorted set is empty
+func (ops operations) withSortedSet(current *StorableWithMeta, sortedSet *SortedSet) *StorableWithMeta {
+	if sortedSet.Len() == 0 {
+		return nil
+	}
+	return ops.rewrap(current, sortedSet)
+}
+
+// loadSortedSet call is used by read-only operations
+func (ops operations) loadSortedSet(key string) (*SortedSet, error) {
+	entry, _ := ops.keyspace.loadNotExpired(key)
+	return sortedSetOf(entry)
+}
+
+// AddToSortedSet ...
+func (ops operations) AddToSortedSet(key string, members ...ScoredMember) (int, error) {
+	if len(members) == 0 {
+		return 0, errors.New("There are no members to add")
+	}
+	for _, member := range members {
+		if err := validScore(member.Score); err != nil {
+			return 0, err
+		}
+	}
+
+	var added int
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		sortedSet, err := sortedSetOf(current)
+		if err != nil {
+			return nil, err
+		}
+		modified := sortedSet
+		added = 0
+		for _, member := range members {
+			var isNew bool
+			if modified, isNew = modified.with(member.Member, member.Score); isNew {
+				added++
+			}
+		}
+		return ops.withSortedSet(current, modified), nil
+	})
+	return added, err
+}
+
+// IncrementSortedSetScore ...
+func (ops operations) IncrementSortedSetScore(key string, member string, delta float64) (float64, error) {
+	if err := validScore(delta); err != nil {
+		return 0, err
+	}
+
+	var score float64
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		sortedSet, err := sortedSetOf(current)
+		if err != nil {
+			return nil, err
+		}
+		currentScore, _ := sortedSet.Score(member)
+		score = currentScore + delta
+		if err := validScore(score); err != nil {
+			return nil, err
+		}
+		modified, _ := sortedSet.with(member, score)
+		return ops.withSortedSet(current, modified), nil
+	})
+	return score, err
+}
+
+// RemoveFromSortedSet ...
+func (ops operations) RemoveFromSortedSet(key string, members ...string) (int, error) {
+	var removed int
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		sortedSet, err := sortedSetOf(current)
+		if err != nil {
+			return nil, err
+		}
+		removed = 0
+		for _, member := range members {
+			if _, exists := sortedSet.Score(member); exists {
+				removed++
+			}
+		}
+		if removed == 0 {
+			return current, nil
+		}
+		modified := sortedSet
+		for _, member := range members {
+			modified, _ = modified.without(member)
+		}
+		return ops.withSortedSet(current, modified), nil
+	})
+	return removed, err
+}
+
+// GetSortedSetScore ...
+func (ops operations) GetSortedSetScore(key string, member string) (float64, bool, error) {
+	sortedSet, err := ops.loadSortedSet(key)
+	if err != nil {
+		return 0, false, err
+	}
+	score, exists := sortedSet.Score(member)
+	return score, exists, nil
+}
+
+// GetSortedSetRank ...
+func (ops operations) GetSortedSetRank(key string, member string, reverse bool) (int, bool, error) {
+	sortedSet, err := ops.loadSortedSet(key)
+	if err != nil {
+		return 0, false, err
+	}
+	rank, exists := sortedSet.Rank(member, reverse)
+	return rank, exists, nil
+}
+
+// GetSortedSetCardinality ...
+func (ops operations) GetSortedSetCardinality(key string) (int, error) {
+	sortedSet, err := ops.loadSortedSet(key)
+	return sortedSet.Len(), err
+}
+
+// GetSortedSetRangeByRank ...
+func (ops operations) GetSortedSetRangeByRank(key string, start int, stop int, reverse bool) ([]ScoredMember, error) {
+	sortedSet, err := ops.loadSortedSet(key)
+	if err != nil {
+		return nil, err
+	}
+	return sortedSet.RangeByRank(start, stop, reverse), nil
+}
+
+// GetSortedSetRangeByScore ...
+func (ops operations) GetSortedSetRangeByScore(key string, scoreRange ScoreRange, reverse bool, offset int, count int) ([]ScoredMember, error) {
+	sortedSet, err := ops.loadSortedSet(key)
+	if err != nil {
+		return nil, err
+	}
+	return sortedSet.RangeByScore(scoreRange, reverse, offset, count), nil
+}
+
+// PopFromSortedSet ...
+func (ops operations) PopFromSortedSet(key string, max bool, count int) ([]ScoredMember, error) {
+	var popped []ScoredMember
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		sortedSet, err := sortedSetOf(current)
+		if err != nil {
+			return nil, err
+		}
+		popped = []ScoredMember{}
+		if count <= 0 || sortedSet.Len() == 0 {
+			return current, nil
+		}
+		popped = sortedSet.RangeByRank(0, count-1, max)
+		modified := sortedSet
+		for _, member := range popped {
+			modified, _ = modified.without(member.Member)
+		}
+		return ops.withSortedSet(current, modified), nil
+	})
+	return popped, err
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSortedSetConsistency(t *testing.T) {
+	sortedSet := NewSortedSet()
+	expected := map[string]float64{}
+	for i := 0; i < 2000; i++ {
+		member := strconv.Itoa(rand.Intn(300))
+		if rand.Intn(3) == 0 {
+			sortedSet, _ = sortedSet.without(member)
+			delete(expected, member)
+		} else {
+			score := float64(rand.Intn(50))
+			sortedSet, _ = sortedSet.with(member, score)
+			expected[member] = score
+		}
+	}
+	previous := sortedSet.Members()
+	for i := 0; i < 100; i++ {
+		sortedSet.with(strconv.Itoa(rand.Intn(300)), float64(rand.Intn(50)))
+		sortedSet.without(strconv.Itoa(rand.Intn(300)))
+	}
+	if !reflect.DeepEqual(sortedSet.Members(), previous) {
+		t.Fatal("Sorted set is modified by changes made on top of it")
+	}
+
+	members := make([]ScoredMember, 0, len(expected))
+	for member, score := range expected {
+		members = append(members, ScoredMember{member, score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Score < members[j].Score ||
+			members[i].Score == members[j].Score && members[i].Member < members[j].Member
+	})
+
+	if !reflect.DeepEqual(sortedSet.Members(), members) {
+		t.Fatal("Members of the sorted set are not ordered")
+	}
+	for i, member := range members {
+		if rank, exists := sortedSet.Rank(member.Member, false); !exists || rank != i {
+			t.Fatal("Rank of the member does not match its position")
+		}
+		if node := treapByRank(sortedSet.ranks, i); node == nil || node.member != member.Member {
+			t.Fatal("Member found by rank does not match")
+		}
+	}
+}
+
+func TestSortedSetAddIncrementAndRemove(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key := "board"
+		if added, err := testStorage.AddToSortedSet(key, ScoredMember{"alice", 10}, ScoredMember{"bob", 20}); err != nil || added != 2 {
+			t.Error(name + ": Can not add members to a new sorted set")
+		}
+		if added, _ := testStorage.AddToSortedSet(key, ScoredMember{"bob", 5}, ScoredMember{"carol", 15}); added != 1 {
+			t.Error(name + ": Existing members are counted as added")
+		}
+		if score, err := testStorage.IncrementSortedSetScore(key, "alice", 7.5); err != nil || score != 17.5 {
+			t.Error(name + ": Can not increment score of the member")
+		}
+		if score, exists, err := testStorage.GetSortedSetScore(key, "bob"); err != nil || !exists || score != 5 {
+			t.Error(name + ": Score of the member does not match")
+		}
+		if rank, exists, _ := testStorage.GetSortedSetRank(key, "carol", false); !exists || rank != 1 {
+			t.Error(name + ": Rank of the member does not match")
+		}
+		if rank, exists, _ := testStorage.GetSortedSetRank(key, "alice", true); !exists || rank != 0 {
+			t.Error(name + ": Reverse rank of the member does not match")
+		}
+		if _, exists, _ := testStorage.GetSortedSetRank(key, "dave", false); exists {
+			t.Error(name + ": Rank of missing member is found")
+		}
+		if _, err := testStorage.AddToSortedSet(key, ScoredMember{"dave", math.NaN()}); err == nil {
+			t.Error(name + ": Adding NaN score does not lead to error")
+		}
+		if removed, err := testStorage.RemoveFromSortedSet(key, "bob", "dave"); err != nil || removed != 1 {
+			t.Error(name + ": Can not remove members of the sorted set")
+		}
+		if cardinality, _ := testStorage.GetSortedSetCardinality(key); cardinality != 2 {
+			t.Error(name + ": Cardinality of the sorted set does not match")
+		}
+
+		testStorage.RemoveFromSortedSet(key, "alice", "carol")
+		if _, ok := testStorage.GetValueByKey(key); ok {
+			t.Error(name + ": Empty sorted set is not removed")
+		}
+		testStorage.AppendNewValue("str", "Not a sorted set")
+		if _, err := testStorage.IncrementSortedSetScore("str", "alice", 1); err == nil {
+			t.Error(name + ": Incrementing score in a string value does not lead to error")
+		}
+	}
+}
+
+func TestSortedSetRanges(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		key := "board"
+		testStorage.AddToSortedSet(key,
+			ScoredMember{"a", 1}, ScoredMember{"b", 2}, ScoredMember{"c", 3}, ScoredMember{"d", 4}, ScoredMember{"e", 5})
+
+		if members, err := testStorage.GetSortedSetRangeByRank(key, 1, 2, false); err != nil ||
+			!reflect.DeepEqual(members, []ScoredMember{{"b", 2}, {"c", 3}}) {
+			t.Error(name + ": Range by rank does not match")
+		}
+		if members, _ := testStorage.GetSortedSetRangeByRank(key, 0, -4, true); !reflect.DeepEqual(members, []ScoredMember{{"e", 5}, {"d", 4}}) {
+			t.Error(name + ": Reverse range by rank does not match")
+		}
+		if members, _ := testStorage.GetSortedSetRangeByRank(key, 3, 1, false); len(members) != 0 {
+			t.Error(name + ": Empty range by rank is not empty")
+		}
+
+		scoreRange := ScoreRange{Min: 2, Max: 5, MaxExclusive: true}
+		if members, err := testStorage.GetSortedSetRangeByScore(key, scoreRange, false, 0, -1); err != nil ||
+			!reflect.DeepEqual(members, []ScoredMember{{"b", 2}, {"c", 3}, {"d", 4}}) {
+			t.Error(name + ": Range by score does not match")
+		}
+		if members, _ := testStorage.GetSortedSetRangeByScore(key, scoreRange, true, 1, 1); !reflect.DeepEqual(members, []ScoredMember{{"c", 3}}) {
+			t.Error(name + ": Limited reverse range by score does not match")
+		}
+		if members, _ := testStorage.GetSortedSetRangeByScore(key, ScoreRange{Min: 5, Max: math.Inf(1), MinExclusive: true}, false, 0, -1); len(members) != 0 {
+			t.Error(name + ": Empty range by score is not empty")
+		}
+
+		if popped, err := testStorage.PopFromSortedSet(key, false, 2); err != nil || !reflect.DeepEqual(popped, []ScoredMember{{"a", 1}, {"b", 2}}) {
+			t.Error(name + ": Can not pop members with the lowest scores")
+		}
+		if popped, _ := testStorage.PopFromSortedSet(key, true, 1); !reflect.DeepEqual(popped, []ScoredMember{{"e", 5}}) {
+			t.Error(name + ": Can not pop the member with the highest score")
+		}
+		if members, _ := testStorage.GetSortedSetRangeByScore(key, AllScores, false, 0, -1); !reflect.DeepEqual(members, []ScoredMember{{"c", 3}, {"d", 4}}) {
+			t.Error(name + ": Popped members are not removed")
+		}
+	}
+}
+
+func TestSortedSetEncoding(t *testing.T) {
+	entry := newStorableWithMeta(NewSortedSet(ScoredMember{"alice", 1.5}, ScoredMember{"bob", -2}), time.Minute)
+	encoded, err := encodeEntry(entry)
+	if err != nil {
+		t.Fatal("Can not encode the sorted set: " + err.Error())
+	}
+	decoded, err := decodeEntry(encoded)
+	if err != nil {
+		t.Fatal("Can not decode the sorted set: " + err.Error())
+	}
+	if sortedSet, yes := decoded.Entity.(*SortedSet); !yes || !reflect.DeepEqual(sortedSet.Members(), []ScoredMember{{"bob", -2}, {"alice", 1.5}}) {
+		t.Error("Decoded sorted set does not match the encoded one")
+	}
+}
@@ -1,30 +1,102 @@
 package storage
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
+
+// lfuDecayPeriod is the idle time halving the access frequency of an entry
+const lfuDecayPeriod = time.Minute
+
+// lastVersion is shared by all the storages of the process, so a version is never reused
+// for the same key even if the key is removed and created again
+var lastVersion uint64
+
+// nextVersion call returns a new version for a written entry
+func nextVersion() uint64 {
+	return atomic.AddUint64(&lastVersion, 1)
+}
+
+// observeVersion call makes sure versions of restored entries are not reused
+func observeVersion(version uint64) {
+	for {
+		last := atomic.LoadUint64(&lastVersion)
+		if last >= version || atomic.CompareAndSwapUint64(&lastVersion, last, version) {
+			return
+		}
+	}
+}
 
 // StorableWithMeta ...
 type StorableWithMeta struct {
+	// access counters are updated atomically, so they are kept first to be 64-bit aligned
+	lastAccessTime  int64
+	accessFrequency uint32
+
 	LastWriteTime time.Time
-	Entity        Storable
+	TTL           time.Duration
+	// Version is changed on every write of the entry and only increases
+	Version uint64
+	// Flags are opaque to the storage, memcached clients keep flags of their items there
+	Flags  uint32
+	Entity Storable
 }
 
-func newStorableWithMeta(entity Storable) *StorableWithMeta {
+func newStorableWithMeta(entity Storable, ttl time.Duration) *StorableWithMeta {
 	s := new(StorableWithMeta)
 	s.Entity = entity
 	s.LastWriteTime = time.Now()
+	s.TTL = ttl
+	s.Version = nextVersion()
+	s.touch()
 	return s
 }
 
+// touch call registers an access to the entry for eviction policies
+func (s *StorableWithMeta) touch() {
+	atomic.StoreInt64(&s.lastAccessTime, time.Now().UnixNano())
+	if atomic.LoadUint32(&s.accessFrequency) < ^uint32(0) {
+		atomic.AddUint32(&s.accessFrequency, 1)
+	}
+}
+
+// LastAccessTime call returns the time of the last read or write of the entry
+func (s *StorableWithMeta) LastAccessTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.lastAccessTime))
+}
+
+// AccessFrequency call returns the number of accesses to the entry, halved
+// for every lfuDecayPeriod passed since the last one
+func (s *StorableWithMeta) AccessFrequency() uint32 {
+	decay := uint(time.Since(s.LastAccessTime()) / lfuDecayPeriod)
+	if decay >= 32 {
+		return 0
+	}
+	return atomic.LoadUint32(&s.accessFrequency) >> decay
+}
+
+// ExpireAt call returns the moment when the entry is considered to be expired
+func (s *StorableWithMeta) ExpireAt() time.Time {
+	return s.LastWriteTime.Add(s.TTL)
+}
+
+// ExpireIn call returns the time left before the entry expiration
+func (s *StorableWithMeta) ExpireIn() time.Duration {
+	return time.Until(s.ExpireAt())
+}
+
 // enpackStorable call wraps internal element (cell of slice or value extracted from map)
-// to the StorableWithMeta with LastWriteTime nested from top-level storable entity
+// to the StorableWithMeta with LastWriteTime and Version nested from top-level storable entity
 func enpackStorable(entity Storable, ref *StorableWithMeta) *StorableWithMeta {
 	s := new(StorableWithMeta)
 	s.Entity = entity
 	s.LastWriteTime = ref.LastWriteTime
+	s.TTL = ref.TTL
+	s.Version = ref.Version
 	return s
 }
 
-// Storable is the storable primitive (string, slice of string or map string -> string)
+// Storable is the storable primitive (string, slice of string, map string -> string, Set or *SortedSet)
 // I had to use generic-like interface{} and runtime checks because considered it as
 // a solution demanding less coding
 // TODO other alternative should be considered
@@ -44,24 +116,104 @@ type Storage interface {
 
 	UpdateValueByKey(key string, newValue Storable) error
 
+	// UpdateValueByKeyWithTTL works as UpdateValueByKey but overrides default TTL
+	// of the storage for the entry. Non-positive ttl means the default one
+	UpdateValueByKeyWithTTL(key string, newValue Storable, ttl time.Duration) error
+
 	AppendNewValue(key string, newValue Storable) error
+
+	// AppendNewValueWithTTL works as AppendNewValue but overrides default TTL
+	// of the storage for the entry. Non-positive ttl means the default one
+	AppendNewValueWithTTL(key string, newValue Storable, ttl time.Duration) error
+
+	ListStorage
+
+	DictStorage
+
+	SetStorage
+
+	SortedSetStorage
+
+	CounterStorage
+
+	VersionedStorage
+
+	TransactionStorage
+
+	ScanStorage
+
+	TTLStorage
 }
 
+// PersistableStorage is a storage which content could be dumped and restored
+type PersistableStorage interface {
+	Storage
+
+	// RangeEntries calls f for every not expired entry until f returns false
+	RangeEntries(f func(key string, entry *StorableWithMeta) bool)
+
+	// SnapshotEntries returns all the not expired entries as of a single point in time. Writes are blocked
+	// while references to the entries are collected, stored entries are never modified, so they could be
+	// serialized afterwards
+	SnapshotEntries() map[string]*StorableWithMeta
+
+	// RestoreEntry stores the entry as is, keeping its meta. Listeners are not notified
+	RestoreEntry(key string, entry *StorableWithMeta)
+
+	ObservableStorage
+}
+
+// MemoryLimitedStorage is a storage which bounds the memory taken by its entries
+type MemoryLimitedStorage interface {
+	SetMaxMemory(maxMemory int64, policy EvictionPolicy)
+
+	MemoryStats() MemoryStats
+}
+
+// LazyExpireStorage is a storage which checks expiration of entries on read
 type LazyExpireStorage interface {
 	getTtl() time.Duration
-	// todo add vacuuming
 }
 
-// TODO refactor : pass ttl
-func filterExpired(entity *StorableWithMeta, ls LazyExpireStorage) (*StorableWithMeta, bool) {
-	if notExpired(entity, ls) {
-		return entity, true
+// ActiveExpireStorage is a storage which is able to reclaim expired entries in background
+// even if they are never read again
+type ActiveExpireStorage interface {
+	StartVacuum(interval time.Duration) error
+
+	StopVacuum()
+
+	VacuumStats() VacuumStats
+}
+
+// DelegatedExpireStorage is a storage which expiry could be taken over by its owner, e.g. by a consensus log
+// replicating removals of expired entries, so every replica removes them at the same point of the log
+type DelegatedExpireStorage interface {
+	// KeepExpired turns expiry of the storage off: expired entries are hidden from reads,
+	// but neither reads nor vacuum remove them, so they are kept until deleted explicitly
+	KeepExpired()
+
+	// ExpiredKeys returns up to n keys of the kept expired entries found by random sampling
+	ExpiredKeys(n int) []string
+}
+
+// effectiveTTL call chooses between per-key ttl and the default one of the storage
+func effectiveTTL(ttl time.Duration, ls LazyExpireStorage) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return ls.getTtl()
+}
+
+// DefaultTTL call returns the TTL the storage gives to entries written without one, zero if it is unknown
+func DefaultTTL(registry Storage) time.Duration {
+	if ls, ok := registry.(LazyExpireStorage); ok {
+		return ls.getTtl()
 	}
-	return nil, false
+	return 0
 }
 
-// TODO refactor : pass ttl
-func notExpired(entity *StorableWithMeta, ls LazyExpireStorage) bool {
+// ttl is stored per entry, so there is no need to consult the storage
+func notExpired(entity *StorableWithMeta) bool {
 	now := time.Now()
-	return !entity.LastWriteTime.Add(ls.getTtl()).Before(now)
+	return !entity.ExpireAt().Before(now)
 }
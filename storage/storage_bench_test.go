@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const benchKeysCount = 1024
+
+var benchKeys = func() []string {
+	keys := make([]string, benchKeysCount)
+	for i := range keys {
+		keys[i] = "bench_" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+// benchmarkReadWriteMix runs parallel readers & writers over the same set of keys,
+// writePercent defines the share of writes
+func benchmarkReadWriteMix(b *testing.B, testStorage Storage, writePercent int) {
+	for _, key := range benchKeys {
+		testStorage.AppendNewValue(key, "initial value")
+	}
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for pb.Next() {
+			key := benchKeys[random.Intn(benchKeysCount)]
+			if random.Intn(100) < writePercent {
+				testStorage.UpdateValueByKey(key, "updated value")
+			} else {
+				testStorage.GetValueByKey(key)
+			}
+		}
+	})
+}
+
+func BenchmarkSyncMapStorage(b *testing.B) {
+	for _, writePercent := range []int{0, 10, 50, 90} {
+		b.Run("writes_"+strconv.Itoa(writePercent)+"%", func(b *testing.B) {
+			benchmarkReadWriteMix(b, InitSyncMapStorage(time.Minute), writePercent)
+		})
+	}
+}
+
+func BenchmarkShardedStorage(b *testing.B) {
+	for _, writePercent := range []int{0, 10, 50, 90} {
+		b.Run("writes_"+strconv.Itoa(writePercent)+"%", func(b *testing.B) {
+			benchmarkReadWriteMix(b, InitShardedStorage(time.Minute, 64), writePercent)
+		})
+	}
+}
+
+// BenchmarkSortedSetIncrement shows the cost of a write does not grow with the size of the sorted set
+func BenchmarkSortedSetIncrement(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		b.Run("members_"+strconv.Itoa(size), func(b *testing.B) {
+			testStorage := InitShardedStorage(time.Minute, 64)
+			members := make([]ScoredMember, size)
+			for i := range members {
+				members[i] = ScoredMember{"player_" + strconv.Itoa(i), float64(i)}
+			}
+			testStorage.AddToSortedSet("board", members...)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				testStorage.IncrementSortedSetScore("board", members[rand.Intn(size)].Member, 1)
+			}
+		})
+	}
+}
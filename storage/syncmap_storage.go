@@ -2,6 +2,8 @@ package storage
 
 import (
 	"errors"
+	"math/rand"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/syncmap"
@@ -13,6 +15,14 @@ type SyncMapStorage struct {
 	// I've chosen syncmap to avoid manual concurrency management (locking/unlocking mutexes)
 	// and to get benefits of its inernal model (read non-only non-blocking access, synchronized write access)
 	internalStorage *syncmap.Map
+	// writes of single entries share the lock, so transactions could hold it exclusively
+	// to apply several entries at once. Reads do not take it
+	writeLock sync.RWMutex
+	index     scanIndex
+	*vacuum
+	*memoryLimiter
+	mutationHub
+	operations
 }
 
 // InitSyncMapStorage ...
@@ -20,6 +30,9 @@ func InitSyncMapStorage(ttl time.Duration) *SyncMapStorage {
 	newStorage := new(SyncMapStorage)
 	newStorage.internalStorage = new(syncmap.Map)
 	newStorage.ttl = ttl
+	newStorage.vacuum = newVacuum(newStorage)
+	newStorage.memoryLimiter = newMemoryLimiter(newStorage)
+	newStorage.operations = operations{newStorage}
 
 	return newStorage
 }
@@ -28,12 +41,172 @@ func (ls *SyncMapStorage) getTtl() time.Duration {
 	return ls.ttl
 }
 
+// iteration order of the map is the same from call to call, so sampling starts at a random
+// position and wraps around if there are not enough entries after it
+func (ls *SyncMapStorage) sampleEntries(n int) map[string]*StorableWithMeta {
+	sample := make(map[string]*StorableWithMeta, n)
+	skip := 0
+	if count := int(ls.entriesCount()); count > n {
+		skip = rand.Intn(count)
+	}
+
+	position := 0
+	ls.internalStorage.Range(func(key interface{}, value interface{}) bool {
+		if position >= skip {
+			sample[key.(string)] = value.(*StorableWithMeta)
+		}
+		position++
+		return len(sample) < n
+	})
+	if len(sample) < n && skip > 0 {
+		position = 0
+		ls.internalStorage.Range(func(key interface{}, value interface{}) bool {
+			sample[key.(string)] = value.(*StorableWithMeta)
+			position++
+			return len(sample) < n && position < skip
+		})
+	}
+	return sample
+}
+
+func (ls *SyncMapStorage) removeEntry(key string, entry *StorableWithMeta, mutationType MutationType) bool {
+	defer ls.lockKey(key)()
+	// the entry could be overridden since it was loaded, so it is removed only if it is still the same
+	ls.writeLock.RLock()
+	removed := ls.internalStorage.CompareAndDelete(key, entry)
+	if removed {
+		ls.accountEntry(key, entry, nil)
+		ls.index.track(key, entry, nil)
+	}
+	ls.writeLock.RUnlock()
+
+	if removed {
+		ls.emit(mutationType, key, nil)
+	}
+	return removed
+}
+
+// store call replaces the entry keeping track of the memory
+func (ls *SyncMapStorage) store(key string, entry *StorableWithMeta) {
+	ls.writeLock.RLock()
+	defer ls.writeLock.RUnlock()
+	if previous, loaded := ls.internalStorage.Swap(key, entry); loaded {
+		ls.accountEntry(key, previous.(*StorableWithMeta), entry)
+		ls.index.track(key, previous.(*StorableWithMeta), entry)
+	} else {
+		ls.accountEntry(key, nil, entry)
+		ls.index.track(key, nil, entry)
+	}
+}
+
+func (ls *SyncMapStorage) loadStored(key string) *StorableWithMeta {
+	if value, loaded := ls.internalStorage.Load(key); loaded {
+		return value.(*StorableWithMeta)
+	}
+	return nil
+}
+
+func (ls *SyncMapStorage) commitEntries(read map[string]*StorableWithMeta, written map[string]*StorableWithMeta) (bool, error) {
+	if err := ls.freeMemory(commitDelta(read, written)); err != nil {
+		return false, err
+	}
+
+	defer ls.lockKeys(read)()
+	ls.writeLock.Lock()
+	for key, stored := range read {
+		if ls.loadStored(key) != stored {
+			ls.writeLock.Unlock()
+			return false, nil
+		}
+	}
+	for key, updated := range written {
+		if updated == nil {
+			ls.internalStorage.Delete(key)
+		} else {
+			ls.internalStorage.Store(key, updated)
+		}
+		ls.accountEntry(key, read[key], updated)
+		ls.index.track(key, read[key], updated)
+	}
+	ls.writeLock.Unlock()
+
+	ls.emitCommitted(read, written)
+	return true, nil
+}
+
+// ExecTransaction ...
+func (ls *SyncMapStorage) ExecTransaction(watched map[string]uint64, transaction Transaction) error {
+	return execTransaction(ls, watched, transaction)
+}
+
+// updateEntry call retries the update until the entry is not changed concurrently
+func (ls *SyncMapStorage) updateEntry(key string, update entryUpdate) (*StorableWithMeta, error) {
+	for {
+		var stored *StorableWithMeta
+		value, loaded := ls.internalStorage.Load(key)
+		if loaded {
+			stored = value.(*StorableWithMeta)
+		}
+		current, updated, err := updateStored(stored, update)
+		if err != nil {
+			return nil, err
+		}
+		if updated == current {
+			return current, nil
+		}
+		if err := ls.freeMemory(sizeDelta(key, current, updated)); err != nil {
+			return nil, err
+		}
+
+		var swapped bool
+		unlockKey := ls.lockKey(key)
+		ls.writeLock.RLock()
+		switch {
+		case !loaded:
+			_, loaded = ls.internalStorage.LoadOrStore(key, updated)
+			swapped = !loaded
+		case updated == nil:
+			swapped = ls.internalStorage.CompareAndDelete(key, stored)
+		default:
+			swapped = ls.internalStorage.CompareAndSwap(key, stored, updated)
+		}
+		if swapped {
+			ls.accountEntry(key, stored, updated)
+			ls.index.track(key, stored, updated)
+		}
+		ls.writeLock.RUnlock()
+
+		if swapped {
+			ls.emit(mutationOf(current, updated), key, updated)
+		}
+		unlockKey()
+		if swapped {
+			return updated, nil
+		}
+	}
+}
+
+// loadNotExpired call loads the entry and removes it if it is already expired
+func (ls *SyncMapStorage) loadNotExpired(key string) (*StorableWithMeta, bool) {
+	if value, exists := ls.internalStorage.Load(key); exists {
+		swm := value.(*StorableWithMeta)
+		if notExpired(swm) {
+			swm.touch()
+			return swm, true
+		}
+		if !ls.keepsExpired() && ls.removeEntry(key, swm, MutationExpire) {
+			ls.lazyExpired()
+		}
+	}
+	return nil, false
+}
+
 // GetAllKeys ....
 func (ls *SyncMapStorage) GetAllKeys() []string {
 	// having no opportunity to get length of ls.internalStorage i have chosen 0 & 16 magic numbers
 	keys := make([]string, 0, 16)
 	ls.internalStorage.Range(func(key interface{}, value interface{}) bool {
-		if notExpired(value.(*StorableWithMeta), ls) {
+		if notExpired(value.(*StorableWithMeta)) {
 			keys = append(keys, key.(string)) // FIXME unsafe
 		}
 		return true
@@ -41,60 +214,110 @@ func (ls *SyncMapStorage) GetAllKeys() []string {
 	return keys
 }
 
+// Scan ...
+func (ls *SyncMapStorage) Scan(cursor uint64, count int, filter ScanFilter) ([]string, uint64, error) {
+	return scanKeys([]*treapNode{ls.index.tree()}, ls.loadStored, cursor, count, filter)
+}
+
 // GetValueByKey ....
 func (ls *SyncMapStorage) GetValueByKey(key string) (*StorableWithMeta, bool) {
-	if value, exists := ls.internalStorage.Load(key); exists {
-		return filterExpired(value.(*StorableWithMeta), ls)
-	}
-	return nil, false
+	return ls.loadNotExpired(key)
 }
 
 // DeleteValueByKey ...
 func (ls *SyncMapStorage) DeleteValueByKey(key string) bool {
-	ls.internalStorage.Delete(key)
-	return true
+	defer ls.lockKey(key)()
+	ls.writeLock.RLock()
+	previous, existed := ls.internalStorage.LoadAndDelete(key)
+	if existed {
+		ls.accountEntry(key, previous.(*StorableWithMeta), nil)
+		ls.index.track(key, previous.(*StorableWithMeta), nil)
+	}
+	ls.writeLock.RUnlock()
+
+	if existed {
+		ls.emit(MutationDelete, key, nil)
+	}
+	return existed
+}
+
+// RangeEntries ...
+func (ls *SyncMapStorage) RangeEntries(f func(key string, entry *StorableWithMeta) bool) {
+	ls.internalStorage.Range(func(key interface{}, value interface{}) bool {
+		if swm := value.(*StorableWithMeta); notExpired(swm) {
+			return f(key.(string), swm)
+		}
+		return true
+	})
+}
+
+// SnapshotEntries call holds the write lock exclusively, as transactions do
+func (ls *SyncMapStorage) SnapshotEntries() map[string]*StorableWithMeta {
+	entries := make(map[string]*StorableWithMeta)
+	ls.writeLock.Lock()
+	defer ls.writeLock.Unlock()
+	ls.RangeEntries(func(key string, entry *StorableWithMeta) bool {
+		entries[key] = entry
+		return true
+	})
+	return entries
+}
+
+// RestoreEntry ...
+func (ls *SyncMapStorage) RestoreEntry(key string, entry *StorableWithMeta) {
+	// the key is locked to keep the scan index in the order of the changes
+	defer ls.lockKey(key)()
+	ls.store(key, entry)
 }
 
 // GetNestedValueByKeyAndIndex ....
 func (ls *SyncMapStorage) GetNestedValueByKeyAndIndex(key string, index int) (*StorableWithMeta, bool, error) {
-	if maybeSlice, exists := ls.internalStorage.Load(key); exists {
-		if swm, yes := maybeSlice.(*StorableWithMeta); yes && notExpired(swm, ls) {
-			slice, yes := swm.Entity.([]string) // TODO  looks ugly. consider another generic / polymorphic construction
-			if yes {
-				if index >= 0 && index < len(slice) {
-					valWithMeta := enpackStorable(slice[index], swm)
-					return valWithMeta, true, nil
-				}
-				return nil, false, errors.New("Index out of range")
+	if swm, exists := ls.loadNotExpired(key); exists {
+		slice, yes := swm.Entity.([]string) // TODO  looks ugly. consider another generic / polymorphic construction
+		if yes {
+			if index >= 0 && index < len(slice) {
+				valWithMeta := enpackStorable(slice[index], swm)
+				return valWithMeta, true, nil
 			}
-			return nil, false, errors.New("Stored value is not an array")
+			return nil, false, errors.New("Index out of range")
 		}
+		return nil, false, errors.New("Stored value is not an array")
 	}
 	return nil, false, nil
 }
 
 // GetNestedValueByKeyAndSubkey ...
 func (ls *SyncMapStorage) GetNestedValueByKeyAndSubkey(key string, subKey string) (*StorableWithMeta, bool, error) {
-	if maybeDict, exists := ls.internalStorage.Load(key); exists {
-		if swm, yes := maybeDict.(*StorableWithMeta); yes && notExpired(swm, ls) {
-			dict, yes := swm.Entity.(map[string]string) // TODO  looks ugly. consider another generic / polymorphic construction
-			if yes {
-				val, ok := dict[subKey]
-				valWithMeta := enpackStorable(val, swm)
-				return valWithMeta, ok, nil
-			}
-			return nil, false, errors.New("Stored value is not a dictionary")
+	if swm, exists := ls.loadNotExpired(key); exists {
+		dict, yes := swm.Entity.(map[string]string) // TODO  looks ugly. consider another generic / polymorphic construction
+		if yes {
+			val, ok := dict[subKey]
+			valWithMeta := enpackStorable(val, swm)
+			return valWithMeta, ok, nil
 		}
+		return nil, false, errors.New("Stored value is not a dictionary")
 	}
 	return nil, false, errors.New("Map not found")
 }
 
 // UpdateValueByKey ...
 func (ls *SyncMapStorage) UpdateValueByKey(key string, newValue Storable) error {
-	if _, exists := ls.internalStorage.Load(key); exists {
+	return ls.UpdateValueByKeyWithTTL(key, newValue, 0)
+}
+
+// UpdateValueByKeyWithTTL ...
+func (ls *SyncMapStorage) UpdateValueByKeyWithTTL(key string, newValue Storable, ttl time.Duration) error {
+	if current, exists := ls.loadNotExpired(key); exists {
 		// wrapping value into newStorableWithMeta ensures that LastWriteTime will be updated
 		// and lifetime of the entity will be prolonged
-		ls.internalStorage.Store(key, newStorableWithMeta(newValue))
+		entry := newStorableWithMeta(newValue, effectiveTTL(ttl, ls))
+		if err := ls.freeMemory(entrySize(key, entry) - entrySize(key, current)); err != nil {
+			return err
+		}
+		unlockKey := ls.lockKey(key)
+		ls.store(key, entry)
+		ls.emit(MutationUpdate, key, entry)
+		unlockKey()
 		return nil
 	}
 	return errors.New("There is no entry with such key")
@@ -102,9 +325,33 @@ func (ls *SyncMapStorage) UpdateValueByKey(key string, newValue Storable) error
 
 // AppendNewValue ...
 func (ls *SyncMapStorage) AppendNewValue(key string, newValue Storable) error {
-	if _, exists := ls.internalStorage.Load(key); !exists {
-		ls.internalStorage.Store(key, newStorableWithMeta(newValue))
-		return nil
+	return ls.AppendNewValueWithTTL(key, newValue, 0)
+}
+
+// AppendNewValueWithTTL ...
+func (ls *SyncMapStorage) AppendNewValueWithTTL(key string, newValue Storable, ttl time.Duration) error {
+	if _, exists := ls.loadNotExpired(key); !exists {
+		entry := newStorableWithMeta(newValue, effectiveTTL(ttl, ls))
+		if err := ls.freeMemory(entrySize(key, entry)); err != nil {
+			return err
+		}
+		// LoadOrStore guarantees that concurrent appends of the same key do not override each other
+		unlockKey := ls.lockKey(key)
+		ls.writeLock.RLock()
+		_, loaded := ls.internalStorage.LoadOrStore(key, entry)
+		if !loaded {
+			ls.accountEntry(key, nil, entry)
+			ls.index.track(key, nil, entry)
+		}
+		ls.writeLock.RUnlock()
+
+		if !loaded {
+			ls.emit(MutationAppend, key, entry)
+		}
+		unlockKey()
+		if !loaded {
+			return nil
+		}
 	}
 	return errors.New("Entry with such key already exists")
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"strconv"
 	"testing"
 	"time"
 )
@@ -131,3 +132,128 @@ func TestExpirationOnDemand(t *testing.T) {
 		t.Error("Expirtion policy is escaped via storing value in dictionary")
 	}
 }
+
+func TestPerKeyTTL(t *testing.T) {
+	shortKey, longKey, value := "ttl_short", "ttl_long", "Per key TTL"
+	var testStorage Storage = InitSyncMapStorage(time.Minute)
+
+	testStorage.AppendNewValueWithTTL(shortKey, value, time.Millisecond*50)
+	testStorage.AppendNewValue(longKey, value)
+
+	if storedVal, ok := testStorage.GetValueByKey(shortKey); !ok || storedVal.TTL != time.Millisecond*50 {
+		t.Error("Per key TTL is not stored with the value")
+	}
+	if storedVal, ok := testStorage.GetValueByKey(longKey); !ok || storedVal.TTL != time.Minute {
+		t.Error("Default TTL is not applied to the value stored without TTL")
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	if _, ok := testStorage.GetValueByKey(shortKey); ok {
+		t.Error("Test storage still contains the value with expired per key TTL")
+	}
+	if _, ok := testStorage.GetValueByKey(longKey); !ok {
+		t.Error("Value with default TTL is expired together with the short living one")
+	}
+
+	testStorage.UpdateValueByKeyWithTTL(longKey, value, time.Hour)
+	if storedVal, ok := testStorage.GetValueByKey(longKey); !ok || storedVal.ExpireIn() <= time.Minute {
+		t.Error("Update does not override TTL of the value")
+	}
+}
+
+func TestActiveExpiry(t *testing.T) {
+	var testVeryShortTTL = time.Millisecond * 10
+	testStorage := InitSyncMapStorage(testVeryShortTTL)
+	for i := 0; i < 100; i++ {
+		testStorage.AppendNewValue("vacuum_"+strconv.Itoa(i), "Never read again")
+	}
+	testStorage.AppendNewValueWithTTL("survivor", "Still alive", time.Hour)
+
+	if err := testStorage.StartVacuum(time.Millisecond * 5); err != nil {
+		t.Error("Can not start vacuum. " + err.Error())
+	}
+	if err := testStorage.StartVacuum(time.Millisecond * 5); err == nil {
+		t.Error("Vacuum is started twice")
+	}
+	time.Sleep(time.Millisecond * 200)
+	testStorage.StopVacuum()
+
+	stored := 0
+	testStorage.internalStorage.Range(func(key interface{}, value interface{}) bool {
+		stored++
+		return true
+	})
+	if stored != 1 {
+		t.Error("Expired entries are not reclaimed by vacuum, entries left: " + strconv.Itoa(stored))
+	}
+	if stats := testStorage.VacuumStats(); stats.ExpiredKeys != 100 || stats.Cycles == 0 {
+		t.Error("Vacuum stats do not match the number of reclaimed entries")
+	}
+	if _, ok := testStorage.GetValueByKey("survivor"); !ok {
+		t.Error("Not expired entry is reclaimed by vacuum")
+	}
+}
+
+func TestActiveExpiryAmongLiveEntries(t *testing.T) {
+	testStorage := InitSyncMapStorage(time.Hour)
+	for i := 0; i < 500; i++ {
+		testStorage.AppendNewValueWithTTL("expired_"+strconv.Itoa(i), "Never read again", time.Millisecond)
+		testStorage.AppendNewValue("alive_"+strconv.Itoa(i), "Still alive")
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	// a cycle stops as soon as few expired entries are sampled, so the rest are reclaimed by later cycles
+	for cycle := 0; cycle < 5000 && testStorage.VacuumStats().ExpiredKeys < 500; cycle++ {
+		testStorage.cycle(time.Second)
+	}
+	if stats := testStorage.VacuumStats(); stats.ExpiredKeys != 500 {
+		t.Error("Not all expired entries are reclaimed by vacuum, reclaimed: " + strconv.FormatUint(stats.ExpiredKeys, 10))
+	}
+	if stats := testStorage.MemoryStats(); stats.Entries != 500 {
+		t.Error("Not expired entries are reclaimed by vacuum")
+	}
+}
+
+func TestLazyExpiry(t *testing.T) {
+	var testVeryShortTTL = time.Millisecond * 10
+	testStorage := InitSyncMapStorage(testVeryShortTTL)
+	testStorage.AppendNewValue("lazy", "Read after expiration")
+
+	time.Sleep(testVeryShortTTL * 2)
+
+	if _, ok := testStorage.GetValueByKey("lazy"); ok {
+		t.Error("Test storage still contains the test value that should be already expired")
+	}
+	if _, exists := testStorage.internalStorage.Load("lazy"); exists {
+		t.Error("Expired entry is not removed on read")
+	}
+	if stats := testStorage.VacuumStats(); stats.LazyExpiredKeys != 1 {
+		t.Error("Entry removed on read is not counted")
+	}
+}
+
+func TestKeepExpired(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		delegated := testStorage.(DelegatedExpireStorage)
+		delegated.KeepExpired()
+		testStorage.AppendNewValueWithTTL("kept", "Removed explicitly", time.Millisecond)
+		testStorage.AppendNewValue("alive", "Not expired")
+		time.Sleep(time.Millisecond * 5)
+
+		if _, ok := testStorage.GetValueByKey("kept"); ok {
+			t.Error(name + ": Expired entry is not hidden from reads")
+		}
+		testStorage.(interface{ cycle(time.Duration) }).cycle(time.Second)
+		if stats := testStorage.(MemoryLimitedStorage).MemoryStats(); stats.Entries != 2 {
+			t.Error(name + ": Expired entry is removed by the storage")
+		}
+		if keys := delegated.ExpiredKeys(10); len(keys) != 1 || keys[0] != "kept" {
+			t.Errorf("%s: Expired keys are not found: %v", name, keys)
+		}
+		testStorage.DeleteValueByKey("kept")
+		if keys := delegated.ExpiredKeys(10); len(keys) != 0 {
+			t.Errorf("%s: Deleted expired entry is still found: %v", name, keys)
+		}
+	}
+}
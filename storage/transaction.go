@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTransactionAborted is returned when any of the watched keys does not have the expected version
+var ErrTransactionAborted = errors.New("Transaction is aborted as watched entries have been changed")
+
+// Transaction is a function performing commands over the isolated view of the storage.
+// Its changes are applied atomically if it returns no error, and are discarded otherwise.
+// The function could be called more than once if the entries it reads are changed concurrently,
+// so it should have no side effects other than capturing results
+type Transaction func(tx *Tx) error
+
+// TransactionStorage runs multi-key transactions with optimistic WATCH semantics
+type TransactionStorage interface {
+	// ExecTransaction runs the transaction if all the watched keys have the expected versions
+	// (zero for absent keys), ErrTransactionAborted is returned otherwise
+	ExecTransaction(watched map[string]uint64, transaction Transaction) error
+}
+
+// transactionalKeyspace is a keyspace capable to apply several changes at once
+type transactionalKeyspace interface {
+	keyspace
+
+	// loadStored call loads the entry as it is stored regardless of its expiration, nil if there is no such key
+	loadStored(key string) *StorableWithMeta
+
+	// commitEntries call atomically applies the written entries (nil removes the key) unless any of
+	// the read ones is changed since it was loaded. False is returned if the changes are not applied
+	commitEntries(read map[string]*StorableWithMeta, written map[string]*StorableWithMeta) (bool, error)
+}
+
+// txKeyspace is a keyspace keeping changes of the transaction aside of the storage
+type txKeyspace struct {
+	storage transactionalKeyspace
+	read    map[string]*StorableWithMeta
+	written map[string]*StorableWithMeta
+}
+
+func (tk *txKeyspace) getTtl() time.Duration {
+	return tk.storage.getTtl()
+}
+
+// load call remembers the stored entry, so the transaction is not committed if it is changed
+func (tk *txKeyspace) load(key string) *StorableWithMeta {
+	if entry, loaded := tk.read[key]; loaded {
+		return entry
+	}
+	entry := tk.storage.loadStored(key)
+	tk.read[key] = entry
+	return entry
+}
+
+func (tk *txKeyspace) loadNotExpired(key string) (*StorableWithMeta, bool) {
+	if entry, written := tk.written[key]; written {
+		return entry, entry != nil
+	}
+	if entry := tk.load(key); entry != nil && notExpired(entry) {
+		return entry, true
+	}
+	return nil, false
+}
+
+func (tk *txKeyspace) updateEntry(key string, update entryUpdate) (*StorableWithMeta, error) {
+	current, _ := tk.loadNotExpired(key)
+	updated, err := update(current)
+	if err != nil {
+		return nil, err
+	}
+	if updated != current {
+		tk.written[key] = updated
+	}
+	return updated, nil
+}
+
+// Tx is the view of the storage used by a transaction. Besides the basic commands it provides
+// all the operations over particular data types
+type Tx struct {
+	operations
+	keyspace *txKeyspace
+}
+
+// GetValueByKey ...
+func (tx *Tx) GetValueByKey(key string) (*StorableWithMeta, bool) {
+	return tx.keyspace.loadNotExpired(key)
+}
+
+// GetStoredValueByKey call returns the entry as it is stored even if it is expired by the clock of the storage,
+// so the caller could judge its expiration as of another moment. Nil is returned if there is no such key
+func (tx *Tx) GetStoredValueByKey(key string) *StorableWithMeta {
+	if entry, written := tx.keyspace.written[key]; written {
+		return entry
+	}
+	return tx.keyspace.load(key)
+}
+
+// SetValueByKey call stores the value regardless of the current one.
+// Non-positive ttl means the default TTL of the storage
+func (tx *Tx) SetValueByKey(key string, newValue Storable, ttl time.Duration) {
+	tx.keyspace.load(key)
+	tx.keyspace.written[key] = newStorableWithMeta(newValue, effectiveTTL(ttl, tx.keyspace))
+}
+
+// DeleteValueByKey call returns true if there was such key
+func (tx *Tx) DeleteValueByKey(key string) bool {
+	_, existed := tx.keyspace.loadNotExpired(key)
+	tx.keyspace.written[key] = nil
+	return existed
+}
+
+// execTransaction call is shared by storage implementations. The transaction is retried
+// until none of the entries it reads is changed concurrently
+func execTransaction(storage transactionalKeyspace, watched map[string]uint64, transaction Transaction) error {
+	for {
+		tk := &txKeyspace{storage, make(map[string]*StorableWithMeta), make(map[string]*StorableWithMeta)}
+		for key, version := range watched {
+			if current, exists := tk.loadNotExpired(key); (exists && current.Version != version) || (!exists && version != 0) {
+				return ErrTransactionAborted
+			}
+		}
+		if err := transaction(&Tx{operations{tk}, tk}); err != nil {
+			return err
+		}
+
+		committed, err := storage.commitEntries(tk.read, tk.written)
+		if err != nil || committed {
+			return err
+		}
+	}
+}
+
+// commitDelta call computes how much memory is needed to apply the changes
+func commitDelta(read map[string]*StorableWithMeta, written map[string]*StorableWithMeta) int64 {
+	var delta int64
+	for key, updated := range written {
+		delta += sizeDelta(key, read[key], updated)
+	}
+	return delta
+}
+
+// emitCommitted call reports changes of the committed transaction, removal of absent keys is not reported
+func (hub *mutationHub) emitCommitted(read map[string]*StorableWithMeta, written map[string]*StorableWithMeta) {
+	for key, updated := range written {
+		var current *StorableWithMeta
+		if stored := read[key]; stored != nil && notExpired(stored) {
+			current = stored
+		}
+		if current != nil || updated != nil {
+			hub.emit(mutationOf(current, updated), key, updated)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestTransactionAppliesAllChanges(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("stock", "10")
+		testStorage.AppendNewValue("obsolete", "value")
+
+		var stock int64
+		err := testStorage.ExecTransaction(nil, func(tx *Tx) error {
+			var err error
+			if stock, err = tx.IncrementBy("stock", -3); err != nil {
+				return err
+			}
+			tx.SetValueByKey("order", "3 items", 0)
+			tx.DeleteValueByKey("obsolete")
+			_, err = tx.PushToList("log", false, "ordered")
+			return err
+		})
+		if err != nil || stock != 7 {
+			t.Fatal(name + ": Can not execute the transaction")
+		}
+
+		if val, ok := testStorage.GetValueByKey("stock"); !ok || val.Entity != "7" {
+			t.Error(name + ": Counter is not updated by the transaction")
+		}
+		if val, ok := testStorage.GetValueByKey("order"); !ok || val.Entity != "3 items" {
+			t.Error(name + ": Value is not set by the transaction")
+		}
+		if _, ok := testStorage.GetValueByKey("obsolete"); ok {
+			t.Error(name + ": Value is not deleted by the transaction")
+		}
+		if length, _ := testStorage.GetListLength("log"); length != 1 {
+			t.Error(name + ": List is not updated by the transaction")
+		}
+	}
+}
+
+func TestTransactionIsAllOrNothing(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("stock", "10")
+		testStorage.AppendNewValue("text", "not a number")
+
+		err := testStorage.ExecTransaction(nil, func(tx *Tx) error {
+			tx.IncrementBy("stock", -3)
+			if val, _ := tx.GetValueByKey("stock"); val.Entity != "7" {
+				return errors.New("Transaction does not see its own changes")
+			}
+			_, err := tx.IncrementBy("text", 1)
+			return err
+		})
+		if err == nil {
+			t.Error(name + ": Failed command does not fail the transaction")
+		}
+		if val, _ := testStorage.GetValueByKey("stock"); val.Entity != "10" {
+			t.Error(name + ": Changes of the failed transaction are applied")
+		}
+	}
+}
+
+func TestTransactionWatch(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("balance", "100")
+		watchedEntry, _ := testStorage.GetValueByKey("balance")
+		watched := map[string]uint64{"balance": watchedEntry.Version, "absent": 0}
+
+		testStorage.IncrementBy("balance", 1)
+		err := testStorage.ExecTransaction(watched, func(tx *Tx) error {
+			tx.SetValueByKey("balance", "0", 0)
+			return nil
+		})
+		if err != ErrTransactionAborted {
+			t.Error(name + ": Change of the watched key does not abort the transaction")
+		}
+
+		current, _ := testStorage.GetValueByKey("balance")
+		watched["balance"] = current.Version
+		if err := testStorage.ExecTransaction(watched, func(tx *Tx) error {
+			tx.SetValueByKey("balance", "0", 0)
+			return nil
+		}); err != nil {
+			t.Error(name + ": Transaction with the actual versions is aborted")
+		}
+		if val, _ := testStorage.GetValueByKey("balance"); val.Entity != "0" {
+			t.Error(name + ": Transaction with the actual versions is not applied")
+		}
+	}
+}
+
+func TestConcurrentTransactions(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("a", "0")
+		testStorage.AppendNewValue("b", "0")
+
+		// the sum of the counters is kept zero, so no partially applied transaction should be seen
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					testStorage.ExecTransaction(nil, func(tx *Tx) error {
+						if _, err := tx.IncrementBy("a", 1); err != nil {
+							return err
+						}
+						_, err := tx.IncrementBy("b", -1)
+						return err
+					})
+					testStorage.IncrementBy("a", 1)
+					testStorage.IncrementBy("a", -1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		var sum int64
+		testStorage.ExecTransaction(nil, func(tx *Tx) error {
+			a, _ := tx.IncrementBy("a", 0)
+			b, _ := tx.IncrementBy("b", 0)
+			sum = a + b
+			return nil
+		})
+		if val, _ := testStorage.GetValueByKey("a"); val.Entity != "1600" || sum != 0 {
+			t.Error(name + ": Some of concurrent transactions are lost or partially applied")
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import "math/rand"
+
+// treapNode is a node of a persistent treap: a binary search tree which is a heap by random priorities
+// of its nodes, so it is balanced in expectation. Nodes are never modified once they are linked into
+// a tree, a change copies only the path from the root to the changed node. So every change takes
+// logarithmic time and previous versions of the tree stay valid for readers which still hold them.
+// Sizes of the subtrees make lookups by rank logarithmic as well
+type treapNode struct {
+	member   string
+	score    float64
+	priority uint32
+	size     int
+	left     *treapNode
+	right    *treapNode
+}
+
+// treapLeft is a predicate telling a node belongs to the left part of the tree,
+// it should hold for a prefix of the nodes in their order
+type treapLeft func(node *treapNode) bool
+
+func newTreapNode(member string, score float64) *treapNode {
+	return &treapNode{member: member, score: score, priority: rand.Uint32(), size: 1}
+}
+
+func (node *treapNode) count() int {
+	if node == nil {
+		return 0
+	}
+	return node.size
+}
+
+// linked call copies the node with new children
+func (node *treapNode) linked(left *treapNode, right *treapNode) *treapNode {
+	copied := *node
+	copied.left, copied.right = left, right
+	copied.size = left.count() + 1 + right.count()
+	return &copied
+}
+
+// before call is the order of nodes by score, nodes with equal scores are ordered by member
+func (node *treapNode) before(score float64, member string) bool {
+	return node.score < score || (node.score == score && node.member < member)
+}
+
+// treapSplit call splits the tree into the nodes the predicate holds for and the rest
+func treapSplit(node *treapNode, left treapLeft) (*treapNode, *treapNode) {
+	if node == nil {
+		return nil, nil
+	}
+	if left(node) {
+		rightOfLeft, rest := treapSplit(node.right, left)
+		return node.linked(node.left, rightOfLeft), rest
+	}
+	lesser, leftOfRight := treapSplit(node.left, left)
+	return lesser, node.linked(leftOfRight, node.right)
+}
+
+// treapMerge call joins the trees, all the nodes of the first one should precede the nodes of the second one
+func treapMerge(first *treapNode, second *treapNode) *treapNode {
+	switch {
+	case first == nil:
+		return second
+	case second == nil:
+		return first
+	case first.priority > second.priority:
+		return first.linked(first.left, treapMerge(first.right, second))
+	default:
+		return second.linked(treapMerge(first, second.left), second.right)
+	}
+}
+
+// treapInsert call puts the node after the nodes the predicate holds for
+func treapInsert(root *treapNode, node *treapNode, before treapLeft) *treapNode {
+	lesser, greater := treapSplit(root, before)
+	return treapMerge(treapMerge(lesser, node), greater)
+}
+
+// treapDelete call removes the nodes the notAfter predicate holds for and the before one does not
+func treapDelete(root *treapNode, before treapLeft, notAfter treapLeft) *treapNode {
+	lesser, rest := treapSplit(root, before)
+	_, greater := treapSplit(rest, notAfter)
+	return treapMerge(lesser, greater)
+}
+
+// treapCount call returns the number of the nodes the predicate holds for
+func treapCount(node *treapNode, left treapLeft) int {
+	count := 0
+	for node != nil {
+		if left(node) {
+			count += node.left.count() + 1
+			node = node.right
+		} else {
+			node = node.left
+		}
+	}
+	return count
+}
+
+// treapByRank call returns the node with 0-based rank or nil if it is out of range
+func treapByRank(node *treapNode, rank int) *treapNode {
+	for node != nil {
+		switch leftSize := node.left.count(); {
+		case rank < leftSize:
+			node = node.left
+		case rank > leftSize:
+			rank -= leftSize + 1
+			node = node.right
+		default:
+			return node
+		}
+	}
+	return nil
+}
+
+// treapAscend call calls f for the nodes in their order starting with the rank until f returns false
+func treapAscend(node *treapNode, from int, f func(node *treapNode) bool) bool {
+	if node == nil {
+		return true
+	}
+	leftSize := node.left.count()
+	if from < leftSize && !treapAscend(node.left, from, f) {
+		return false
+	}
+	if from <= leftSize && !f(node) {
+		return false
+	}
+	return treapAscend(node.right, from-leftSize-1, f)
+}
+
+// treapDescend call calls f for the nodes in reverse order starting with the rank until f returns false
+func treapDescend(node *treapNode, from int, f func(node *treapNode) bool) bool {
+	if node == nil {
+		return true
+	}
+	leftSize := node.left.count()
+	if from > leftSize && !treapDescend(node.right, from-leftSize-1, f) {
+		return false
+	}
+	if from >= leftSize && !f(node) {
+		return false
+	}
+	return treapDescend(node.left, from, f)
+}
@@ -0,0 +1,26 @@
+package storage
+
+import "time"
+
+// TTLStorage changes lifetime of the stored entries
+type TTLStorage interface {
+	// Expire sets the new TTL of the entry counted from now, non-positive ttl removes the entry.
+	// False is returned if there is no such key
+	Expire(key string, ttl time.Duration) (bool, error)
+}
+
+// Expire call rewrites the entry as changing of TTL is a write as well
+func (ops operations) Expire(key string, ttl time.Duration) (bool, error) {
+	existed := false
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		if current == nil {
+			return nil, nil
+		}
+		existed = true
+		if ttl <= 0 {
+			return nil, nil
+		}
+		return newStorableWithMeta(current.Entity, ttl), nil
+	})
+	return existed, err
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("key", "value")
+		before, _ := testStorage.GetValueByKey("key")
+
+		if existed, err := testStorage.Expire("key", time.Hour); err != nil || !existed {
+			t.Error(name + ": Can not change TTL of the entry")
+		}
+		if after, _ := testStorage.GetValueByKey("key"); after.Entity != "value" || after.ExpireIn() < 59*time.Minute || after.Version <= before.Version {
+			t.Error(name + ": Entry is not rewritten with the new TTL")
+		}
+		if existed, _ := testStorage.Expire("missing", time.Hour); existed {
+			t.Error(name + ": TTL of absent entry is changed")
+		}
+		if existed, _ := testStorage.Expire("key", 0); !existed {
+			t.Error(name + ": Can not expire the entry immediately")
+		}
+		if _, exists := testStorage.GetValueByKey("key"); exists {
+			t.Error(name + ": Entry expired immediately still exists")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// number of keys checked at once, the same as Redis uses by default
+	vacuumSampleSize = 20
+	// sampling is repeated while more than 1/vacuumRepeatRatio of sampled keys are expired
+	vacuumRepeatRatio = 4
+	// fraction of the interval a single cycle is allowed to take
+	vacuumTimeBudgetRatio = 4
+)
+
+// VacuumStats contains counters of the background expiry
+type VacuumStats struct {
+	Cycles          uint64
+	SampledKeys     uint64
+	ExpiredKeys     uint64
+	LazyExpiredKeys uint64
+}
+
+// vacuum is a Redis-like active expiry cycle: it samples a few random keys, removes expired ones
+// and repeats immediately while a lot of sampled keys appear to be expired
+type vacuum struct {
+	target sampledStorage
+
+	lock sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+
+	cycles          uint64
+	sampledKeys     uint64
+	expiredKeys     uint64
+	lazyExpiredKeys uint64
+
+	keepExpired int32 // expired entries are removed only explicitly, see KeepExpired
+}
+
+func newVacuum(target sampledStorage) *vacuum {
+	v := new(vacuum)
+	v.target = target
+	return v
+}
+
+// StartVacuum call starts background expiry running every interval
+func (v *vacuum) StartVacuum(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("Vacuum interval should be positive")
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.stop != nil {
+		return errors.New("Vacuum is already started")
+	}
+	v.stop = make(chan struct{})
+	v.done = make(chan struct{})
+
+	go v.run(interval, v.stop, v.done)
+	return nil
+}
+
+// StopVacuum call stops background expiry and waits for the current cycle to finish
+func (v *vacuum) StopVacuum() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.stop == nil {
+		return
+	}
+	close(v.stop)
+	<-v.done
+	v.stop, v.done = nil, nil
+}
+
+// VacuumStats ...
+func (v *vacuum) VacuumStats() VacuumStats {
+	return VacuumStats{
+		Cycles:          atomic.LoadUint64(&v.cycles),
+		SampledKeys:     atomic.LoadUint64(&v.sampledKeys),
+		ExpiredKeys:     atomic.LoadUint64(&v.expiredKeys),
+		LazyExpiredKeys: atomic.LoadUint64(&v.lazyExpiredKeys),
+	}
+}
+
+func (v *vacuum) run(interval time.Duration, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			v.cycle(interval / vacuumTimeBudgetRatio)
+		}
+	}
+}
+
+func (v *vacuum) cycle(timeBudget time.Duration) {
+	if v.keepsExpired() {
+		return
+	}
+	start := time.Now()
+	atomic.AddUint64(&v.cycles, 1)
+	for {
+		sample := v.target.sampleEntries(vacuumSampleSize)
+		expired := 0
+		for key, entry := range sample {
+			if !notExpired(entry) && v.target.removeEntry(key, entry, MutationExpire) {
+				expired++
+			}
+		}
+		atomic.AddUint64(&v.sampledKeys, uint64(len(sample)))
+		atomic.AddUint64(&v.expiredKeys, uint64(expired))
+
+		if len(sample) == 0 || expired*vacuumRepeatRatio <= len(sample) || time.Since(start) > timeBudget {
+			return
+		}
+	}
+}
+
+// lazyExpired call counts the entries removed on read
+func (v *vacuum) lazyExpired() {
+	atomic.AddUint64(&v.lazyExpiredKeys, 1)
+}
+
+// KeepExpired call turns expiry off: expired entries are hidden from reads, but neither reads nor vacuum
+// remove them. The owner of the storage finds them with ExpiredKeys and deletes them on its own
+func (v *vacuum) KeepExpired() {
+	atomic.StoreInt32(&v.keepExpired, 1)
+}
+
+func (v *vacuum) keepsExpired() bool {
+	return atomic.LoadInt32(&v.keepExpired) != 0
+}
+
+// ExpiredKeys call samples the storage the same way the vacuum cycle does: sampling is repeated
+// while a lot of sampled keys appear to be expired, up to n keys are returned
+func (v *vacuum) ExpiredKeys(n int) []string {
+	var keys []string
+	found := make(map[string]bool)
+	for len(keys) < n {
+		sample := v.target.sampleEntries(vacuumSampleSize)
+		expired, added := 0, 0
+		for key, entry := range sample {
+			if !notExpired(entry) {
+				expired++
+				if !found[key] && len(keys) < n {
+					found[key] = true
+					keys = append(keys, key)
+					added++
+				}
+			}
+		}
+		// unlike the cycle, sampling does not remove the expired entries, so it stops once nothing new is found
+		if added == 0 || expired*vacuumRepeatRatio <= len(sample) {
+			break
+		}
+	}
+	return keys
+}
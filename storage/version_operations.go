@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrVersionConflict is returned by conditional writes when the precondition is not met
+var ErrVersionConflict = errors.New("Entry does not match the expected version")
+
+// Precondition is checked atomically against the current entry, which is nil if there is no such key
+type Precondition func(current *StorableWithMeta) bool
+
+// Always precondition is met regardless of the current entry
+func Always(current *StorableWithMeta) bool {
+	return true
+}
+
+// IfExists precondition is met if there is such key
+func IfExists(current *StorableWithMeta) bool {
+	return current != nil
+}
+
+// IfAbsent precondition is met if there is no such key
+func IfAbsent(current *StorableWithMeta) bool {
+	return current == nil
+}
+
+// IfVersion call creates a precondition met if the entry exists and has one of the versions
+func IfVersion(versions ...uint64) Precondition {
+	return func(current *StorableWithMeta) bool {
+		if current == nil {
+			return false
+		}
+		for _, version := range versions {
+			if current.Version == version {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IfNotVersion call creates a precondition met if there is no such key or the entry has none of the versions
+func IfNotVersion(versions ...uint64) Precondition {
+	matches := IfVersion(versions...)
+	return func(current *StorableWithMeta) bool {
+		return !matches(current)
+	}
+}
+
+// VersionedStorage contains compare-and-swap writes based on versions of the entries
+type VersionedStorage interface {
+	// SetValueByKeyIf stores the value creating the entry if needed. The new entry is returned,
+	// or the current one (nil if there is no such key) along with ErrVersionConflict
+	SetValueByKeyIf(key string, newValue Storable, ttl time.Duration, precondition Precondition) (*StorableWithMeta, error)
+
+	// SetFlaggedValueByKeyIf works as SetValueByKeyIf but keeps the flags with the entry
+	SetFlaggedValueByKeyIf(key string, newValue Storable, flags uint32, ttl time.Duration, precondition Precondition) (*StorableWithMeta, error)
+
+	// SetValueByKeyUntil works as SetValueByKeyIf but the entry expires at the moment regardless of the time
+	// it is written, so replicas applying the write later get the same expiration
+	SetValueByKeyUntil(key string, newValue Storable, expireAt time.Time, precondition Precondition) (*StorableWithMeta, error)
+
+	// DeleteValueByKeyIf returns the removed entry (nil if there was no such key),
+	// or the current one along with ErrVersionConflict
+	DeleteValueByKeyIf(key string, precondition Precondition) (*StorableWithMeta, error)
+}
+
+// SetValueByKeyIf ...
+func (ops operations) SetValueByKeyIf(key string, newValue Storable, ttl time.Duration, precondition Precondition) (*StorableWithMeta, error) {
+	return ops.SetFlaggedValueByKeyIf(key, newValue, 0, ttl, precondition)
+}
+
+// SetFlaggedValueByKeyIf ...
+func (ops operations) SetFlaggedValueByKeyIf(key string, newValue Storable, flags uint32, ttl time.Duration, precondition Precondition) (*StorableWithMeta, error) {
+	var conflicting *StorableWithMeta
+	updated, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		if !precondition(current) {
+			conflicting = current
+			return nil, ErrVersionConflict
+		}
+		entry := newStorableWithMeta(newValue, effectiveTTL(ttl, ops.keyspace))
+		entry.Flags = flags
+		return entry, nil
+	})
+	if err == ErrVersionConflict {
+		return conflicting, err
+	}
+	return updated, err
+}
+
+// SetValueByKeyUntil ...
+func (ops operations) SetValueByKeyUntil(key string, newValue Storable, expireAt time.Time, precondition Precondition) (*StorableWithMeta, error) {
+	var conflicting *StorableWithMeta
+	updated, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		if !precondition(current) {
+			conflicting = current
+			return nil, ErrVersionConflict
+		}
+		entry := newStorableWithMeta(newValue, 0)
+		// non-positive TTL means the entry is expired already
+		entry.TTL = expireAt.Sub(entry.LastWriteTime)
+		return entry, nil
+	})
+	if err == ErrVersionConflict {
+		return conflicting, err
+	}
+	return updated, err
+}
+
+// DeleteValueByKeyIf ...
+func (ops operations) DeleteValueByKeyIf(key string, precondition Precondition) (*StorableWithMeta, error) {
+	var removed *StorableWithMeta
+	_, err := ops.keyspace.updateEntry(key, func(current *StorableWithMeta) (*StorableWithMeta, error) {
+		removed = current
+		if !precondition(current) {
+			return nil, ErrVersionConflict
+		}
+		return nil, nil
+	})
+	return removed, err
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVersionsIncrease(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		testStorage.AppendNewValue("key", "1")
+		first, _ := testStorage.GetValueByKey("key")
+		testStorage.UpdateValueByKey("key", "2")
+		second, _ := testStorage.GetValueByKey("key")
+		testStorage.PushToList("list", false, "1")
+		list, _ := testStorage.GetValueByKey("list")
+		testStorage.DeleteValueByKey("key")
+		testStorage.AppendNewValue("key", "3")
+		third, _ := testStorage.GetValueByKey("key")
+
+		if first.Version == 0 || second.Version <= first.Version || list.Version <= second.Version || third.Version <= list.Version {
+			t.Error(name + ": Versions of the entries do not increase")
+		}
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		created, err := testStorage.SetValueByKeyIf("key", "1", 0, IfAbsent)
+		if err != nil || created == nil || created.Entity != "1" {
+			t.Fatal(name + ": Can not create the entry if it is absent")
+		}
+		if current, err := testStorage.SetValueByKeyIf("key", "2", 0, IfAbsent); err != ErrVersionConflict || current.Version != created.Version {
+			t.Error(name + ": Existing entry is overridden or conflicting version is not reported")
+		}
+		updated, err := testStorage.SetValueByKeyIf("key", "2", 0, IfVersion(created.Version))
+		if err != nil || updated.Version <= created.Version {
+			t.Error(name + ": Can not update the entry of the expected version")
+		}
+		if _, err := testStorage.SetValueByKeyIf("key", "3", 0, IfVersion(created.Version)); err != ErrVersionConflict {
+			t.Error(name + ": Stale version does not lead to conflict")
+		}
+		if _, err := testStorage.DeleteValueByKeyIf("key", IfNotVersion(updated.Version)); err != ErrVersionConflict {
+			t.Error(name + ": Entry of the version is removed in spite of If-None-Match precondition")
+		}
+		if removed, err := testStorage.DeleteValueByKeyIf("key", IfVersion(updated.Version)); err != nil || removed.Entity != "2" {
+			t.Error(name + ": Can not remove the entry of the expected version")
+		}
+		if current, err := testStorage.SetValueByKeyIf("key", "4", 0, IfExists); err != ErrVersionConflict || current != nil {
+			t.Error(name + ": Absent entry is not reported as conflicting")
+		}
+	}
+}
+
+func TestConcurrentCompareAndSwap(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		initial, _ := testStorage.SetValueByKeyIf("key", "initial", 0, IfAbsent)
+
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		succeeded := 0
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := testStorage.SetValueByKeyIf("key", "updated", 0, IfVersion(initial.Version)); err == nil {
+					lock.Lock()
+					succeeded++
+					lock.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if succeeded != 1 {
+			t.Error(name + ": Exactly one of concurrent updates of the same version should succeed")
+		}
+	}
+}
+
+func TestSetValueByKeyUntil(t *testing.T) {
+	for name, testStorage := range testStorages() {
+		expireAt := time.Now().Add(time.Hour)
+		stored, err := testStorage.SetValueByKeyUntil("key", "value", expireAt, IfAbsent)
+		if err != nil || !stored.ExpireAt().Equal(expireAt) {
+			t.Error(name + ": Entry does not expire at the passed moment")
+		}
+		if _, err := testStorage.SetValueByKeyUntil("key", "other", expireAt, IfAbsent); err != ErrVersionConflict {
+			t.Error(name + ": Precondition is not checked")
+		}
+		testStorage.SetValueByKeyUntil("key", "expired", time.Now().Add(-time.Second), Always)
+		if _, exists := testStorage.GetValueByKey("key"); exists {
+			t.Error(name + ": Entry written after its expiration is visible")
+		}
+	}
+}